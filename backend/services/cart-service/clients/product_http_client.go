@@ -3,75 +3,53 @@ package clients
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/client"
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/types"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
-	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/httpclient"
 )
 
 type ProductHTTPClient struct {
-	ProductServiceURL string
-	httpClient        *http.Client
-	serviceName       string
-	serviceSecret     string
+	client *httpclient.Client
 }
 
 type ProductHTTPClientConfig struct {
 	ProductServiceURL string
 	Timeout           time.Duration
 	ServiceName       string
-	ServiceSecret     string
+	ServiceAuth       auth.ServiceAuthOptions
 }
 
-func NewProductHTTPClient(config ProductHTTPClientConfig) client.ProductServiceClient {
-	return &ProductHTTPClient{
-		ProductServiceURL: config.ProductServiceURL,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
-		serviceName:   config.ServiceName,
-		serviceSecret: config.ServiceSecret,
+// NewProductHTTPClient fails without SERVICE_SECRET, product-service only verifies legacy tokens.
+func NewProductHTTPClient(config ProductHTTPClientConfig) (client.ProductServiceClient, error) {
+	signer, err := config.ServiceAuth.LegacySigner(config.ServiceName)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (c *ProductHTTPClient) addServiceHeaders(req *http.Request) {
-	serviceToken := auth.GenerateServiceToken(c.serviceName, c.serviceSecret)
-	req.Header.Set(auth.ServiceAuthHeader, serviceToken)
-	req.Header.Set(auth.ServiceNameHeader, c.serviceName)
+	return &ProductHTTPClient{
+		client: httpclient.New(httpclient.ClientConfig{
+			Target:  "product-service",
+			BaseURL: config.ProductServiceURL,
+			Timeout: config.Timeout,
+			Signer:  signer,
+		}),
+	}, nil
 }
 
 func (c *ProductHTTPClient) GetProductByIdBase(ctx context.Context, productId string) (*types.ProductResponseDTO, error) {
-	url := fmt.Sprintf("%s/api/product/productsBase/%s", c.ProductServiceURL, productId)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	var product types.ProductResponseDTO
+	err := c.client.Get(ctx, fmt.Sprintf("/api/product/productsBase/%s", url.PathEscape(productId)), &product)
+	if httpclient.IsStatus(err, http.StatusNotFound) {
+		return nil, nil
 	}
-
-	c.addServiceHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		//TODO: if token expired, refresh the token and send the request
-		return nil, fmt.Errorf("failed to call product service: %w", err)
-	}
-
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("product service returned %d: %s", resp.StatusCode, string(body))
-	}
-
-	var product types.ProductResponseDTO
-	if err := utils.ParseJSONBody(resp.Body, &product); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, httpclient.AppError(err, "product service is not available, please try again")
 	}
 
 	return &product, nil
-
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
 	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/clients"
@@ -11,40 +14,115 @@ import (
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/internal/repository"
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/internal/service"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 func main() {
+	if err := config.LoadLogging(); err != nil {
+		log.Fatal("invalid logging configuration: ", err)
+	}
+	logger, err := logging.Init("cart-service", config.Envs.LOG)
+	if err != nil {
+		log.Fatal("Failed to create logger: ", err)
+	}
+	defer logger.Sync()
+
+	if err := config.Load(); err != nil {
+		logger.Fatal("invalid configuration", zap.Error(err))
+	}
+
 	database, err := db.NewPostgresStore()
 	if err != nil {
-		log.Fatal("Failed to connect to database: ", err)
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+	//"<binary> migrate [up | down [steps] | status]" only migrates and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+	if config.Envs.DB_AUTO_MIGRATE {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("failed to apply migrations", zap.Error(err))
+		}
 	}
 
 	initDatabase(database)
 
+	//rules from INTERNAL_POLICY_FILE are added to the ones registered below
+	internalPolicy := auth.NewPolicy()
+	if config.Envs.INTERNAL_POLICY != "" {
+		if internalPolicy, err = auth.LoadPolicyFile(config.Envs.INTERNAL_POLICY); err != nil {
+			logger.Fatal("failed to load internal policy", zap.Error(err))
+		}
+	}
+
+	serviceTokens, err := config.Envs.SERVICE_AUTH.Verifier("cart-service")
+	if err != nil {
+		logger.Fatal("invalid service auth configuration", zap.Error(err))
+	}
+	userAuth, err := config.Envs.USER_AUTH.Config()
+	if err != nil {
+		logger.Fatal("invalid user auth configuration", zap.Error(err))
+	}
+
 	apiServer := api.NewServer(api.ServerConfig{
-		Addr:        config.Envs.CART_SERVICE_PORT,
-		DB:          database,
-		ServiceName: "cart-service",
-		APIPrefix:   "cart",
+		Addr:           config.Envs.CART_SERVICE_PORT,
+		MetricsAddr:    config.Envs.METRICS_ADDR,
+		DB:             database,
+		ServiceName:    "cart-service",
+		APIPrefix:      "cart",
+		InternalPolicy: internalPolicy,
+		Timeouts:       config.Envs.HTTP_TIMEOUTS,
+		ServiceTokens:  serviceTokens,
+		UserAuth:       userAuth,
 	})
+	//only order-service reads and clears carts at checkout
+	apiServer.AllowInternal("order-service", []string{http.MethodGet, http.MethodDelete}, "/checkout/{userId}")
 
-	productClient := clients.NewProductHTTPClient(clients.ProductHTTPClientConfig{
+	//carts still load without product-service, only adding items needs it
+	apiServer.AddHealthCheck(api.Optional(api.HTTPChecker("product-service", config.Envs.PRODUCT_SERVICE_URL+"/health")))
+
+	productClient, err := clients.NewProductHTTPClient(clients.ProductHTTPClientConfig{
 		ProductServiceURL: config.Envs.PRODUCT_SERVICE_URL,
 		Timeout:           5 * time.Second,
-		ServiceName:       "product-service",
-		ServiceSecret:     config.Envs.SERVICE_SECRET,
+		ServiceName:       "cart-service",
+		ServiceAuth:       config.Envs.SERVICE_AUTH,
 	})
+	if err != nil {
+		logger.Fatal("failed to create product client", zap.Error(err))
+	}
 
 	cartRepository := repository.NewCartRepository(database)
 	cartService := service.NewCartService(cartRepository, productClient, service.CartServiceConfig{
 		ProductServiceTimeout: 5 * time.Second,
 	})
-	cartHandler := controller.NewCartHandler(cartService)
+	cartHandler := controller.NewCartHandler(cartService, controller.CartHandlerConfig{
+		Authenticated: apiServer.Authenticated,
+		Idempotent:    apiServer.Idempotent,
+	})
 	apiServer.RegisterRoutes(cartHandler.RegisterRoutes)
 
-	if err := apiServer.Start(); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	apiServer.OnShutdown("database", func(ctx context.Context) error {
+		sqlDB, err := database.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
+	if err := apiServer.Run(context.Background()); err != nil {
+		logger.Fatal("server stopped", zap.Error(err))
 	}
 
 }
@@ -53,12 +131,12 @@ func initDatabase(gorm_Db *gorm.DB) {
 	db, err := gorm_Db.DB()
 
 	if err != nil {
-		log.Fatal("Unable to get generic DB from gorm: ", err)
+		logging.L().Fatal("unable to get generic DB from gorm", zap.Error(err))
 	}
 
 	if err := db.Ping(); err != nil {
-		log.Fatal("Unable to connect to database: ", err)
+		logging.L().Fatal("unable to connect to database", zap.Error(err))
 	}
 
-	log.Println("DB: Successfully connected")
+	logging.L().Info("database connected")
 }
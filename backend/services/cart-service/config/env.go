@@ -1,35 +1,52 @@
 package config
 
 import (
-	"github.com/Flow-Indo/LAKOO/backend/shared/go/env"
-	"github.com/lpernett/godotenv"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	sharedconfig "github.com/Flow-Indo/LAKOO/backend/shared/go/config"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
 )
 
 type Config struct {
-	CART_SERVICE_PORT   string
-	PRODUCT_SERVICE_URL string
-	SERVICE_SECRET      string
-	DB_USER             string
-	DB_PASSWORD         string
-	DB_NAME             string
-	DB_HOST             string
-	DB_PORT             string
-	DB_SSL              string
+	CART_SERVICE_PORT   string `env:"CART_SERVICE_PORT" default:"8003"`
+	METRICS_ADDR        string `env:"METRICS_ADDR" default:":9103"`
+	PRODUCT_SERVICE_URL string `env:"GATEWAY_URL" default:"http://localhost:8002" validate:"url"`
+	INTERNAL_POLICY     string `env:"INTERNAL_POLICY_FILE"`
+	DB_USER             string `env:"DB_USER,required"`
+	DB_PASSWORD         string `env:"DB_PASSWORD,required,secret"`
+	DB_NAME             string `env:"DB_NAME,required"`
+	DB_HOST             string `env:"DB_HOST" default:"localhost"`
+	DB_PORT             int    `env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+	DB_SSL              string `env:"DB_SSL" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	DB_AUTO_MIGRATE     bool   `env:"DB_AUTO_MIGRATE" default:"false"`
+
+	//pool sizes, statement timeout, slow query threshold and read replicas, see postgres.Options
+	DB_POOL postgres.Options
+
+	//service token keys, SERVICE_SECRET signs the calls to product-service, see auth.ServiceAuthOptions
+	SERVICE_AUTH auth.ServiceAuthOptions
+
+	//JWKS for bearer tokens and the secret of the gateway headers, see middleware.UserAuthOptions
+	USER_AUTH middleware.UserAuthOptions
+
+	//read, write, idle and shutdown timeouts of the server, see api.Timeouts
+	HTTP_TIMEOUTS api.Timeouts
+
+	//loaded before the rest, see LoadLogging
+	LOG logging.Options
 }
 
-func initConfig() *Config {
-	godotenv.Load("../../.env")
-	return &Config{
-		CART_SERVICE_PORT:   env.GetEnv("CART_SERVICE_PORT", "8003"),
-		PRODUCT_SERVICE_URL: env.GetEnv("GATEWAY_URL", "http://localhost:8002"),
-		SERVICE_SECRET:      env.GetEnv("SERVICE_SECRET", "secret"),
-		DB_USER:             env.GetEnv("DB_USER", "user"),
-		DB_PASSWORD:         env.GetEnv("DB_PASSWORD", "password"),
-		DB_NAME:             env.GetEnv("DB_NAME", "dbname"),
-		DB_HOST:             env.GetEnv("DB_HOST", "localhost"),
-		DB_PORT:             env.GetEnv("DB_PORT", "5432"),
-		DB_SSL:              env.GetEnv("DB_SSL", "disable"),
-	}
+// Envs is filled by Load at startup.
+var Envs = &Config{}
+
+// Load reads the config from the environment, CONFIG_FILE and Docker secrets, see sharedconfig.Load.
+func Load() error {
+	return sharedconfig.Load(Envs, sharedconfig.Options{})
 }
 
-var Envs = initConfig()
+// LoadLogging only fills the LOG options of Envs, quietly, so the logger is up before Load logs the config.
+func LoadLogging() error {
+	return sharedconfig.Load(&Envs.LOG, sharedconfig.Options{Quiet: true})
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS carts;
+
+DROP TYPE IF EXISTS cart_item_type;
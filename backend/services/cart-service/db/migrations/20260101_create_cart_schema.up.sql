@@ -0,0 +1,67 @@
+-- Cart schema: one cart per user or session and its items.
+-- Written for databases created before migrations were tracked too, so it is safe to re-run.
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+DO $$ BEGIN
+  CREATE TYPE cart_item_type AS ENUM ('brand_product', 'seller_product');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE TABLE IF NOT EXISTS carts (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid,
+  session_id varchar(255),
+  status varchar(20) NOT NULL DEFAULT 'active',
+  item_count integer NOT NULL DEFAULT 0,
+  total numeric(10,2) NOT NULL DEFAULT 0.0,
+  coupon_id uuid,
+  coupon_code varchar(100),
+  discount_amount numeric(10,2) NOT NULL DEFAULT 0.0,
+  currency varchar(3) NOT NULL DEFAULT 'USD',
+  last_activity_at timestamptz NOT NULL,
+  expires_at timestamptz,
+  created_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL,
+  deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_carts_user_id ON carts(user_id);
+CREATE INDEX IF NOT EXISTS idx_carts_expires_at ON carts(expires_at);
+CREATE INDEX IF NOT EXISTS idx_carts_deleted_at ON carts(deleted_at);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  cart_id uuid NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+  item_type cart_item_type NOT NULL,
+  product_id uuid,
+  variant_id uuid,
+  brand_id uuid,
+  brand_product_id uuid,
+  seller_product_id uuid,
+  seller_id uuid,
+  quantity integer NOT NULL DEFAULT 1,
+  snapshot_compare_price numeric(10,2),
+  current_unit_price numeric(10,2) NOT NULL,
+  price_changed boolean NOT NULL DEFAULT false,
+  price_last_checked_at timestamptz NOT NULL,
+  subtotal numeric(10,2) NOT NULL DEFAULT 0.0,
+  is_available boolean NOT NULL DEFAULT true,
+  availability_message varchar(255),
+  snapshot_unit_price numeric(10,2) NOT NULL,
+  snapshot_product_name varchar(255) NOT NULL,
+  snapshot_variant_name varchar(255),
+  snapshot_sku varchar(100),
+  snapshot_image_url text,
+  snapshot_seller_name varchar(255),
+  snapshot_brand_name varchar(255),
+  added_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_items_cart_id ON cart_items(cart_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_product_id ON cart_items(product_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_variant_id ON cart_items(variant_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_brand_id ON cart_items(brand_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_brand_product_id ON cart_items(brand_product_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_seller_product_id ON cart_items(seller_product_id);
+CREATE INDEX IF NOT EXISTS idx_cart_items_seller_id ON cart_items(seller_id);
@@ -1,18 +1,20 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/models"
 	"github.com/google/uuid"
 )
 
 type CartRepositoryInterface interface {
 	// GetAllCartsByUserId(userId string) (*models.Cart, error)
-	GetActiveCartByUserId(userId string) (*models.Cart, error)
-	GetCartItemByUserIdAndProductId(userId string, productId string) (*models.CartItem, error)
-	UpdateCartItem(userId string, cartId string, cartItem *models.CartItem) error
-	CreateCartItem(userId string, cartId string, cartItem *models.CartItem) error
-	CreateCart(newCart *models.Cart) error
-	RecalculateCartTotals(existingActiveCartID uuid.UUID) error
-	RemoveCartItem(cardID uuid.UUID, sku uuid.UUID) error
-	DeleteAllCartItems(cartID uuid.UUID) error
+	GetActiveCartByUserId(ctx context.Context, userId string) (*models.Cart, error)
+	GetCartItemByUserIdAndProductId(ctx context.Context, userId string, productId string) (*models.CartItem, error)
+	UpdateCartItem(ctx context.Context, userId string, cartId string, cartItem *models.CartItem) error
+	CreateCartItem(ctx context.Context, userId string, cartId string, cartItem *models.CartItem) error
+	CreateCart(ctx context.Context, newCart *models.Cart) error
+	RecalculateCartTotals(ctx context.Context, existingActiveCartID uuid.UUID) error
+	RemoveCartItem(ctx context.Context, cardID uuid.UUID, sku uuid.UUID) error
+	DeleteAllCartItems(ctx context.Context, cartID uuid.UUID) error
 }
@@ -5,53 +5,68 @@ import (
 
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/services"
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/gorilla/mux"
 )
 
+type CartHandlerConfig struct {
+	Authenticated func(http.Handler) http.Handler // authenticates the end user of every external route
+	Idempotent    func(http.Handler) http.Handler // honours Idempotency-Key on adding items
+}
+
 type CartHandler struct {
 	service services.CartServiceInterface
+	config  CartHandlerConfig
 }
 
-func NewCartHandler(service services.CartServiceInterface) *CartHandler {
+func NewCartHandler(service services.CartServiceInterface, config CartHandlerConfig) *CartHandler {
+	if config.Idempotent == nil {
+		config.Idempotent = func(next http.Handler) http.Handler { return next }
+	}
+
 	return &CartHandler{
 		service: service,
+		config:  config,
 	}
 }
 
 func (h *CartHandler) RegisterRoutes(cartExternalRouter *mux.Router, cartInternalRouter *mux.Router) {
 	// cartRouter.HandleFunc("/", h.GetCart).Methods("GET")
 	//for external
-	cartExternalRouter.Use(middleware.UserIDMiddleware)
-	cartExternalRouter.HandleFunc("/addToCart", h.AddToCart).Methods("POST")
+	cartExternalRouter.Use(h.config.Authenticated)
+	cartExternalRouter.Handle("/addToCart", h.config.Idempotent(http.HandlerFunc(h.AddToCart))).Methods("POST")
 	cartExternalRouter.HandleFunc("/", h.GetActiveCart).Methods("GET")
 
-	//for internal
-	cartInternalRouter.Use(middleware.ServiceAuthMiddleware)
-
+	//for internal, service auth and the caller policy are applied by the server
+	cartInternalRouter.HandleFunc("/checkout/{userId}", h.GetCheckoutCart).Methods("GET")
+	cartInternalRouter.HandleFunc("/checkout/{userId}", h.ClearCheckoutCart).Methods("DELETE")
 }
 
 func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
 	//take user id
 	userId, err := middleware.GetUserIdFromContext(r.Context())
 	if err != nil {
-		utils.WriteJSONResponse(w, http.StatusUnauthorized, err)
+		apperror.Write(w, r, apperror.Wrap(err, apperror.CodeUnauthenticated, ""))
+		return
 	}
 
 	var cartItemRequest types.CartItemRequest
 	if err := utils.ParseJSONBody(r.Body, &cartItemRequest); err != nil {
-		utils.WriteJSONResponse(w, http.StatusBadRequest, err)
+		apperror.Write(w, r, apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid JSON body"))
+		return
 	}
 
 	//validate if align with struct
-
 	if err := utils.ValidatePayload(cartItemRequest); err != nil {
-		utils.WriteJSONResponse(w, http.StatusBadRequest, err)
+		apperror.Write(w, r, err)
+		return
 	}
 
 	if err := h.service.AddToCart(r.Context(), userId, cartItemRequest); err != nil {
-		utils.WriteJSONResponse(w, http.StatusInternalServerError, err)
+		apperror.Write(w, r, err)
+		return
 	}
 
 	utils.WriteJSONResponse(w, http.StatusOK, map[string]any{
@@ -63,13 +78,38 @@ func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
 func (h *CartHandler) GetActiveCart(w http.ResponseWriter, r *http.Request) {
 	userId, err := middleware.GetUserIdFromContext(r.Context())
 	if err != nil {
-		utils.WriteJSONResponse(w, http.StatusUnauthorized, err)
+		apperror.Write(w, r, apperror.Wrap(err, apperror.CodeUnauthenticated, ""))
+		return
+	}
+
+	cart, err := h.service.GetActiveCart(r.Context(), userId)
+	if err != nil {
+		apperror.Write(w, r, err)
+		return
 	}
 
-	cart, err := h.service.GetActiveCart(userId)
+	utils.WriteJSONResponse(w, http.StatusOK, cart)
+}
+
+func (h *CartHandler) GetCheckoutCart(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+
+	cart, err := h.service.GetActiveCart(r.Context(), userId)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		apperror.Write(w, r, err)
+		return
 	}
 
 	utils.WriteJSONResponse(w, http.StatusOK, cart)
 }
+
+func (h *CartHandler) ClearCheckoutCart(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+
+	if err := h.service.ClearCart(r.Context(), userId); err != nil {
+		apperror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
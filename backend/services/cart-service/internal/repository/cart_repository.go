@@ -1,12 +1,14 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -22,36 +24,36 @@ func NewCartRepository(db *gorm.DB) *CartRepository {
 }
 
 // all carts
-func (r *CartRepository) GetAllCartsByUserId(userId string) (*models.Cart, error) {
+func (r *CartRepository) GetAllCartsByUserId(ctx context.Context, userId string) (*models.Cart, error) {
 	var cart models.Cart
 
-	_, err := r.db.Model(&models.Cart{}).
+	_, err := r.db.WithContext(ctx).Model(&models.Cart{}).
 		Where("user_id = ?", userId).
 		Find(&cart).Rows()
 
 	if err != nil {
-		return nil, errors.New("failed to fetch cart items")
+		return nil, fmt.Errorf("failed to fetch cart items: %w", err)
 	}
 
 	return &cart, nil
 }
 
 // active carts
-func (r *CartRepository) GetActiveCartByUserId(userId string) (*models.Cart, error) {
+func (r *CartRepository) GetActiveCartByUserId(ctx context.Context, userId string) (*models.Cart, error) {
 	var cart models.Cart
 
-	_, err := r.db.Model(&models.Cart{}).
+	_, err := r.db.WithContext(ctx).Model(&models.Cart{}).
 		Where("user_id = ? AND status = active", userId).
 		Find(&cart).Rows()
 
 	if err != nil {
-		return nil, errors.New("failed to fetch cart items")
+		return nil, fmt.Errorf("failed to fetch cart items: %w", err)
 	}
 
 	return &cart, nil
 }
 
-func (r *CartRepository) GetCartItemByUserIdAndProductId(userId string, productId string) (*models.CartItem, error) {
+func (r *CartRepository) GetCartItemByUserIdAndProductId(ctx context.Context, userId string, productId string) (*models.CartItem, error) {
 	var cartItem models.CartItem
 
 	// userUUID, err := uuid.Parse(userId)
@@ -64,7 +66,7 @@ func (r *CartRepository) GetCartItemByUserIdAndProductId(userId string, productI
 	// 	return nil, fmt.Errorf("invalid product ID format: %w", err)
 	// }
 
-	err := r.db.
+	err := r.db.WithContext(ctx).
 		Joins("JOIN carts ON carts.id = cart_items.cart_id").
 		Where("carts.user_id = ? AND cart_items.product_id = ?", userId, productId).
 		Where("carts.status = ?", models.CartStatusActive). // Only active carts
@@ -80,25 +82,25 @@ func (r *CartRepository) GetCartItemByUserIdAndProductId(userId string, productI
 	return &cartItem, nil
 }
 
-func (r *CartRepository) CreateCart(newCart *models.Cart) error {
-	if err := r.db.Create(newCart).Error; err != nil {
+func (r *CartRepository) CreateCart(ctx context.Context, newCart *models.Cart) error {
+	if err := r.db.WithContext(ctx).Create(newCart).Error; err != nil {
 		return fmt.Errorf("failed to create cart: %w", err)
 	}
 	return nil
 }
 
-func (r *CartRepository) UpdateCartItem(userId string, cartId string, cartItem *models.CartItem) error {
+func (r *CartRepository) UpdateCartItem(ctx context.Context, userId string, cartId string, cartItem *models.CartItem) error {
 	//verify the cart belongs to the user
 	var cart models.Cart
-	if err := r.db.Where("id = ? AND user_id = ?", cartId, userId).First(&cart).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", cartId, userId).First(&cart).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("cart not found or doesn't belong to user")
+			return apperror.NotFound("cart not found or doesn't belong to user")
 		}
 		return fmt.Errorf("failed to verify cart ownership: %w", err)
 	}
 
 	//update the cart item
-	result := r.db.Model(&models.CartItem{}).
+	result := r.db.WithContext(ctx).Model(&models.CartItem{}).
 		Where("id = ? AND cart_id = ?", cartItem.ID, cartId).
 		Updates(map[string]interface{}{
 			"quantity":              cartItem.Quantity,
@@ -116,18 +118,18 @@ func (r *CartRepository) UpdateCartItem(userId string, cartId string, cartItem *
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("cart item not found")
+		return apperror.NotFound("cart item not found")
 	}
 
 	return nil
 }
 
-func (r *CartRepository) CreateCartItem(userId string, cartId string, cartItem *models.CartItem) error {
+func (r *CartRepository) CreateCartItem(ctx context.Context, userId string, cartId string, cartItem *models.CartItem) error {
 	//verify the cart belongs to the user
 	var cart models.Cart
-	if err := r.db.Where("id = ? AND user_id = ?", cartId, userId).First(&cart).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", cartId, userId).First(&cart).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("cart not found or doesn't belong to user")
+			return apperror.NotFound("cart not found or doesn't belong to user")
 		}
 		return fmt.Errorf("failed to verify cart ownership: %w", err)
 	}
@@ -142,11 +144,11 @@ func (r *CartRepository) CreateCartItem(userId string, cartId string, cartItem *
 	cartItem.UpdatedAt = time.Now()
 
 	//create cart item
-	if err := r.db.Create(cartItem).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(cartItem).Error; err != nil {
 		//check for duplicate
 		if strings.Contains(err.Error(), "duplicate key") ||
 			strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return fmt.Errorf("product already exists in cart")
+			return apperror.Conflict("product already exists in cart")
 		}
 		return fmt.Errorf("failed to create cart item: %w", err)
 	}
@@ -154,20 +156,20 @@ func (r *CartRepository) CreateCartItem(userId string, cartId string, cartItem *
 	return nil
 }
 
-func (r *CartRepository) RecalculateCartTotals(cartID uuid.UUID) error {
+func (r *CartRepository) RecalculateCartTotals(ctx context.Context, cartID uuid.UUID) error {
 	var agg struct {
 		ItemCount int64
 		Subtotal  float64
 	}
 
-	if err := r.db.Model(&models.CartItem{}).
+	if err := r.db.WithContext(ctx).Model(&models.CartItem{}).
 		Select("COALESCE(SUM(quantity), 0) as item_count, COALESCE(SUM(quantity * current_unit_price), 0) as subtotal").
 		Where("cart_id = ?", cartID).
 		Scan(&agg).Error; err != nil {
 		return fmt.Errorf("failed to recalculate cart totals: %w", err)
 	}
 
-	if err := r.db.Model(&models.Cart{}).
+	if err := r.db.WithContext(ctx).Model(&models.Cart{}).
 		Where("id = ?", cartID).
 		Updates(map[string]interface{}{
 			"item_count":       int(agg.ItemCount),
@@ -181,19 +183,19 @@ func (r *CartRepository) RecalculateCartTotals(cartID uuid.UUID) error {
 	return nil
 }
 
-func (r *CartRepository) RemoveCartItem(cartID uuid.UUID, sku uuid.UUID) error {
-	result := r.db.Where("cart_id = ? AND snapshot_sku = ?", cartID, sku).Delete(&models.CartItem{})
+func (r *CartRepository) RemoveCartItem(ctx context.Context, cartID uuid.UUID, sku uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("cart_id = ? AND snapshot_sku = ?", cartID, sku).Delete(&models.CartItem{})
 	if result.Error != nil {
 		return fmt.Errorf("failed to remove cart item: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("cart item not found")
+		return apperror.NotFound("cart item not found")
 	}
 	return nil
 }
 
-func (r *CartRepository) DeleteAllCartItems(cartID uuid.UUID) error {
-	if err := r.db.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+func (r *CartRepository) DeleteAllCartItems(ctx context.Context, cartID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
 		return fmt.Errorf("failed to delete all cart items: %w", err)
 	}
 	return nil
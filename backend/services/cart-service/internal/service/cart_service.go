@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +9,7 @@ import (
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/models"
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/repository"
 	"github.com/Flow-Indo/LAKOO/backend/services/cart-service/domain/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
 	"github.com/google/uuid"
 )
 
@@ -39,7 +39,7 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 	}
 	// //check is no product, or check if requested quantity > stock quantity
 	if productResponse == nil {
-		return errors.New("product not found")
+		return apperror.NotFound("product not found")
 	}
 
 	//check if seller product or brand
@@ -53,14 +53,14 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 		itemType = models.BrandProduct
 	}
 
-	existingActiveCart, err := s.repository.GetActiveCartByUserId(userId)
+	existingActiveCart, err := s.repository.GetActiveCartByUserId(ctx, userId)
 	if err != nil {
 		return err
 	}
 
 	userUUID, err := uuid.Parse(userId)
 	if err != nil {
-		return fmt.Errorf("invalid user ID format: %w", err)
+		return apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid user ID format")
 	}
 	//create cart if user does not have an active cart
 	if existingActiveCart == nil {
@@ -76,7 +76,7 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 			UpdatedAt:      time.Now(),
 		}
 
-		if err := s.repository.CreateCart(newCart); err != nil {
+		if err := s.repository.CreateCart(ctx, newCart); err != nil {
 			return err
 		}
 	}
@@ -107,11 +107,11 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 					cartItem.AvailabilityMessage = nil
 				}
 
-				if err := s.repository.UpdateCartItem(userId, existingActiveCart.ID.String(), &cartItem); err != nil {
+				if err := s.repository.UpdateCartItem(ctx, userId, existingActiveCart.ID.String(), &cartItem); err != nil {
 					return fmt.Errorf("Unable to update cart for productID: %v", request.ProductID)
 				}
 
-				if err := s.repository.RecalculateCartTotals(existingActiveCart.ID); err != nil {
+				if err := s.repository.RecalculateCartTotals(ctx, existingActiveCart.ID); err != nil {
 					return err
 				}
 
@@ -154,11 +154,11 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 		//add item count
 		existingActiveCart.ItemCount += 1
 
-		if err := s.repository.CreateCartItem(userId, existingActiveCart.ID.String(), newCartItem); err != nil {
+		if err := s.repository.CreateCartItem(ctx, userId, existingActiveCart.ID.String(), newCartItem); err != nil {
 			return fmt.Errorf("Unable to create cartItem for productID: %v", productResponse.ID)
 		}
 
-		if err := s.repository.RecalculateCartTotals(existingActiveCart.ID); err != nil {
+		if err := s.repository.RecalculateCartTotals(ctx, existingActiveCart.ID); err != nil {
 			return err
 		}
 
@@ -167,8 +167,8 @@ func (s *CartService) AddToCart(ctx context.Context, userId string, request type
 	return nil
 }
 
-func (s *CartService) GetActiveCart(userId string) (*types.CartResponseDTO, error) {
-	cart, err := s.repository.GetActiveCartByUserId(userId)
+func (s *CartService) GetActiveCart(ctx context.Context, userId string) (*types.CartResponseDTO, error) {
+	cart, err := s.repository.GetActiveCartByUserId(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -185,30 +185,30 @@ func (s *CartService) GetActiveCart(userId string) (*types.CartResponseDTO, erro
 
 }
 
-func (s *CartService) RemoveFromCart(userId string, sku string) error {
-	cart, err := s.repository.GetActiveCartByUserId(userId)
+func (s *CartService) RemoveFromCart(ctx context.Context, userId string, sku string) error {
+	cart, err := s.repository.GetActiveCartByUserId(ctx, userId)
 	if err != nil {
 		return err
 	}
 
 	if cart == nil {
-		return errors.New("no active cart found")
+		return apperror.NotFound("no active cart found")
 	}
 
 	skuUUID, err := uuid.Parse(sku)
 	if err != nil {
-		return fmt.Errorf("invalid product ID format: %w", err)
+		return apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid product ID format")
 	}
 
-	if err := s.repository.RemoveCartItem(cart.ID, skuUUID); err != nil {
+	if err := s.repository.RemoveCartItem(ctx, cart.ID, skuUUID); err != nil {
 		return err
 	}
 
-	return s.repository.RecalculateCartTotals(cart.ID)
+	return s.repository.RecalculateCartTotals(ctx, cart.ID)
 }
 
-func (s *CartService) ClearCart(userId string) error {
-	cart, err := s.repository.GetActiveCartByUserId(userId)
+func (s *CartService) ClearCart(ctx context.Context, userId string) error {
+	cart, err := s.repository.GetActiveCartByUserId(ctx, userId)
 	if err != nil {
 		return err
 	}
@@ -216,11 +216,11 @@ func (s *CartService) ClearCart(userId string) error {
 		return nil
 	}
 
-	if err := s.repository.DeleteAllCartItems(cart.ID); err != nil {
+	if err := s.repository.DeleteAllCartItems(ctx, cart.ID); err != nil {
 		return err
 	}
 
-	return s.repository.RecalculateCartTotals(cart.ID)
+	return s.repository.RecalculateCartTotals(ctx, cart.ID)
 }
 
 func (s *CartService) parseToCartResponse(cart *models.Cart) *types.CartResponseDTO {
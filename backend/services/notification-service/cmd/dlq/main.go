@@ -0,0 +1,126 @@
+// Command dlq lists and re-drives messages from the notification dead letter topics.
+//
+//	dlq list -topic order_event [-limit 50]
+//	dlq redrive -topic order_event -partition 0 -offset 42
+//	dlq redrive -topic order_event -all
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/consumer"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	//only the brokers and the consumer group of the service config are needed
+	if err := config.LoadKafka(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "list":
+		list(ctx, os.Args[2:])
+	case "redrive":
+		if err := redrive(ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		usage()
+	}
+}
+
+func list(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	topic := flags.String("topic", "", "source topic whose dead letters are listed, e.g. order_event")
+	limit := flags.Int("limit", 100, "maximum number of messages to list, 0 for all")
+	flags.Parse(args)
+
+	if *topic == "" {
+		log.Fatal("-topic is required")
+	}
+
+	letters, err := consumer.ListDeadLetters(ctx, *topic, *limit)
+	if err != nil {
+		log.Fatal("Failed to list dead letters: ", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tOFFSET\tORIGINAL TOPIC\tKEY\tATTEMPTS\tLAST FAILED\tERROR")
+	for _, letter := range letters {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%s\t%s\n",
+			letter.Partition,
+			letter.Offset,
+			letter.OriginalTopic,
+			string(letter.Key),
+			letter.Attempt,
+			letter.LastFailedAt.Format(time.RFC3339),
+			letter.Error,
+		)
+	}
+	w.Flush()
+}
+
+// redrive returns its error instead of exiting, so the producer is closed first.
+func redrive(ctx context.Context, args []string) (err error) {
+	flags := flag.NewFlagSet("redrive", flag.ExitOnError)
+	topic := flags.String("topic", "", "source topic whose dead letters are re-driven, e.g. order_event")
+	partition := flags.Int("partition", -1, "partition of a single dead letter to re-drive")
+	offset := flags.Int64("offset", -1, "offset of a single dead letter to re-drive")
+	all := flags.Bool("all", false, "re-drive every dead letter not re-driven before")
+	idle := flags.Duration("idle", 10*time.Second, "with -all, stop after no message arrived for this long")
+	flags.Parse(args)
+
+	if *topic == "" {
+		log.Fatal("-topic is required")
+	}
+	if !*all && (*partition < 0 || *offset < 0) {
+		log.Fatal("either -all or both -partition and -offset are required")
+	}
+
+	redriver := consumer.NewRedriver(*topic)
+	defer func() {
+		if closeErr := redriver.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close producer: %w", closeErr))
+		}
+	}()
+
+	if *all {
+		count, err := redriver.RedriveAll(ctx, *idle)
+		if err != nil {
+			return fmt.Errorf("re-drove %d messages before failing: %w", count, err)
+		}
+		log.Printf("Re-drove %d messages to %s", count, *topic)
+		return nil
+	}
+
+	letter, err := redriver.RedriveOne(ctx, *partition, *offset)
+	if err != nil {
+		return fmt.Errorf("failed to re-drive message: %w", err)
+	}
+	log.Printf("Re-drove message with key %q to %s", string(letter.Key), letter.OriginalTopic)
+
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dlq list -topic <topic> [-limit n]")
+	fmt.Fprintln(os.Stderr, "       dlq redrive -topic <topic> (-partition p -offset o | -all)")
+	os.Exit(2)
+}
@@ -2,21 +2,40 @@ package main
 
 import (
 	"context"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/db"
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/client"
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/consumer"
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/events"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/monitoring"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/repository"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
+	if err := config.LoadLogging(); err != nil {
+		log.Fatal("invalid logging configuration: ", err)
+	}
+	logger, err := logging.Init("notification-service", config.Envs.LOG)
+	if err != nil {
+		log.Fatal("Failed to create logger: ", err)
+	}
 	defer logger.Sync() //called when main() exits, prevents losing the last log messages during crash/shutdown
 
+	if err := config.Load(); err != nil {
+		logger.Fatal("invalid configuration", zap.Error(err))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background()) //cancel is the function to call when you want to shutdown the entire app
 	sigchan := make(chan os.Signal, 1)                      //To listen for shutdown signals from the operating system like ctrl + c
 	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
@@ -30,22 +49,111 @@ func main() {
 	//router: to route different topics to different handlers
 	router := events.NewRouter()
 
-	payment_handler := events.NewPaymentHandler(*whatsAppNotifier)
-	router.Register("payment_event", payment_handler)
-	router.Register("order_event", payment_handler)
-
-	consumer_manager := consumer.NewManager(router, logger)
+	database, err := db.NewPostgresStore()
+	if err != nil {
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+	//"<binary> migrate [up | down [steps] | status]" only migrates and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+	if config.Envs.DB_AUTO_MIGRATE {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("failed to apply migrations", zap.Error(err))
+		}
+	}
+	processedMessages := repository.NewProcessedMessageRepository(database)
+
+	//wrapped so a redelivered event does not send the same whatsapp message twice
+	payment_handler := events.NewIdempotentHandler("payment_handler", events.NewPaymentHandler(whatsAppNotifier), processedMessages, config.Envs.IDEMPOTENCY_TTL, config.Envs.IDEMPOTENCY_LEASE)
+	router.Register(kafka.TopicPaymentEvent, payment_handler)
+	router.Register(kafka.TopicOrderEvent, payment_handler)
+
+	go purgeProcessedMessages(ctx, processedMessages, logger)
+
+	retryTiers, err := consumer.ParseRetryTiers(config.Envs.KAFKA_RETRY_TIERS)
+	if err != nil {
+		logger.Fatal("invalid KAFKA_RETRY_TIERS", zap.Error(err))
+	}
+
+	topicConsumers, err := consumer.ParseTopicConsumers(config.Envs.KAFKA_TOPIC_CONSUMERS)
+	if err != nil {
+		logger.Fatal("invalid KAFKA_TOPIC_CONSUMERS", zap.Error(err))
+	}
+
+	broker := kafka.NewKafkaBroker(config.Envs.KAFKA_BROKERS)
+	broker.Producer, err = config.Envs.KAFKA_PRODUCER.ProducerConfig()
+	if err != nil {
+		logger.Fatal("invalid kafka producer config", zap.Error(err))
+	}
+	//the original message is committed once the retry or dead letter copy is written, so wait for the in-sync replicas
+	if err := broker.Producer.RequireDelivery(); err != nil {
+		logger.Fatal("the retry topics need KAFKA_PRODUCER_ASYNC=false and KAFKA_PRODUCER_ACKS=all", zap.Error(err))
+	}
+	consumer_manager := consumer.NewManager(broker, router, logger, consumer.ManagerConfig{
+		RetryPolicy:       consumer.RetryPolicy{Tiers: retryTiers},
+		CommitInterval:    config.Envs.KAFKA_COMMIT_INTERVAL,
+		ConsumersPerTopic: config.Envs.KAFKA_CONSUMERS_PER_TOPIC,
+		TopicConsumers:    topicConsumers,
+		StuckAfter:        config.Envs.CONSUMER_STUCK_AFTER,
+		Metrics:           consumer.NewMetrics(prometheus.DefaultRegisterer),
+	})
 	consumer_manager.Run(ctx, config.Envs.KAFKA_TOPICS)
 
+	//probes and metrics, the service has no other HTTP endpoints
+	monitoringServer := monitoring.NewServer(":"+config.Envs.NOTIFICATION_SERVICE_PORT, config.Envs.METRICS_ADDR,
+		monitoring.Check{Name: "consumers", Check: consumer_manager.Ready},
+	)
+	go func() {
+		if err := monitoringServer.Start(); err != nil {
+			logger.Fatal("monitoring server failed", zap.Error(err))
+		}
+	}()
+
 	<-sigchan
 	logger.Info("shutdown signal received")
 
 	cancel() //signals workers to stop reading new messages, and for handlers take care of data corruption problem
 
 	consumer_manager.Shutdown() //cleans up resources, like closes kafka connections
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := monitoringServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to shut down monitoring server", zap.Error(err))
+	}
 	logger.Info("service shutdown gracefully")
 }
 
+// purgeProcessedMessages deletes expired dedup records once an hour until ctx is cancelled.
+func purgeProcessedMessages(ctx context.Context, processedMessages *repository.ProcessedMessageRepository, logger *zap.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := processedMessages.DeleteExpired(ctx)
+		if err != nil {
+			logger.Error("failed to purge processed messages", zap.Error(err))
+		} else if deleted > 0 {
+			logger.Info("purged processed messages", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // // NotificationService listens for Kafka events and processes them.
 // type NotificationService struct {
 // 	consumer *kafka.KafkaConsumer
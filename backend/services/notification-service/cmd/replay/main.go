@@ -0,0 +1,60 @@
+// Command replay re-runs notification handlers over a range of a topic, e.g. after a handler bug fix.
+// It accepts the flags of kafka-replay plus -route, which passes matches through the same router as the service.
+//
+//	replay -topic order_event -from-time 2026-01-01T00:00:00Z -event-types order.paid -route
+//
+// WhatsApp messages are only logged unless -send is given, and routing here skips the
+// processed-message store so already handled events are replayed too.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/client"
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/events"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka/replay"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := replay.Main(ctx, "replay", os.Args[1:], routerFlags, os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatal("Replay failed: ", err)
+	}
+}
+
+// routerFlags adds -send, the router is built once the flags are parsed.
+func routerFlags(flags *flag.FlagSet) func() (replay.Router, error) {
+	send := flags.Bool("send", false, "send the WhatsApp messages of -route instead of logging them, needs the Twilio config")
+
+	return func() (replay.Router, error) {
+		var notifier client.WhatsAppSender = client.NewDryRunWhatsAppService()
+		if *send {
+			//only sending needs the twilio credentials, and nothing else of the service config
+			if err := config.LoadTwilio(); err != nil {
+				return nil, fmt.Errorf("invalid configuration: %w", err)
+			}
+			notifier = client.NewWhatsAppService()
+		}
+
+		router := events.NewRouter()
+		paymentHandler := events.NewPaymentHandler(notifier)
+		router.Register(kafka.TopicPaymentEvent, paymentHandler)
+		router.Register(kafka.TopicOrderEvent, paymentHandler)
+
+		return router, nil
+	}
+}
@@ -1,38 +1,83 @@
 package config
 
 import (
-	"github.com/Flow-Indo/LAKOO/backend/shared/go/env"
-	"github.com/lpernett/godotenv"
+	"time"
+
+	sharedconfig "github.com/Flow-Indo/LAKOO/backend/shared/go/config"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
 )
 
 type Config struct {
-	NOTIFICATION_SERVICE_PORT string
-	TWILIO_ACCOUNT_SID        string
-	TWILIO_AUTH_TOKEN         string
-	TWILIO_WHATSAPP_NUMBER    string
-	VAPID_PUBLIC_KEY          string
-	VAPID_PRIVATE_KEY         string
-	VAPID_EMAIL               string
-	KAFKA_BROKERS             []string
-	KAFKA_GROUP_ID            string
-	KAFKA_TOPICS              []string
+	NOTIFICATION_SERVICE_PORT string        `env:"NOTIFICATION_SERVICE_PORT" default:"3007"`
+	METRICS_ADDR              string        `env:"METRICS_ADDR" default:":9107"`
+	VAPID_PUBLIC_KEY          string        `env:"VAPID_PUBLIC_KEY"`
+	VAPID_PRIVATE_KEY         string        `env:"VAPID_PRIVATE_KEY,secret"`
+	VAPID_EMAIL               string        `env:"VAPID_EMAIL" validate:"omitempty,email"`
+	KAFKA_TOPICS              []string      `env:"KAFKA_TOPICS" default:"kafka_topic" validate:"min=1"`
+	KAFKA_RETRY_TIERS         []string      `env:"KAFKA_RETRY_TIERS" default:"1m,10m"`
+	KAFKA_COMMIT_INTERVAL     time.Duration `env:"KAFKA_COMMIT_INTERVAL" default:"1s" validate:"gt=0"`
+	KAFKA_CONSUMERS_PER_TOPIC int           `env:"KAFKA_CONSUMERS_PER_TOPIC" default:"3" validate:"min=1"`
+	KAFKA_TOPIC_CONSUMERS     []string      `env:"KAFKA_TOPIC_CONSUMERS"`
+	CONSUMER_STUCK_AFTER      time.Duration `env:"CONSUMER_STUCK_AFTER" default:"5m" validate:"gt=0"`
+	DB_HOST                   string        `env:"DB_HOST" default:"localhost"`
+	DB_USER                   string        `env:"DB_USER,required"`
+	DB_PASSWORD               string        `env:"DB_PASSWORD,required,secret"`
+	DB_NAME                   string        `env:"DB_NAME" default:"notificationdb"`
+	DB_PORT                   int           `env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+	DB_SSL                    string        `env:"DB_SSL" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	DB_AUTO_MIGRATE           bool          `env:"DB_AUTO_MIGRATE" default:"false"`
+	IDEMPOTENCY_TTL           time.Duration `env:"IDEMPOTENCY_TTL" default:"168h" validate:"gt=0"`
+	IDEMPOTENCY_LEASE         time.Duration `env:"IDEMPOTENCY_LEASE" default:"5m" validate:"gt=0"`
+
+	//pool sizes, statement timeout, slow query threshold and read replicas, see postgres.Options
+	DB_POOL postgres.Options
+
+	//async mode, batching, compression, acks and partitioning, see kafka.ProducerOptions
+	KAFKA_PRODUCER kafka.ProducerOptions
+
+	//loaded before the rest, see LoadLogging
+	LOG logging.Options
+
+	//loaded on their own by the command line tools, see LoadTwilio and LoadKafka
+	TwilioConfig
+	KafkaConfig
+}
+
+// TwilioConfig is what sending WhatsApp messages needs, the replay command loads it on its own.
+type TwilioConfig struct {
+	TWILIO_ACCOUNT_SID     string `env:"TWILIO_ACCOUNT_SID,required"`
+	TWILIO_AUTH_TOKEN      string `env:"TWILIO_AUTH_TOKEN,required,secret"`
+	TWILIO_WHATSAPP_NUMBER string `env:"TWILIO_WHATSAPP_NUMBER,required"`
+}
+
+// KafkaConfig is what the dead letter and replay commands need next to the service, so they run
+// without the Twilio and database credentials.
+type KafkaConfig struct {
+	KAFKA_BROKERS  []string `env:"KAFKA_BROKERS" default:"localhost:9092" validate:"min=1"`
+	KAFKA_GROUP_ID string   `env:"KAFKA_GROUP_ID" default:"kafka_group"`
+}
+
+// Envs is filled by Load at startup.
+var Envs = &Config{}
+
+// Load reads the config from the environment, CONFIG_FILE and Docker secrets, see sharedconfig.Load.
+func Load() error {
+	return sharedconfig.Load(Envs, sharedconfig.Options{})
+}
+
+// LoadLogging only fills the LOG options of Envs, quietly, so the logger is up before Load logs the config.
+func LoadLogging() error {
+	return sharedconfig.Load(&Envs.LOG, sharedconfig.Options{Quiet: true})
+}
+
+// LoadKafka only fills the KafkaConfig of Envs, quietly, for the command line tools.
+func LoadKafka() error {
+	return sharedconfig.Load(&Envs.KafkaConfig, sharedconfig.Options{Quiet: true})
 }
 
-var Envs = initConfig()
-
-func initConfig() *Config {
-	godotenv.Load("../.env")
-
-	return &Config{
-		NOTIFICATION_SERVICE_PORT: env.GetEnv("NOTIFICATION_SERVICE_PORT", "3007"),
-		TWILIO_ACCOUNT_SID:        env.GetEnv("TWILIO_ACCOUNT_SID", "123"),
-		TWILIO_AUTH_TOKEN:         env.GetEnv("TWILIO_AUTH_TOKEN", "123"),
-		TWILIO_WHATSAPP_NUMBER:    env.GetEnv("TWILIO_WHATSAPP_NUMBER", "123"),
-		VAPID_PUBLIC_KEY:          env.GetEnv("VAPID_PUBLIC_KEY", "123"),
-		VAPID_PRIVATE_KEY:         env.GetEnv("VAPID_PRIVATE_KEY", "123"),
-		VAPID_EMAIL:               env.GetEnv("VAPID_EMAIL", "123@gmail.com"),
-		KAFKA_BROKERS:             env.GetEnvAsSlice("KAFKA_BROKERS", []string{"localhost:9092"}, ","),
-		KAFKA_GROUP_ID:            env.GetEnv("KAFKA_GROUP_ID", "kafka_group"),
-		KAFKA_TOPICS:              env.GetEnvAsSlice("KAFKA_TOPICS", []string{"kafka_topic"}, ","),
-	}
+// LoadTwilio only fills the TwilioConfig of Envs, quietly, for the command line tools.
+func LoadTwilio() error {
+	return sharedconfig.Load(&Envs.TwilioConfig, sharedconfig.Options{Quiet: true})
 }
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
+	"gorm.io/gorm"
+)
+
+func NewPostgresStore() (*gorm.DB, error) {
+	return postgres.Open(postgres.Config{
+		Host:            config.Envs.DB_HOST,
+		Port:            config.Envs.DB_PORT,
+		User:            config.Envs.DB_USER,
+		Password:        config.Envs.DB_PASSWORD,
+		Name:            config.Envs.DB_NAME,
+		SSLMode:         config.Envs.DB_SSL,
+		ApplicationName: "notification-service",
+		Options:         config.Envs.DB_POOL,
+	})
+}
@@ -0,0 +1,18 @@
+package db
+
+import (
+	"embed"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrator applies the migrations embedded from db/migrations, see the migrate subcommand.
+func NewMigrator(database *gorm.DB) (*migrate.Migrator, error) {
+	return migrate.New(database, migrations, "migrations", migrateConfig)
+}
+
+var migrateConfig = migrate.Config{}
@@ -0,0 +1,24 @@
+-- Processed message store used to deduplicate kafka redeliveries.
+-- One row per (handler, message_key), the key is the event id or topic/partition/offset when the event has none.
+-- This is safe to re-run.
+
+CREATE TABLE IF NOT EXISTS processed_messages (
+  handler varchar(100) NOT NULL,
+  message_key varchar(255) NOT NULL,
+  event_id varchar(255),
+  event_type varchar(100),
+  topic varchar(255) NOT NULL,
+  kafka_partition integer NOT NULL,
+  kafka_offset bigint NOT NULL,
+  status varchar(20) NOT NULL,
+  error text,
+  attempts integer NOT NULL DEFAULT 1,
+  duration_ms bigint,
+  started_at timestamptz NOT NULL DEFAULT now(),
+  completed_at timestamptz,
+  expires_at timestamptz NOT NULL,
+  PRIMARY KEY (handler, message_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_messages_expires_at ON processed_messages(expires_at);
+CREATE INDEX IF NOT EXISTS idx_processed_messages_status ON processed_messages(status);
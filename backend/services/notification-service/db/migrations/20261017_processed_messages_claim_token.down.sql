@@ -0,0 +1 @@
+ALTER TABLE processed_messages DROP COLUMN IF EXISTS claim_token;
@@ -0,0 +1,5 @@
+-- The claim token identifies the consumer holding a processing message, so a consumer whose lease ran
+-- out and was taken over cannot record its outcome over that of its successor.
+-- This is safe to re-run.
+
+ALTER TABLE processed_messages ADD COLUMN IF NOT EXISTS claim_token uuid;
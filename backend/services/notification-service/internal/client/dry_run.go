@@ -0,0 +1,24 @@
+package client
+
+import (
+	"log"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/types"
+)
+
+// WhatsAppSender is what handlers need from a WhatsApp client.
+type WhatsAppSender interface {
+	Send(payload types.WhatsAppMessage) error
+}
+
+// DryRunWhatsAppService logs messages instead of sending them, used by replays.
+type DryRunWhatsAppService struct{}
+
+func NewDryRunWhatsAppService() *DryRunWhatsAppService {
+	return &DryRunWhatsAppService{}
+}
+
+func (ws *DryRunWhatsAppService) Send(payload types.WhatsAppMessage) error {
+	log.Printf("dry run: whatsapp to %s: %s", formatPhoneNumber(payload.PhoneNumber), payload.Message)
+	return nil
+}
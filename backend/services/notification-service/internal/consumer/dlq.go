@@ -0,0 +1,159 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
+	kafkaService "github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+)
+
+type DeadLetter struct {
+	Partition int
+	Offset    int64
+	FailedMessage
+}
+
+// ListDeadLetters returns every message currently stored in the dead letter topic of topic, oldest first per partition.
+func ListDeadLetters(ctx context.Context, topic string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+
+	err := kafkaService.ScanTopic(ctx, config.Envs.KAFKA_BROKERS, DeadLetterTopic(topic), func(msg kafkaService.Message) error {
+		letter, err := toDeadLetter(msg)
+		if err != nil {
+			return err
+		}
+
+		letters = append(letters, letter)
+		if limit > 0 && len(letters) >= limit {
+			return kafkaService.ErrStopScan
+		}
+		return nil
+	})
+
+	return letters, err
+}
+
+// Redriver publishes the dead letters of topic back to it. A command run creates one, so every message goes
+// through the same producer, and closes it at the end.
+type Redriver struct {
+	topic    string
+	producer kafkaService.Producer
+}
+
+func NewRedriver(topic string) *Redriver {
+	return &Redriver{
+		topic:    topic,
+		producer: kafkaService.NewProducer(config.Envs.KAFKA_BROKERS, topic),
+	}
+}
+
+func (r *Redriver) Close() error {
+	return r.producer.Close()
+}
+
+// RedriveOne publishes a single dead letter back to its original topic and commits it in the redrive group,
+// so RedriveAll does not send it a second time.
+func (r *Redriver) RedriveOne(ctx context.Context, partition int, offset int64) (DeadLetter, error) {
+	var found *DeadLetter
+
+	err := kafkaService.ScanTopic(ctx, config.Envs.KAFKA_BROKERS, DeadLetterTopic(r.topic), func(msg kafkaService.Message) error {
+		if msg.Partition != partition || msg.Offset != offset {
+			return nil
+		}
+
+		letter, err := toDeadLetter(msg)
+		if err != nil {
+			return err
+		}
+		found = &letter
+		return kafkaService.ErrStopScan
+	})
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	if found == nil {
+		return DeadLetter{}, fmt.Errorf("no dead letter at %s partition %d offset %d", DeadLetterTopic(r.topic), partition, offset)
+	}
+
+	if err := r.redrive(ctx, found.FailedMessage); err != nil {
+		return *found, err
+	}
+
+	//committed only after the message is back in its original topic, messages before it are not skipped
+	//because the group offset never moves back
+	err = kafkaService.CommitOffset(ctx, config.Envs.KAFKA_BROKERS, redriveGroupID(), DeadLetterTopic(r.topic), partition, offset)
+	if err != nil {
+		return *found, fmt.Errorf("re-drove the message but failed to commit it, RedriveAll would send it again: %w", err)
+	}
+
+	return *found, nil
+}
+
+// RedriveAll drains the dead letter topic through its own consumer group, so running it twice never resends a message.
+// It returns once no new dead letter arrived for idleTimeout.
+func (r *Redriver) RedriveAll(ctx context.Context, idleTimeout time.Duration) (int, error) {
+	dlq := kafkaService.NewConsumer(config.Envs.KAFKA_BROKERS, DeadLetterTopic(r.topic), redriveGroupID())
+	defer dlq.Close()
+
+	redriven := 0
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		msg, err := dlq.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return redriven, nil
+			}
+			return redriven, err
+		}
+
+		failed, err := DecodeFailedMessage(msg.Value)
+		if err != nil {
+			return redriven, err
+		}
+
+		if err := r.redrive(ctx, failed); err != nil {
+			return redriven, err
+		}
+
+		//committed only after the message is back in its original topic
+		if err := dlq.CommitMessages(ctx, msg); err != nil {
+			return redriven, err
+		}
+		redriven++
+	}
+}
+
+// redrive publishes the original record with its original headers, so handlers see the same trace and correlation ids.
+func (r *Redriver) redrive(ctx context.Context, failed FailedMessage) error {
+	//the dead letter topic of a topic only holds its own messages, anything else was put there by hand
+	if failed.OriginalTopic != r.topic {
+		return fmt.Errorf("dead letter of %s belongs to %s", r.topic, failed.OriginalTopic)
+	}
+
+	if err := r.producer.PublishMessageWithHeaders(ctx, failed.Key, failed.Value, failed.Headers); err != nil {
+		return fmt.Errorf("failed to redrive message to %s: %w", failed.OriginalTopic, err)
+	}
+
+	return nil
+}
+
+func redriveGroupID() string {
+	return config.Envs.KAFKA_GROUP_ID + ".dlq-redrive"
+}
+
+func toDeadLetter(msg kafkaService.Message) (DeadLetter, error) {
+	failed, err := DecodeFailedMessage(msg.Value)
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("partition %d offset %d: %w", msg.Partition, msg.Offset, err)
+	}
+
+	return DeadLetter{
+		Partition:     msg.Partition,
+		Offset:        msg.Offset,
+		FailedMessage: failed,
+	}, nil
+}
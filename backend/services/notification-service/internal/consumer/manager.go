@@ -2,8 +2,13 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	// "github.com/segmentio/kafka-go"
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/config"
@@ -11,80 +16,467 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	minReadBackoff = 100 * time.Millisecond
+	maxReadBackoff = 30 * time.Second
+
+	statsInterval = 15 * time.Second
+)
+
 type MessageRouter interface {
-	Route(ctx context.Context, topic string, key []byte, message []byte) error
+	Route(ctx context.Context, msg kafkaService.Message) error
+}
+
+type ManagerConfig struct {
+	RetryPolicy       RetryPolicy
+	CommitInterval    time.Duration
+	ConsumersPerTopic int            // defaults to 3
+	TopicConsumers    map[string]int // overrides ConsumersPerTopic for single topics
+	StuckAfter        time.Duration  // a handler running longer than this fails readiness, defaults to 5m
+	Metrics           *Metrics
 }
 
 type Manager struct {
-	router    MessageRouter
-	wg        sync.WaitGroup
-	logger    *zap.Logger
-	consumers []*kafkaService.KafkaConsumer
+	broker  kafkaService.Broker
+	router  MessageRouter
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+	config  ManagerConfig
+	metrics *Metrics
+	running atomic.Bool
+
+	mu        sync.Mutex //guards workers and producers, workers register them concurrently
+	workers   []*worker
+	producers map[string]kafkaService.Producer
+}
+
+// worker is one consumer goroutine, busySince lets readiness spot handlers that never return.
+type worker struct {
+	id        string
+	topic     string
+	consumer  kafkaService.Consumer
+	allowance time.Duration //expected waiting on top of StuckAfter, retry workers hold messages until they are due
+	busySince atomic.Int64
+	stopped   atomic.Bool //stopped on a message it could not commit, see consume
+	closeOnce sync.Once
+	closeErr  error
 }
 
-func NewManager(router MessageRouter, logger *zap.Logger) *Manager {
+// close closes the consumer once, a worker that stopped early is closed again by Shutdown.
+func (w *worker) close() error {
+	w.closeOnce.Do(func() {
+		w.closeErr = w.consumer.Close()
+	})
+
+	return w.closeErr
+}
+
+func NewManager(broker kafkaService.Broker, router MessageRouter, logger *zap.Logger, config ManagerConfig) *Manager {
+	if config.ConsumersPerTopic <= 0 {
+		config.ConsumersPerTopic = 3
+	}
+	if config.StuckAfter <= 0 {
+		config.StuckAfter = 5 * time.Minute
+	}
+
 	return &Manager{
-		router: router,
-		logger: logger,
+		broker:    broker,
+		router:    router,
+		logger:    logger,
+		config:    config,
+		metrics:   config.Metrics,
+		producers: make(map[string]kafkaService.Producer),
 	}
 }
 
-func (m *Manager) consume(ctx context.Context, topic string, consumerId string) {
+// consume fetches, handles and then commits, so a message is only acknowledged once it was handled
+// or safely parked in a retry/dead letter topic. A crash in between redelivers it.
+func (m *Manager) consume(ctx context.Context, w *worker, handle func(ctx context.Context, msg kafkaService.Message) string) {
 	defer m.wg.Done() //says to parent that my job is done, will be called when this consume exits
+	defer m.metrics.workerStopped(w.topic)
 
-	kafka_consumer := kafkaService.NewConsumer(config.Envs.KAFKA_BROKERS, topic, config.Envs.KAFKA_GROUP_ID)
-	m.consumers = append(m.consumers, kafka_consumer) //for one worker append its consumer to then close all consumers all at once
-
+	m.metrics.workerStarted(w.topic)
 	m.logger.Info(
 		"Consumer started",
-		zap.String("topic", topic),
-		zap.String("consumer", consumerId),
+		zap.String("topic", w.topic),
+		zap.String("consumer", w.id),
 	)
+
+	backoff := minReadBackoff
 	for {
-		key, value, err := kafka_consumer.ReadMessage(ctx)
+		msg, err := w.consumer.FetchMessage(ctx)
 		if err != nil {
 			if ctx.Err() != nil { //context was cancelled, should be a clean shutdown, cancel what the worker is doing
 				break
 			}
 
+			m.metrics.fetchFailed(w.topic)
+			m.logger.Warn("Failed to read message",
+				zap.String("topic", w.topic),
+				zap.String("consumer", w.id),
+				zap.Duration("backoff", backoff),
+				zap.Error(err),
+			)
+			if !sleep(ctx, backoff) {
+				break
+			}
+			backoff = min(backoff*2, maxReadBackoff)
 			continue
 		}
+		backoff = minReadBackoff
+		m.metrics.fetched(msg.Topic, msg.Partition, msg.Offset, msg.HighWaterMark)
 
-		if err := m.router.Route(ctx, topic, key, value); err != nil {
-			m.logger.Error("Failed to route message",
-				zap.String("topic", topic),
-				zap.String("consumer", consumerId),
+		w.busySince.Store(time.Now().UnixNano())
+		outcome := handle(ctx, msg)
+		w.busySince.Store(0)
+
+		m.metrics.message(w.topic, outcome)
+		if outcome == outcomeUncommitted {
+			//commits are cumulative, committing any later message of the partition would skip this one for good
+			if ctx.Err() == nil {
+				m.stop(w, msg)
+			}
+			break
+		}
+
+		if err := w.consumer.CommitMessages(ctx, msg); err != nil {
+			m.metrics.commitFailed(w.topic)
+			m.logger.Error("Failed to commit message",
+				zap.String("topic", w.topic),
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
 				zap.Error(err),
 			)
 		}
 	}
 }
 
-func (m *Manager) Run(ctx context.Context, topics []string) {
-	consumersPerTopic := 3
+// stop takes a worker out after a message it could not commit. Closing the consumer leaves the group, so its
+// partitions are handed to the other members from the last committed offset and the message is fetched again.
+// Readiness fails from now on, the remaining workers keep going until the service is restarted.
+func (m *Manager) stop(w *worker, msg kafkaService.Message) {
+	w.stopped.Store(true)
+	m.logger.Error("Stopping consumer on a message that was neither handled nor stored for retry",
+		zap.String("topic", w.topic),
+		zap.String("consumer", w.id),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+	)
+
+	if err := w.close(); err != nil {
+		m.logger.Error("failed to close kafka reader", zap.String("consumer", w.id), zap.Error(err))
+	}
+}
 
+// route calls the router and records handler latency and errors under the topic the worker consumes.
+func (m *Manager) route(ctx context.Context, topic string, msg kafkaService.Message) error {
+	start := time.Now()
+	err := m.router.Route(ctx, msg)
+	m.metrics.handled(topic, time.Since(start), err)
+
+	return err
+}
+
+// handleMessage routes a message from a source topic and parks it in the first retry tier when the handler fails.
+func (m *Manager) handleMessage(ctx context.Context, msg kafkaService.Message) string {
+	if err := m.route(ctx, msg.Topic, msg); err != nil {
+		return m.fail(ctx, newFailedMessage(msg), err)
+	}
+
+	return outcomeHandled
+}
+
+// handleRetry waits until a parked message is due, routes it to its original topic handler
+// and moves it one tier further (or to the dead letter topic) when it fails again.
+func (m *Manager) handleRetry(topic string) func(ctx context.Context, msg kafkaService.Message) string {
+	return func(ctx context.Context, msg kafkaService.Message) string {
+		failed, err := DecodeFailedMessage(msg.Value)
+		if err != nil {
+			//nothing to retry, keep the raw record in the dead letter topic so it can still be inspected
+			invalid := newFailedMessage(msg)
+			invalid.Error = err.Error()
+			invalid.FirstFailedAt = time.Now()
+			invalid.LastFailedAt = invalid.FirstFailedAt
+			if !m.publish(ctx, DeadLetterTopic(topic), msg.Key, invalid) {
+				return outcomeUncommitted
+			}
+			return outcomeDeadLetter
+		}
+
+		if !sleep(ctx, time.Until(failed.NotBefore)) {
+			return outcomeUncommitted
+		}
+
+		if err := m.route(ctx, msg.Topic, failed.Message()); err != nil {
+			return m.fail(ctx, failed, err)
+		}
+
+		m.logger.Info("Retried message succeeded",
+			zap.String("topic", failed.OriginalTopic),
+			zap.Int("attempt", failed.Attempt+1),
+		)
+		return outcomeHandled
+	}
+}
+
+// fail moves a message to its next retry tier or the dead letter topic and returns the outcome,
+// outcomeUncommitted when it could not be stored.
+func (m *Manager) fail(ctx context.Context, failed FailedMessage, cause error) string {
+	now := time.Now()
+	failed.Attempt++
+	failed.Error = cause.Error()
+	failed.LastFailedAt = now
+	if failed.FirstFailedAt.IsZero() {
+		failed.FirstFailedAt = now
+	}
+
+	outcome := outcomeRetried
+	target, delay, retry := m.config.RetryPolicy.Next(failed.OriginalTopic, failed.Attempt)
+	if retry {
+		failed.NotBefore = now.Add(delay)
+		m.logger.Warn("Failed to route message, scheduling retry",
+			zap.String("topic", failed.OriginalTopic),
+			zap.String("retry_topic", target),
+			zap.Int("attempt", failed.Attempt),
+			zap.Error(cause),
+		)
+	} else {
+		outcome = outcomeDeadLetter
+		failed.NotBefore = time.Time{}
+		m.logger.Error("Failed to route message, moving to dead letter topic",
+			zap.String("topic", failed.OriginalTopic),
+			zap.String("dlq_topic", target),
+			zap.Int("attempts", failed.Attempt),
+			zap.Error(cause),
+		)
+	}
+
+	if !m.publish(ctx, target, failed.Key, failed) {
+		return outcomeUncommitted
+	}
+	return outcome
+}
+
+// publish keeps retrying until the failed message is stored or the manager shuts down, a message is never dropped on purpose.
+func (m *Manager) publish(ctx context.Context, topic string, key []byte, failed FailedMessage) bool {
+	value, err := failed.Encode()
+	if err != nil {
+		m.logger.Error("Failed to encode failed message", zap.String("topic", topic), zap.Error(err))
+		return false
+	}
+
+	producer := m.producer(topic)
+	backoff := minReadBackoff
+	for {
+		err := producer.PublishMessage(ctx, key, value)
+		if err == nil {
+			return true
+		}
+
+		m.logger.Error("Failed to publish failed message",
+			zap.String("topic", topic),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		if !sleep(ctx, backoff) {
+			m.logger.Error("Shutting down before failed message was stored",
+				zap.String("topic", topic),
+				zap.String("original_topic", failed.OriginalTopic),
+			)
+			return false
+		}
+		backoff = min(backoff*2, maxReadBackoff)
+	}
+}
+
+func (m *Manager) producer(topic string) kafkaService.Producer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	producer, ok := m.producers[topic]
+	if !ok {
+		producer = m.broker.NewProducer(topic)
+		m.producers[topic] = producer
+	}
+
+	return producer
+}
+
+// start registers a worker for topic and runs it in its own goroutine.
+func (m *Manager) start(ctx context.Context, topic string, id string, allowance time.Duration, handle func(ctx context.Context, msg kafkaService.Message) string) {
+	w := &worker{
+		id:    id,
+		topic: topic,
+		consumer: m.broker.NewConsumer(kafkaService.ConsumerConfig{
+			Topic:          topic,
+			GroupID:        config.Envs.KAFKA_GROUP_ID,
+			CommitInterval: m.config.CommitInterval,
+		}),
+		allowance: allowance,
+	}
+
+	m.mu.Lock()
+	m.workers = append(m.workers, w) //for one worker append its consumer to then close all consumers all at once
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.consume(ctx, w, handle)
+}
+
+func (m *Manager) consumersFor(topic string) int {
+	if consumers, ok := m.config.TopicConsumers[topic]; ok && consumers > 0 {
+		return consumers
+	}
+
+	return m.config.ConsumersPerTopic
+}
+
+func (m *Manager) Run(ctx context.Context, topics []string) {
 	for _, topic := range topics {
+		consumers := m.consumersFor(topic)
 		m.logger.Info("Starting consumers for topic",
 			zap.String("topic", topic),
-			zap.Int("consumers", consumersPerTopic),
+			zap.Int("consumers", consumers),
 		)
-		for i := 0; i < consumersPerTopic; i++ {
-			m.wg.Add(1)
+		for i := 0; i < consumers; i++ {
+			m.start(ctx, topic, fmt.Sprintf("%s-consumer-%d", topic, i), 0, m.handleMessage)
+		}
+
+		//one consumer per tier is enough, messages in a tier are already ordered by due time
+		for retryTopic, delay := range m.config.RetryPolicy.RetryTopics(topic) {
+			m.start(ctx, retryTopic, fmt.Sprintf("%s-consumer", retryTopic), delay, m.handleRetry(topic))
+		}
+	}
+
+	m.wg.Add(1)
+	go m.collectStats(ctx)
+
+	m.running.Store(true)
+}
+
+// collectStats turns the reader snapshots into rebalance metrics until ctx is cancelled.
+func (m *Manager) collectStats(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		for _, w := range m.workers {
+			stats := w.consumer.Stats()
+			if stats.Rebalances > 0 {
+				m.metrics.rebalanced(w.topic, stats.Rebalances)
+				m.logger.Info("Consumer group rebalanced",
+					zap.String("topic", w.topic),
+					zap.String("consumer", w.id),
+					zap.Int64("rebalances", stats.Rebalances),
+				)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// Ready fails while the consumers are not running, the broker cannot be reached or a handler is stuck.
+func (m *Manager) Ready(ctx context.Context) error {
+	if !m.running.Load() {
+		return errors.New("consumers are not running")
+	}
 
-			go m.consume(ctx, topic, fmt.Sprintf("%s-consumer-%d", topic, i))
+	if err := m.broker.Ping(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.workers {
+		if w.stopped.Load() {
+			return fmt.Errorf("consumer %s stopped on a message it could not commit", w.id)
+		}
+
+		busySince := w.busySince.Load()
+		if busySince == 0 {
+			continue
+		}
+
+		if busy := time.Since(time.Unix(0, busySince)); busy > m.config.StuckAfter+w.allowance {
+			return fmt.Errorf("consumer %s is stuck on a message for %s", w.id, busy.Round(time.Second))
 		}
 	}
+
+	return nil
 }
 
 func (m *Manager) Shutdown() {
 	m.logger.Info("shutting down consumer manager")
+	m.running.Store(false)
 
-	for _, r := range m.consumers {
-		if err := r.Close(); err != nil {
+	m.mu.Lock()
+	for _, w := range m.workers {
+		if err := w.close(); err != nil {
 			m.logger.Error("failed to close kafka reader", zap.Error(err))
 		}
 	}
+	m.mu.Unlock()
 
 	m.wg.Wait() //waits for all go routines to call Done()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for topic, p := range m.producers {
+		if err := p.Flush(flushCtx); err != nil {
+			m.logger.Error("failed to flush kafka writer", zap.String("topic", topic), zap.Error(err))
+		}
+		if err := p.Close(); err != nil {
+			m.logger.Error("failed to close kafka writer", zap.String("topic", topic), zap.Error(err))
+		}
+	}
 	m.logger.Info("all workers have shut down")
 }
+
+// sleep waits for d or until ctx is cancelled, it reports whether the full duration elapsed.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// ParseTopicConsumers reads per-topic consumer counts written as topic=count.
+func ParseTopicConsumers(values []string) (map[string]int, error) {
+	consumers := make(map[string]int, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		topic, count, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid topic consumers %q, expected topic=count", value)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid consumer count in %q", value)
+		}
+		consumers[topic] = n
+	}
+
+	return consumers, nil
+}
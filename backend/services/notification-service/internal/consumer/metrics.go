@@ -0,0 +1,153 @@
+package consumer
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	outcomeHandled     = "handled"
+	outcomeRetried     = "retried"
+	outcomeDeadLetter  = "dead_lettered"
+	outcomeUncommitted = "uncommitted"
+)
+
+// Metrics are the consumer metrics served on /metrics. A nil *Metrics records nothing.
+type Metrics struct {
+	messages        *prometheus.CounterVec
+	handlerErrors   *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	lag             *prometheus.GaugeVec
+	rebalances      *prometheus.CounterVec
+	fetchErrors     *prometheus.CounterVec
+	commitErrors    *prometheus.CounterVec
+	workers         *prometheus.GaugeVec
+}
+
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	metrics := &Metrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "messages_total",
+			Help:      "Messages fetched per topic by outcome: handled, retried, dead_lettered or uncommitted.",
+		}, []string{"topic", "outcome"}),
+		handlerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "handler_errors_total",
+			Help:      "Handler calls that returned an error.",
+		}, []string{"topic"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "handler_duration_seconds",
+			Help:      "Time spent in handlers per message.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"topic"}),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "lag",
+			Help:      "Messages behind the partition end at the last fetch.",
+		}, []string{"topic", "partition"}),
+		rebalances: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "rebalances_total",
+			Help:      "Consumer group rebalances seen by the consumers of a topic.",
+		}, []string{"topic"}),
+		fetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "fetch_errors_total",
+			Help:      "Failed fetches from kafka.",
+		}, []string{"topic"}),
+		commitErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "commit_errors_total",
+			Help:      "Failed offset commits.",
+		}, []string{"topic"}),
+		workers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "notification",
+			Subsystem: "consumer",
+			Name:      "workers",
+			Help:      "Running consumer goroutines per topic.",
+		}, []string{"topic"}),
+	}
+
+	registerer.MustRegister(
+		metrics.messages,
+		metrics.handlerErrors,
+		metrics.handlerDuration,
+		metrics.lag,
+		metrics.rebalances,
+		metrics.fetchErrors,
+		metrics.commitErrors,
+		metrics.workers,
+	)
+
+	return metrics
+}
+
+func (m *Metrics) message(topic string, outcome string) {
+	if m == nil {
+		return
+	}
+	m.messages.WithLabelValues(topic, outcome).Inc()
+}
+
+func (m *Metrics) handled(topic string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.handlerDuration.WithLabelValues(topic).Observe(duration.Seconds())
+	if err != nil {
+		m.handlerErrors.WithLabelValues(topic).Inc()
+	}
+}
+
+func (m *Metrics) fetched(topic string, partition int, offset int64, highWaterMark int64) {
+	if m == nil || highWaterMark <= 0 {
+		return
+	}
+	m.lag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(max(highWaterMark-offset-1, 0)))
+}
+
+func (m *Metrics) rebalanced(topic string, count int64) {
+	if m == nil || count <= 0 {
+		return
+	}
+	m.rebalances.WithLabelValues(topic).Add(float64(count))
+}
+
+func (m *Metrics) fetchFailed(topic string) {
+	if m == nil {
+		return
+	}
+	m.fetchErrors.WithLabelValues(topic).Inc()
+}
+
+func (m *Metrics) commitFailed(topic string) {
+	if m == nil {
+		return
+	}
+	m.commitErrors.WithLabelValues(topic).Inc()
+}
+
+func (m *Metrics) workerStarted(topic string) {
+	if m == nil {
+		return
+	}
+	m.workers.WithLabelValues(topic).Inc()
+}
+
+func (m *Metrics) workerStopped(topic string) {
+	if m == nil {
+		return
+	}
+	m.workers.WithLabelValues(topic).Dec()
+}
@@ -0,0 +1,137 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	kafkaService "github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+)
+
+// FailedMessage is what the manager publishes to retry and dead letter topics.
+// It carries the original record untouched plus enough metadata to retry it or explain why it was given up on.
+type FailedMessage struct {
+	OriginalTopic     string            `json:"originalTopic"`
+	OriginalPartition int               `json:"originalPartition"`
+	OriginalOffset    int64             `json:"originalOffset"`
+	Key               []byte            `json:"key"`
+	Value             []byte            `json:"value"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	Attempt           int               `json:"attempt"`
+	Error             string            `json:"error"`
+	FirstFailedAt     time.Time         `json:"firstFailedAt"`
+	LastFailedAt      time.Time         `json:"lastFailedAt"`
+	NotBefore         time.Time         `json:"notBefore"` //retry consumers hold the message until this time
+}
+
+func newFailedMessage(msg kafkaService.Message) FailedMessage {
+	return FailedMessage{
+		OriginalTopic:     msg.Topic,
+		OriginalPartition: msg.Partition,
+		OriginalOffset:    msg.Offset,
+		Key:               msg.Key,
+		Value:             msg.Value,
+		Headers:           msg.Headers,
+	}
+}
+
+// Message rebuilds the record as it was first consumed, handlers see the same topic, position and headers on every attempt.
+func (f FailedMessage) Message() kafkaService.Message {
+	return kafkaService.Message{
+		Topic:     f.OriginalTopic,
+		Partition: f.OriginalPartition,
+		Offset:    f.OriginalOffset,
+		Key:       f.Key,
+		Value:     f.Value,
+		Headers:   f.Headers,
+	}
+}
+
+func (f FailedMessage) Encode() ([]byte, error) {
+	return json.Marshal(f)
+}
+
+func DecodeFailedMessage(value []byte) (FailedMessage, error) {
+	var failed FailedMessage
+	if err := json.Unmarshal(value, &failed); err != nil {
+		return FailedMessage{}, fmt.Errorf("invalid failed message: %w", err)
+	}
+
+	if failed.OriginalTopic == "" {
+		return FailedMessage{}, fmt.Errorf("invalid failed message: missing originalTopic")
+	}
+
+	return failed, nil
+}
+
+// RetryPolicy maps the attempt number of a failed message to the topic it is parked in next.
+// Attempt n (starting at 1) goes to the n-th tier; once the tiers are exhausted it goes to the dead letter topic.
+type RetryPolicy struct {
+	Tiers []time.Duration
+}
+
+func (p RetryPolicy) Next(topic string, attempt int) (string, time.Duration, bool) {
+	if attempt > len(p.Tiers) {
+		return DeadLetterTopic(topic), 0, false
+	}
+
+	delay := p.Tiers[attempt-1]
+	return RetryTopic(topic, delay), delay, true
+}
+
+func (p RetryPolicy) RetryTopics(topic string) map[string]time.Duration {
+	topics := make(map[string]time.Duration, len(p.Tiers))
+	for _, delay := range p.Tiers {
+		topics[RetryTopic(topic, delay)] = delay
+	}
+
+	return topics
+}
+
+func ParseRetryTiers(values []string) ([]time.Duration, error) {
+	tiers := make([]time.Duration, 0, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		delay, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry tier %q: %w", value, err)
+		}
+		if delay <= 0 {
+			return nil, fmt.Errorf("retry tier %q must be positive", value)
+		}
+		tiers = append(tiers, delay)
+	}
+
+	return tiers, nil
+}
+
+// RetryTopic names a tier topic after its delay, e.g. order_event.retry.1m or order_event.retry.1h30m.
+func RetryTopic(topic string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", topic, formatDelay(delay))
+}
+
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+func formatDelay(delay time.Duration) string {
+	hours := int(delay / time.Hour)
+	minutes := int(delay % time.Hour / time.Minute)
+	seconds := int(delay % time.Minute / time.Second)
+
+	formatted := ""
+	if hours > 0 {
+		formatted += fmt.Sprintf("%dh", hours)
+	}
+	if minutes > 0 {
+		formatted += fmt.Sprintf("%dm", minutes)
+	}
+	if seconds > 0 || formatted == "" {
+		formatted += fmt.Sprintf("%ds", seconds)
+	}
+
+	return formatted
+}
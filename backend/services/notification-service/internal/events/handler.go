@@ -1,7 +1,12 @@
 package events
 
-import "context"
+import (
+	"context"
 
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+)
+
+// Handler receives the decoded envelope together with the raw message it came from (topic, partition, offset, headers).
 type Handler interface {
-	Handle(ctx context.Context, key []byte, message []byte) error
+	Handle(ctx context.Context, msg kafka.Message, envelope kafka.Envelope) error
 }
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+)
+
+// ErrMessageInProgress is returned when another consumer holds the processing lease of the same message.
+// The manager treats it like any failure, so the message is retried after the lease had time to finish.
+var ErrMessageInProgress = errors.New("message is already being processed")
+
+type ProcessedMessageStore interface {
+	Claim(ctx context.Context, message models.ProcessedMessage, lease time.Duration) (string, *models.ProcessedMessage, error)
+	Complete(ctx context.Context, handler string, messageKey string, token string, status models.ProcessedMessageStatus, handleErr error, duration time.Duration, ttl time.Duration) error
+}
+
+// IdempotentHandler wraps a Handler so every message is handled at most once successfully within ttl.
+// Messages are keyed by event id, or by topic/partition/offset when the envelope has none.
+type IdempotentHandler struct {
+	name    string
+	handler Handler
+	store   ProcessedMessageStore
+	ttl     time.Duration
+	lease   time.Duration
+}
+
+// NewIdempotentHandler wraps handler under name, the name keeps the records of different handlers apart.
+// lease bounds how long a crashed consumer can block a message before another one takes it over.
+func NewIdempotentHandler(name string, handler Handler, store ProcessedMessageStore, ttl time.Duration, lease time.Duration) *IdempotentHandler {
+	return &IdempotentHandler{
+		name:    name,
+		handler: handler,
+		store:   store,
+		ttl:     ttl,
+		lease:   lease,
+	}
+}
+
+func (h *IdempotentHandler) Handle(ctx context.Context, msg kafka.Message, envelope kafka.Envelope) error {
+	record := processedMessage(h.name, msg, envelope)
+
+	token, existing, err := h.store.Claim(ctx, record, h.lease)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		if existing.Status == models.ProcessedMessageSucceeded {
+			logging.FromContext(ctx).Info("skipping duplicate message", zap.String("handler", h.name), zap.String("message_key", record.MessageKey))
+			return nil
+		}
+		return fmt.Errorf("%s: %s: %w", h.name, record.MessageKey, ErrMessageInProgress)
+	}
+
+	start := time.Now()
+	handleErr := h.handler.Handle(ctx, msg, envelope)
+	duration := time.Since(start)
+
+	status := models.ProcessedMessageSucceeded
+	if handleErr != nil {
+		status = models.ProcessedMessageFailed
+	}
+
+	//the outcome is recorded even when shutdown cancelled ctx, otherwise a sent notification would be sent again
+	if err := h.store.Complete(context.WithoutCancel(ctx), h.name, record.MessageKey, token, status, handleErr, duration, h.ttl); err != nil {
+		//the handler already ran, failing here would only trigger a retry of something that succeeded, and a lost
+		//claim belongs to the consumer that took the message over after the lease ran out
+		logging.FromContext(ctx).Error("failed to record message outcome", zap.String("handler", h.name), zap.String("message_key", record.MessageKey), zap.Error(err))
+	}
+
+	return handleErr
+}
+
+func processedMessage(handler string, msg kafka.Message, envelope kafka.Envelope) models.ProcessedMessage {
+	record := models.ProcessedMessage{
+		Handler:    handler,
+		MessageKey: fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset),
+		Topic:      msg.Topic,
+		Partition:  msg.Partition,
+		Offset:     msg.Offset,
+	}
+
+	if envelope.EventID != "" {
+		eventID := envelope.EventID
+		record.MessageKey = eventID
+		record.EventID = &eventID
+	}
+	if envelope.EventType != "" {
+		eventType := string(envelope.EventType)
+		record.EventType = &eventType
+	}
+
+	return record
+}
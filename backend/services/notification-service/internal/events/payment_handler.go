@@ -2,28 +2,97 @@ package events
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/internal/client"
 	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
 )
 
 type PaymentHandler struct {
-	notifier client.WhatsAppService
+	notifier client.WhatsAppSender
 }
 
-func NewPaymentHandler(notifier client.WhatsAppService) *PaymentHandler {
+func NewPaymentHandler(notifier client.WhatsAppSender) *PaymentHandler {
 	return &PaymentHandler{
 		notifier: notifier,
 	}
 }
 
-func (h *PaymentHandler) Handle(ctx context.Context, key []byte, message []byte) error {
-	log.Printf("key: %s, value: %s", string(key), string(message))
-	whatsAppPayload := types.WhatsAppMessage{
-		UserId: string(key), PhoneNumber: "08119883223", Message: string(message),
+func (h *PaymentHandler) Handle(ctx context.Context, msg kafka.Message, envelope kafka.Envelope) error {
+	logger := logging.FromContext(ctx).With(
+		zap.String("event_type", string(envelope.EventType)),
+		zap.String("event_id", envelope.EventID),
+		zap.String("topic", msg.Topic),
+		zap.Int("partition", msg.Partition),
+		zap.Int64("offset", msg.Offset),
+	)
+	logger.Info("handling event")
+
+	whatsAppPayload, err := h.buildMessage(envelope)
+	if err != nil {
+		return err
+	}
+	if whatsAppPayload == nil {
+		logger.Info("no whatsapp notification for event type")
+		return nil
+	}
+
+	if err := h.notifier.Send(*whatsAppPayload); err != nil {
+		return err
 	}
-	h.notifier.Send(whatsAppPayload)
-	log.Print("sent to whatsapp")
+	logger.Info("sent to whatsapp")
 	return nil
 }
+
+func (h *PaymentHandler) buildMessage(envelope kafka.Envelope) (*types.WhatsAppMessage, error) {
+	switch envelope.EventType {
+	case kafka.EventOrderCreated:
+		event, err := kafka.DecodePayload[kafka.OrderCreatedEvent](envelope)
+		if err != nil {
+			return nil, err
+		}
+		return &types.WhatsAppMessage{
+			UserId:      event.UserID,
+			PhoneNumber: event.CustomerPhone,
+			Message:     fmt.Sprintf("Your order %s has been created. Total: %s %.0f", event.OrderNumber, event.Currency, event.TotalAmount),
+		}, nil
+
+	case kafka.EventOrderPaid:
+		event, err := kafka.DecodePayload[kafka.OrderPaidEvent](envelope)
+		if err != nil {
+			return nil, err
+		}
+		return &types.WhatsAppMessage{
+			UserId:      event.UserID,
+			PhoneNumber: event.CustomerPhone,
+			Message:     fmt.Sprintf("Payment for order %s has been received. Thank you!", event.OrderNumber),
+		}, nil
+
+	case kafka.EventOrderCancelled:
+		event, err := kafka.DecodePayload[kafka.OrderCancelledEvent](envelope)
+		if err != nil {
+			return nil, err
+		}
+		return &types.WhatsAppMessage{
+			UserId:      event.UserID,
+			PhoneNumber: event.CustomerPhone,
+			Message:     fmt.Sprintf("Your order %s has been cancelled.", event.OrderNumber),
+		}, nil
+
+	case kafka.EventPaymentSettled:
+		event, err := kafka.DecodePayload[kafka.PaymentSettledEvent](envelope)
+		if err != nil {
+			return nil, err
+		}
+		return &types.WhatsAppMessage{
+			UserId:      event.UserID,
+			PhoneNumber: event.CustomerPhone,
+			Message:     fmt.Sprintf("Payment %s of %s %.0f has been settled.", event.PaymentNumber, event.Currency, event.Amount),
+		}, nil
+	}
+
+	return nil, nil
+}
@@ -3,6 +3,9 @@ package events
 import (
 	"context"
 	"fmt"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
 )
 
 type Router struct {
@@ -19,11 +22,27 @@ func (r *Router) Register(topic string, handler Handler) {
 	r.handlers[topic] = handler
 }
 
-func (r *Router) Route(ctx context.Context, topic string, key []byte, message []byte) error {
-	handler, ok := r.handlers[topic]
+func (r *Router) Route(ctx context.Context, msg kafka.Message) error {
+	handler, ok := r.handlers[msg.Topic]
 	if !ok {
-		return fmt.Errorf("no handler registered for this topic: %s", topic)
+		return fmt.Errorf("no handler registered for this topic: %s", msg.Topic)
+	}
+
+	envelope, err := kafka.DecodeEnvelope(msg.Value)
+	if err != nil {
+		return fmt.Errorf("topic %s, partition %d, offset %d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+	}
+
+	return handler.Handle(messageContext(ctx, msg, envelope), msg, envelope)
+}
+
+// messageContext restores the trace context the producer attached, so handler logs join the originating request.
+// Messages from producers that do not send headers yet still get the correlation id of their envelope.
+func messageContext(ctx context.Context, msg kafka.Message, envelope kafka.Envelope) context.Context {
+	ctx = kafka.ContextFromHeaders(ctx, msg)
+	if msg.Headers[tracing.CorrelationIDHeader] == "" && envelope.CorrelationID != "" {
+		ctx = tracing.WithCorrelationID(ctx, envelope.CorrelationID)
 	}
 
-	return handler.Handle(ctx, key, message)
+	return ctx
 }
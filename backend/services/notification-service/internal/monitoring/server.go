@@ -0,0 +1,103 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Check is a named readiness check. Its error is logged, /readyz only names the failing check.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Server serves /livez and /readyz for the consumer process, which has no other HTTP surface, and
+// /metrics on a separate listener that stays off the public load balancer.
+type Server struct {
+	server        *http.Server
+	metricsServer *http.Server
+}
+
+// NewServer serves the probes on addr and /metrics on metricsAddr, empty disables the metrics listener.
+func NewServer(addr string, metricsAddr string, checks ...Check) *Server {
+	mux := http.NewServeMux()
+
+	//the process answers, so it is alive, restarting it would not fix a broker outage
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		for _, check := range checks {
+			if err := check.Check(ctx); err != nil {
+				logging.L().Warn("readiness check failed", zap.String("check", check.Name), zap.Error(err))
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "check": check.Name})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	var metricsServer *http.Server
+	if metricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", promhttp.Handler())
+		metricsServer = &http.Server{
+			Addr:              metricsAddr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+	}
+
+	return &Server{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		metricsServer: metricsServer,
+	}
+}
+
+// Start blocks until the server fails or is shut down, a shutdown is not reported as an error.
+// The metrics listener runs alongside, its failure is logged but keeps the probes up.
+func (s *Server) Start() error {
+	if s.metricsServer != nil {
+		go func() {
+			if err := s.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logging.L().Error("metrics listener failed", zap.String("addr", s.metricsServer.Addr), zap.Error(err))
+			}
+		}()
+	}
+
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if s.metricsServer != nil {
+		err = errors.Join(err, s.metricsServer.Shutdown(ctx))
+	}
+
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
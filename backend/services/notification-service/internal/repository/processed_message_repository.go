@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/notification-service/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrClaimLost is returned when the lease of a claim ran out and another consumer took the message over.
+var ErrClaimLost = errors.New("processed message was claimed by another consumer")
+
+type ProcessedMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewProcessedMessageRepository(db *gorm.DB) *ProcessedMessageRepository {
+	return &ProcessedMessageRepository{db: db}
+}
+
+// Claim marks a message as being processed by a handler for the length of lease and returns the claim token
+// that Complete needs. It only succeeds when the message was never seen, failed before, or its previous lease or
+// dedup window expired. Otherwise the token is empty and the stored record is returned.
+func (r *ProcessedMessageRepository) Claim(ctx context.Context, message models.ProcessedMessage, lease time.Duration) (string, *models.ProcessedMessage, error) {
+	now := time.Now()
+	token := uuid.NewString()
+
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO processed_messages
+			(handler, message_key, event_id, event_type, topic, kafka_partition, kafka_offset, status, claim_token, attempts, started_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT (handler, message_key) DO UPDATE SET
+			topic = EXCLUDED.topic,
+			kafka_partition = EXCLUDED.kafka_partition,
+			kafka_offset = EXCLUDED.kafka_offset,
+			status = EXCLUDED.status,
+			claim_token = EXCLUDED.claim_token,
+			error = NULL,
+			duration_ms = NULL,
+			attempts = processed_messages.attempts + 1,
+			started_at = EXCLUDED.started_at,
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+		WHERE processed_messages.status = ? OR processed_messages.expires_at < ?`,
+		message.Handler, message.MessageKey, message.EventID, message.EventType,
+		message.Topic, message.Partition, message.Offset,
+		models.ProcessedMessageProcessing, token, now, now.Add(lease),
+		models.ProcessedMessageFailed, now,
+	)
+	if result.Error != nil {
+		return "", nil, fmt.Errorf("failed to claim processed message: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return token, nil, nil
+	}
+
+	var existing models.ProcessedMessage
+	err := r.db.WithContext(ctx).
+		Where("handler = ? AND message_key = ?", message.Handler, message.MessageKey).
+		First(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, fmt.Errorf("processed message %s disappeared while claiming", message.MessageKey)
+		}
+		return "", nil, fmt.Errorf("failed to get processed message: %w", err)
+	}
+
+	return "", &existing, nil
+}
+
+// Complete stores the outcome of a claimed message and starts its dedup window of ttl. A claim whose lease ran
+// out and was taken over is left to its new owner.
+func (r *ProcessedMessageRepository) Complete(ctx context.Context, handler string, messageKey string, token string, status models.ProcessedMessageStatus, handleErr error, duration time.Duration, ttl time.Duration) error {
+	now := time.Now()
+	durationMs := duration.Milliseconds()
+
+	var errMessage *string
+	if handleErr != nil {
+		message := handleErr.Error()
+		errMessage = &message
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&models.ProcessedMessage{}).
+		Where("handler = ? AND message_key = ? AND status = ? AND claim_token = ?", handler, messageKey, models.ProcessedMessageProcessing, token).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"error":        errMessage,
+			"duration_ms":  durationMs,
+			"completed_at": now,
+			"expires_at":   now.Add(ttl),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to complete processed message: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+// DeleteExpired removes records whose dedup window or processing lease is over, Claim would take them over anyway.
+func (r *ProcessedMessageRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.ProcessedMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired processed messages: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
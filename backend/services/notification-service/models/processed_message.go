@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type ProcessedMessageStatus string
+
+const (
+	ProcessedMessageProcessing ProcessedMessageStatus = "processing"
+	ProcessedMessageSucceeded  ProcessedMessageStatus = "succeeded"
+	ProcessedMessageFailed     ProcessedMessageStatus = "failed"
+)
+
+// ProcessedMessage records that a handler has seen a message and how handling it went.
+// ExpiresAt is the end of the processing lease while in progress, and the end of the dedup window once completed.
+type ProcessedMessage struct {
+	Handler     string                 `gorm:"column:handler;primaryKey" json:"handler"`
+	MessageKey  string                 `gorm:"column:message_key;primaryKey" json:"messageKey"`
+	EventID     *string                `gorm:"column:event_id" json:"eventId"`
+	EventType   *string                `gorm:"column:event_type" json:"eventType"`
+	Topic       string                 `gorm:"column:topic" json:"topic"`
+	Partition   int                    `gorm:"column:kafka_partition" json:"partition"`
+	Offset      int64                  `gorm:"column:kafka_offset" json:"offset"`
+	Status      ProcessedMessageStatus `gorm:"column:status" json:"status"`
+	ClaimToken  *string                `gorm:"column:claim_token" json:"-"`
+	Error       *string                `gorm:"column:error" json:"error"`
+	Attempts    int                    `gorm:"column:attempts" json:"attempts"`
+	DurationMs  *int64                 `gorm:"column:duration_ms" json:"durationMs"`
+	StartedAt   time.Time              `gorm:"column:started_at" json:"startedAt"`
+	CompletedAt *time.Time             `gorm:"column:completed_at" json:"completedAt"`
+	ExpiresAt   time.Time              `gorm:"column:expires_at" json:"expiresAt"`
+}
+
+func (ProcessedMessage) TableName() string {
+	return "processed_messages"
+}
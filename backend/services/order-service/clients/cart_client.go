@@ -1,16 +1,19 @@
 package clients
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"os"
+	"net/url"
 	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/httpclient"
 )
 
+const cartServiceName = "cart-service"
+
 type CartClient struct {
-	baseURL    string
-	httpClient *http.Client
+	client *httpclient.Client
 }
 
 type CartResponse struct {
@@ -40,58 +43,43 @@ type CartItem struct {
 	IsAvailable         bool    `json:"is_available"`
 }
 
-func NewCartClient() *CartClient {
-	baseURL := os.Getenv("CART_SERVICE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:3003"
-	}
-
-	return &CartClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
+type CartClientConfig struct {
+	BaseURL     string
+	ServiceAuth auth.ServiceAuthOptions
 }
 
-func (c *CartClient) GetCart(userID string) (*CartResponse, error) {
-	url := fmt.Sprintf("%s/api/cart/%s", c.baseURL, userID)
-
-	resp, err := c.httpClient.Get(url)
+// NewCartClient fails on malformed SERVICE_KEYS rather than falling back to legacy tokens,
+// which cart-service rejects unless it still allows them.
+func NewCartClient(config CartClientConfig) (*CartClient, error) {
+	signer, err := config.ServiceAuth.Signer("order-service")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cart service returned status: %d", resp.StatusCode)
-	}
+	return &CartClient{
+		client: httpclient.New(httpclient.ClientConfig{
+			Target:  cartServiceName,
+			BaseURL: config.BaseURL,
+			Timeout: 10 * time.Second,
+			Signer:  signer,
+		}),
+	}, nil
+}
 
+// HealthURL is the liveness endpoint of cart-service, for readiness checks.
+func (c *CartClient) HealthURL() string {
+	return c.client.BaseURL() + "/livez"
+}
+
+func (c *CartClient) GetCart(ctx context.Context, userID string) (*CartResponse, error) {
 	var cart CartResponse
-	if err := json.NewDecoder(resp.Body).Decode(&cart); err != nil {
+	if err := c.client.Get(ctx, fmt.Sprintf("/internal/cart/checkout/%s", url.PathEscape(userID)), &cart); err != nil {
 		return nil, err
 	}
 
 	return &cart, nil
 }
 
-func (c *CartClient) ClearCart(userID string) error {
-	url := fmt.Sprintf("%s/api/cart/%s", c.baseURL, userID)
-
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("failed to clear cart: status %d", resp.StatusCode)
-	}
-
-	return nil
+func (c *CartClient) ClearCart(ctx context.Context, userID string) error {
+	return c.client.Delete(ctx, fmt.Sprintf("/internal/cart/checkout/%s", url.PathEscape(userID)), nil)
 }
@@ -1,20 +1,15 @@
 package clients
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"os"
+	"context"
 	"time"
 
-	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/httpclient"
 )
 
 type PaymentClient struct {
-	baseURL       string
-	httpClient    *http.Client
-	serviceSecret string
+	client *httpclient.Client
 }
 
 type CreatePaymentRequest struct {
@@ -33,53 +28,41 @@ type PaymentResponse struct {
 	InvoiceURL    *string `json:"invoiceUrl"`
 }
 
-func NewPaymentClient() *PaymentClient {
-	baseURL := os.Getenv("PAYMENT_SERVICE_URL")
-	if baseURL == "" {
-		baseURL = "http://localhost:3007"
-	}
-	serviceSecret := os.Getenv("SERVICE_SECRET")
-
-	return &PaymentClient{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		serviceSecret: serviceSecret,
-	}
+type PaymentClientConfig struct {
+	BaseURL     string
+	ServiceAuth auth.ServiceAuthOptions
 }
 
-func (c *PaymentClient) CreatePayment(req CreatePaymentRequest) (*PaymentResponse, error) {
-	url := fmt.Sprintf("%s/api/payments", c.baseURL)
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
-	}
-
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+// NewPaymentClient fails without SERVICE_SECRET, payment-service only verifies legacy tokens.
+func NewPaymentClient(config PaymentClientConfig) (*PaymentClient, error) {
+	signer, err := config.ServiceAuth.LegacySigner("order-service")
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-service-name", "order-service")
-	httpReq.Header.Set("x-service-auth", utils.GenerateServiceToken("order-service", c.serviceSecret))
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	return &PaymentClient{
+		client: httpclient.New(httpclient.ClientConfig{
+			Target:  "payment-service",
+			BaseURL: config.BaseURL,
+			Timeout: 30 * time.Second,
+			Signer:  signer,
+		}),
+	}, nil
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("payment service returned status: %d", resp.StatusCode)
-	}
+// HealthURL is the health endpoint of payment-service, for readiness checks.
+func (c *PaymentClient) HealthURL() string {
+	return c.client.BaseURL() + "/health"
+}
 
+// CreatePayment is not retried, payment-service has no idempotency keys and a retry after a lost
+// response would create a second payment.
+func (c *PaymentClient) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*PaymentResponse, error) {
 	var result struct {
 		Success bool            `json:"success"`
 		Data    PaymentResponse `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := c.client.Post(ctx, "/api/payments", req, &result); err != nil {
 		return nil, err
 	}
 
@@ -1,55 +1,74 @@
 package api
 
 import (
-	"fmt"
-	"net/http"
+	"context"
 
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/clients"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/controller"
 	orderMiddleware "github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/middleware"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/repository"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/service"
+	sharedapi "github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+type APIServerConfig struct {
+	Addr          string
+	MetricsAddr   string // /metrics is only served here
+	Timeouts      sharedapi.Timeouts
+	UserAuth      middleware.UserAuthConfig
+	GatewaySecret string // the x-gateway-key every api request has to carry
+	CursorKey     []byte // signs the cursors of order pages, see utils.Paginate
+	CartClient    *clients.CartClient
+}
+
 type APIServer struct {
-	addr string
-	db   *gorm.DB
+	db     *gorm.DB
+	config APIServerConfig
+	server *sharedapi.Server
 }
 
-func NewAPIServer(addr string, db *gorm.DB) *APIServer {
+func NewAPIServer(db *gorm.DB, config APIServerConfig) *APIServer {
 	return &APIServer{
-		addr: addr,
-		db:   db,
+		db:     db,
+		config: config,
+		server: sharedapi.NewServer(sharedapi.ServerConfig{
+			Addr:        config.Addr,
+			MetricsAddr: config.MetricsAddr,
+			DB:          db,
+			ServiceName: "order-service",
+			APIPrefix:   "orders",
+			Timeouts:    config.Timeouts,
+			UserAuth:    config.UserAuth,
+		}),
 	}
 }
 
-func (s *APIServer) Start() error {
-	router := mux.NewRouter()
-
-	// Health check endpoint
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","service":"order-service"}`))
-	}).Methods("GET")
-
-	subrouter := router.PathPrefix("/api/orders").Subrouter()
-	subrouter.Use(orderMiddleware.GatewayAuth)
+// OnShutdown runs fn after in-flight requests have drained, in registration order.
+func (s *APIServer) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.server.OnShutdown(name, fn)
+}
 
-	orderRepository := repository.NewOrderRepository(s.db)
-	orderService := service.NewService(orderRepository)
-	orderHandler := controller.NewHandler(orderService)
+// AddHealthCheck adds dependencies to /readyz next to the database.
+func (s *APIServer) AddHealthCheck(checkers ...sharedapi.Checker) {
+	s.server.AddHealthCheck(checkers...)
+}
 
-	orderHandler.RegisterRoutes(subrouter)
+// Run serves until SIGINT, SIGTERM or ctx is done and then shuts down gracefully.
+func (s *APIServer) Run(ctx context.Context) error {
+	orderRepository := repository.NewOrderRepository(s.db, s.config.CursorKey)
+	orderService := service.NewService(orderRepository, s.config.CartClient)
+	orderHandler := controller.NewHandler(orderService, controller.OrderHandlerConfig{
+		Authenticated: s.server.Authenticated,
+		Idempotent:    s.server.Idempotent,
+	})
 
-	// subrouter.Use(func(next http.Handler) http.Handler {
-	// 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-	// 		fmt.Printf("Received request: %s %s\n", r.Method, r.URL.Path)
-	// 		next.ServeHTTP(w, r)
-	// 	})
-	// })
+	s.server.RegisterRoutes(func(subrouter *mux.Router, internal *mux.Router) {
+		subrouter.Use(orderMiddleware.GatewayAuth(s.config.GatewaySecret))
+		orderHandler.RegisterRoutes(subrouter)
+	})
 
-	fmt.Printf("Listening at port: %v\n", s.addr)
-	return http.ListenAndServe(":"+s.addr, router)
+	return s.server.Run(ctx)
 }
@@ -1,42 +1,160 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"os"
 
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/clients"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/cmd/api"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/config"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/db"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/outbox"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/repository"
+	sharedapi "github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 func main() {
+	if err := config.LoadLogging(); err != nil {
+		log.Fatal("invalid logging configuration: ", err)
+	}
+	logger, err := logging.Init("order-service", config.Envs.LOG)
+	if err != nil {
+		log.Fatal("Failed to create logger: ", err)
+	}
+	defer logger.Sync()
+
+	if err := config.Load(); err != nil {
+		logger.Fatal("invalid configuration", zap.Error(err))
+	}
 
-	fmt.Printf("config: %v", config.Envs.DB_HOST)
 	database, err := db.NewPostgresStore()
 	if err != nil {
-		log.Fatal("Failed to connect to database: ", err)
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+	//"<binary> migrate [up | down [steps] | status]" only migrates and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+	if config.Envs.DB_AUTO_MIGRATE {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("failed to apply migrations", zap.Error(err))
+		}
 	}
 
 	initDatabase(database)
-	apiServer := api.NewAPIServer(config.Envs.ORDER_SERVICE_PORT, database)
 
-	if err := apiServer.Start(); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relayDone := make(chan struct{})
+	broker := kafka.NewKafkaBroker(config.Envs.KAFKA_BROKERS)
+	broker.Producer, err = config.Envs.KAFKA_PRODUCER.ProducerConfig()
+	if err != nil {
+		logger.Fatal("invalid kafka producer config", zap.Error(err))
+	}
+	//rows are marked published as soon as PublishEvent returns, so it has to wait for the in-sync replicas,
+	//and the events of one order only keep their order on one partition
+	if err := broker.Producer.RequireDelivery(); err != nil {
+		logger.Fatal("the outbox relay needs KAFKA_PRODUCER_ASYNC=false and KAFKA_PRODUCER_ACKS=all", zap.Error(err))
+	}
+	if broker.Producer.Balancer != kafka.BalancerHash {
+		logger.Fatal("the outbox relay needs KAFKA_PRODUCER_BALANCER=hash")
+	}
+	go func() {
+		defer close(relayDone)
+		newOutboxRelay(database, broker).Run(relayCtx)
+	}()
+
+	cartClient, err := clients.NewCartClient(clients.CartClientConfig{
+		BaseURL:     config.Envs.CART_SERVICE_URL,
+		ServiceAuth: config.Envs.SERVICE_AUTH,
+	})
+	if err != nil {
+		logger.Fatal("failed to create cart client", zap.Error(err))
+	}
+	paymentClient, err := clients.NewPaymentClient(clients.PaymentClientConfig{
+		BaseURL:     config.Envs.PAYMENT_SERVICE_URL,
+		ServiceAuth: config.Envs.SERVICE_AUTH,
+	})
+	if err != nil {
+		logger.Fatal("failed to create payment client", zap.Error(err))
+	}
+	userAuth, err := config.Envs.USER_AUTH.Config()
+	if err != nil {
+		logger.Fatal("invalid user auth configuration", zap.Error(err))
 	}
 
+	apiServer := api.NewAPIServer(database, api.APIServerConfig{
+		Addr:          config.Envs.ORDER_SERVICE_PORT,
+		MetricsAddr:   config.Envs.METRICS_ADDR,
+		Timeouts:      config.Envs.HTTP_TIMEOUTS,
+		UserAuth:      userAuth,
+		GatewaySecret: config.Envs.GATEWAY_SECRET_KEY,
+		CursorKey:     []byte(config.Envs.PAGINATION_CURSOR_KEY),
+		CartClient:    cartClient,
+	})
+	//reported as degraded only: kafka just delays publishing thanks to the outbox, and taking
+	//order-service out of rotation would not bring cart or payment back
+	apiServer.AddHealthCheck(
+		sharedapi.Optional(sharedapi.KafkaChecker(broker)),
+		sharedapi.Optional(sharedapi.HTTPChecker("cart-service", cartClient.HealthURL())),
+		sharedapi.Optional(sharedapi.HTTPChecker("payment-service", paymentClient.HealthURL())),
+	)
+
+	//orders written while draining are still in the outbox, the relay picks them up after a restart
+	apiServer.OnShutdown("outbox relay", func(ctx context.Context) error {
+		stopRelay()
+		select {
+		case <-relayDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	apiServer.OnShutdown("database", func(ctx context.Context) error {
+		sqlDB, err := database.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
+	if err := apiServer.Run(context.Background()); err != nil {
+		logger.Fatal("server stopped", zap.Error(err))
+	}
+
+}
+
+func newOutboxRelay(database *gorm.DB, broker kafka.Broker) *outbox.Relay {
+	return outbox.NewRelay(repository.NewOutboxRepository(database), broker, outbox.RelayConfig{
+		PollInterval: config.Envs.OUTBOX_POLL_INTERVAL,
+		MaxRetries:   config.Envs.OUTBOX_MAX_RETRIES,
+	})
 }
 
 func initDatabase(gorm_Db *gorm.DB) {
 	db, err := gorm_Db.DB()
 
 	if err != nil {
-		log.Fatal("Unable to get generic DB from gorm: ", err)
+		logging.L().Fatal("unable to get generic DB from gorm", zap.Error(err))
 	}
 
 	if err := db.Ping(); err != nil {
-		log.Fatal("Unable to connect to database: ", err)
+		logging.L().Fatal("unable to connect to database", zap.Error(err))
 	}
 
-	log.Println("DB: Successfully connected")
+	logging.L().Info("database connected")
 }
@@ -1,46 +1,67 @@
 package config
 
 import (
-	"log"
-	"os"
+	"time"
 
-	"github.com/lpernett/godotenv"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	sharedconfig "github.com/Flow-Indo/LAKOO/backend/shared/go/config"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
 )
 
 type Config struct {
-	ORDER_SERVICE_PORT string
-	DB_HOST            string
-	DB_USER            string
-	DB_PASSWORD        string
-	DB_NAME            string
-	DB_PORT            string
-	DB_SSL             string
-	KAFKA_BROKERS      string
-}
+	ORDER_SERVICE_PORT string   `env:"ORDER_SERVICE_PORT" default:"3006"`
+	METRICS_ADDR       string   `env:"METRICS_ADDR" default:":9106"`
+	DB_HOST            string   `env:"DB_HOST" default:"localhost"`
+	DB_USER            string   `env:"DB_USER,required"`
+	DB_PASSWORD        string   `env:"DB_PASSWORD,required,secret"`
+	DB_NAME            string   `env:"DB_NAME" default:"orderdb"`
+	DB_PORT            int      `env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+	DB_SSL             string   `env:"DB_SSL" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	DB_AUTO_MIGRATE    bool     `env:"DB_AUTO_MIGRATE" default:"false"`
+	KAFKA_BROKERS      []string `env:"KAFKA_BROKERS" default:"localhost:9092" validate:"min=1"`
+
+	CART_SERVICE_URL    string `env:"CART_SERVICE_URL,required" validate:"url"`
+	PAYMENT_SERVICE_URL string `env:"PAYMENT_SERVICE_URL,required" validate:"url"`
+	GATEWAY_SECRET_KEY  string `env:"GATEWAY_SECRET_KEY,required,secret"` // x-gateway-key of every api request
+
+	//signs the ?cursor= of list endpoints, the same on every instance
+	PAGINATION_CURSOR_KEY string `env:"PAGINATION_CURSOR_KEY,required,secret" validate:"min=32"`
+
+	OUTBOX_POLL_INTERVAL time.Duration `env:"OUTBOX_POLL_INTERVAL" default:"2s" validate:"gt=0"`
+	OUTBOX_MAX_RETRIES   int           `env:"OUTBOX_MAX_RETRIES" default:"10" validate:"min=1"`
+
+	//pool sizes, statement timeout, slow query threshold and read replicas, see postgres.Options
+	DB_POOL postgres.Options
+
+	//async mode, batching, compression, acks and partitioning, see kafka.ProducerOptions
+	KAFKA_PRODUCER kafka.ProducerOptions
 
-var Envs = initConfig()
-
-func initConfig() *Config {
-	godotenv.Load("../.env")
-
-	return &Config{
-		ORDER_SERVICE_PORT: getEnv("ORDER_SERVICE_PORT", "3006"),
-		DB_HOST:            getEnv("DB_HOST", "localhost"),
-		DB_USER:            getEnv("DB_USER", "postgres"),
-		DB_PASSWORD:        getEnv("DB_PASSWORD", "password"),
-		DB_NAME:            getEnv("DB_NAME", "orderdb"),
-		DB_PORT:            getEnv("DB_PORT", "5432"),
-		DB_SSL:             getEnv("DB_SSL", "DISABLED"),
-		KAFKA_BROKERS:      getEnv("KAFKA_BROKERS", "localhost:9092"),
-	}
+	//service token keys, SERVICE_SECRET signs the calls to payment-service, see auth.ServiceAuthOptions
+	SERVICE_AUTH auth.ServiceAuthOptions
+
+	//JWKS for bearer tokens and the secret of the gateway headers, see middleware.UserAuthOptions
+	USER_AUTH middleware.UserAuthOptions
+
+	//read, write, idle and shutdown timeouts of the server, see api.Timeouts
+	HTTP_TIMEOUTS api.Timeouts
+
+	//loaded before the rest, see LoadLogging
+	LOG logging.Options
 }
 
-func getEnv(key string, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		log.Println("Value: ", value)
-		return value
-	}
+// Envs is filled by Load at startup.
+var Envs = &Config{}
+
+// Load reads the config from the environment, CONFIG_FILE and Docker secrets, see sharedconfig.Load.
+func Load() error {
+	return sharedconfig.Load(Envs, sharedconfig.Options{})
+}
 
-	log.Println("Returning feedback")
-	return fallback
+// LoadLogging only fills the LOG options of Envs, quietly, so the logger is up before Load logs the config.
+func LoadLogging() error {
+	return sharedconfig.Load(&Envs.LOG, sharedconfig.Options{Quiet: true})
 }
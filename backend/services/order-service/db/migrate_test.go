@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+)
+
+func TestMigrations(t *testing.T) {
+	loaded, err := migrate.LoadAll(migrations, "migrations", migrateConfig.Include...)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i := 1; i < len(loaded); i++ {
+		if loaded[i-1].Version >= loaded[i].Version {
+			t.Fatalf("migration %s runs before %s", loaded[i-1].Version, loaded[i].Version)
+		}
+	}
+}
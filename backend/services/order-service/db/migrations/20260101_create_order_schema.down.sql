@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS service_outbox;
+DROP TABLE IF EXISTS order_item;
+DROP TABLE IF EXISTS "order";
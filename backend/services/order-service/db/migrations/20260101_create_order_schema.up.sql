@@ -0,0 +1,111 @@
+-- Order schema: orders with their items, and the outbox the relay publishes to kafka.
+-- Written for databases created before migrations were tracked too, so it is safe to re-run.
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS "order" (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_number varchar(50) NOT NULL,
+  user_id uuid NOT NULL,
+  order_source varchar(20) NOT NULL DEFAULT 'brand',
+  brand_id uuid,
+  seller_id uuid,
+  subtotal numeric(15,2) NOT NULL,
+  discount_amount numeric(15,2) NOT NULL DEFAULT 0,
+  shipping_cost numeric(15,2) NOT NULL DEFAULT 0,
+  tax_amount numeric(15,2) NOT NULL DEFAULT 0,
+  total_amount numeric(15,2) NOT NULL,
+  currency varchar(3) NOT NULL DEFAULT 'IDR',
+  coupon_id uuid,
+  coupon_code varchar(50),
+  shipping_address_id uuid,
+  shipping_recipient varchar(255) NOT NULL,
+  shipping_phone varchar(20) NOT NULL,
+  shipping_street text NOT NULL,
+  shipping_district varchar(100),
+  shipping_city varchar(100) NOT NULL,
+  shipping_province varchar(100) NOT NULL,
+  shipping_postal_code varchar(10) NOT NULL,
+  shipping_country varchar(100) NOT NULL DEFAULT 'Indonesia',
+  shipping_latitude numeric(10,8),
+  shipping_longitude numeric(11,8),
+  customer_email varchar(255),
+  customer_phone varchar(20) NOT NULL,
+  customer_name varchar(255) NOT NULL,
+  shipping_method varchar(100),
+  shipping_courier varchar(50),
+  estimated_delivery timestamptz,
+  status varchar(20) NOT NULL DEFAULT 'pending',
+  customer_notes text,
+  internal_notes text,
+  paid_at timestamptz,
+  confirmed_at timestamptz,
+  processed_at timestamptz,
+  shipped_at timestamptz,
+  delivered_at timestamptz,
+  completed_at timestamptz,
+  cancelled_at timestamptz,
+  cancel_reason varchar(500),
+  cancelled_by varchar(50),
+  live_session_id uuid,
+  idempotency_key varchar(100),
+  created_by uuid,
+  updated_by uuid,
+  created_at timestamptz NOT NULL,
+  updated_at timestamptz NOT NULL,
+  deleted_at timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_order_number ON "order"(order_number);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_idempotency_key ON "order"(idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_order_brand_id ON "order"(brand_id);
+CREATE INDEX IF NOT EXISTS idx_order_seller_id ON "order"(seller_id);
+CREATE INDEX IF NOT EXISTS idx_order_status ON "order"(status);
+CREATE INDEX IF NOT EXISTS idx_order_deleted_at ON "order"(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_user_id_created_at ON "order"(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS order_item (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  order_id uuid NOT NULL REFERENCES "order"(id) ON DELETE CASCADE,
+  item_type varchar(20) NOT NULL DEFAULT 'brand_product',
+  product_id uuid,
+  variant_id uuid,
+  brand_id uuid,
+  brand_product_id uuid,
+  seller_product_id uuid,
+  seller_id uuid,
+  snapshot_product_name varchar(255) NOT NULL,
+  snapshot_variant_name varchar(255),
+  snapshot_sku varchar(100),
+  snapshot_image_url text,
+  snapshot_brand_name varchar(255),
+  snapshot_seller_name varchar(255),
+  unit_price numeric(15,2) NOT NULL,
+  quantity integer NOT NULL,
+  subtotal numeric(15,2) NOT NULL,
+  discount_amount numeric(15,2) NOT NULL DEFAULT 0,
+  total_amount numeric(15,2) NOT NULL,
+  fulfilled_quantity integer NOT NULL DEFAULT 0,
+  returned_quantity integer NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_item_order_id ON order_item(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_item_product_id ON order_item(product_id);
+
+CREATE TABLE IF NOT EXISTS service_outbox (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  aggregate_type varchar(100) NOT NULL,
+  aggregate_id uuid NOT NULL,
+  event_type varchar(100) NOT NULL,
+  payload jsonb NOT NULL,
+  metadata jsonb,
+  is_published boolean NOT NULL DEFAULT false,
+  published_at timestamptz,
+  retry_count integer NOT NULL DEFAULT 0,
+  last_error text,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_outbox_aggregate ON service_outbox(aggregate_type, aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_service_outbox_published ON service_outbox(is_published, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_service_outbox_pending;
+
+ALTER TABLE service_outbox DROP COLUMN IF EXISTS dead_lettered_at;
+ALTER TABLE service_outbox DROP COLUMN IF EXISTS claimed_until;
+ALTER TABLE service_outbox DROP COLUMN IF EXISTS claim_token;
@@ -0,0 +1,9 @@
+-- Outbox rows are claimed for a lease and published outside the claiming transaction,
+-- rows that used up their retries are dead lettered and hold back later events of their aggregate.
+-- This is safe to re-run.
+
+ALTER TABLE service_outbox ADD COLUMN IF NOT EXISTS claim_token uuid;
+ALTER TABLE service_outbox ADD COLUMN IF NOT EXISTS claimed_until timestamptz;
+ALTER TABLE service_outbox ADD COLUMN IF NOT EXISTS dead_lettered_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_service_outbox_pending ON service_outbox(created_at) WHERE is_published = false;
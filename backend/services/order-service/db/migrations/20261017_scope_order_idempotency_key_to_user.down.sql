@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_order_user_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_idempotency_key ON "order"(idempotency_key);
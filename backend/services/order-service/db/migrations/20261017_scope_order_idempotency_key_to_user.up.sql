@@ -0,0 +1,6 @@
+-- Idempotency keys are picked by clients, two users may well pick the same one.
+-- They are unique per user, like the scope the idempotency middleware keeps them in.
+-- This is safe to re-run.
+
+DROP INDEX IF EXISTS idx_order_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_user_idempotency_key ON "order"(user_id, idempotency_key);
@@ -6,23 +6,38 @@ import (
 
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/service"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/gorilla/mux"
 )
 
+type OrderHandlerConfig struct {
+	Authenticated func(http.Handler) http.Handler // authenticates the end user
+	Idempotent    func(http.Handler) http.Handler // honours Idempotency-Key on order creation
+}
+
 type OrderHandler struct {
 	orderService *service.OrderService
+	config       OrderHandlerConfig
 }
 
-func NewHandler(orderService *service.OrderService) *OrderHandler {
+func NewHandler(orderService *service.OrderService, config OrderHandlerConfig) *OrderHandler {
+	if config.Idempotent == nil {
+		config.Idempotent = func(next http.Handler) http.Handler { return next }
+	}
+
 	return &OrderHandler{
 		orderService: orderService,
+		config:       config,
 	}
 }
 
 func (h *OrderHandler) RegisterRoutes(orderRouter *mux.Router) {
-	orderRouter.HandleFunc("", h.getOrders).Methods("GET")
-	orderRouter.HandleFunc("", h.createOrder).Methods("POST")
+	//users only list their own orders
+	orderRouter.Handle("", h.config.Authenticated(http.HandlerFunc(h.getOrders))).Methods("GET")
+	//the user is authenticated for idempotency keys, which are scoped to them
+	orderRouter.Handle("", h.config.Authenticated(h.config.Idempotent(http.HandlerFunc(h.createOrder)))).Methods("POST")
 	// orderRouter.HandleFunc("/bulk", h.orderService.createBulkOrders).Methods("POST")
 	// orderRouter.HandleFunc("/{orderId}/cancel", h.orderService.cancelOrder).Methods("POST")
 	// orderRouter.HandleFunc("/stats", h.orderService.getOrderStats).Methods("GET")
@@ -34,36 +49,63 @@ func (h *OrderHandler) RegisterRoutes(orderRouter *mux.Router) {
 	// orderRouter.HandleFunc("/{orderId}/shipping-cost", h.orderService.updateShippingCost).Methods("PUT")
 }
 
+// orderListSpec is what the order list can be sorted and filtered by.
+var orderListSpec = utils.ListSpec{
+	Sorts: map[string]string{
+		"created_at":   "created_at",
+		"updated_at":   "updated_at",
+		"total_amount": "total_amount",
+		"order_number": "order_number",
+	},
+	DefaultSort: "-created_at",
+	Filters: []utils.Filter{
+		{Param: "seller_id", Columns: []string{"seller_id"}, Op: utils.OpEq, Kind: utils.KindUUID},
+		{Param: "brand_id", Columns: []string{"brand_id"}, Op: utils.OpEq, Kind: utils.KindUUID},
+		{Param: "status", Columns: []string{"status"}, Op: utils.OpIn},
+		{Param: "order_source", Columns: []string{"order_source"}, Op: utils.OpIn},
+		{Param: "search", Columns: []string{"order_number", "customer_name"}, Op: utils.OpSearch},
+		{Param: "total_amount", Columns: []string{"total_amount"}, Op: utils.OpRange, Kind: utils.KindNumber},
+		{Param: "created", Columns: []string{"created_at"}, Op: utils.OpRange, Kind: utils.KindTime},
+	},
+}
+
 func (h *OrderHandler) getOrders(w http.ResponseWriter, r *http.Request) {
-	var orderFilterPayload types.OrderFilterPayload
-	if err := utils.DecodeQueryParamsWithValidation(&orderFilterPayload, r); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+	userId, err := middleware.GetUserIdFromContext(r.Context())
+	if err != nil {
+		apperror.Write(w, r, apperror.Wrap(err, apperror.CodeUnauthenticated, ""))
 		return
 	}
 
-	orders, err := h.orderService.GetOrders(orderFilterPayload)
+	query, err := utils.ParseListQuery(r, orderListSpec)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		apperror.Write(w, r, err)
 		return
 	}
 
-	if err := utils.WriteJSONResponse(w, http.StatusOK, orders); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+	orders, err := h.orderService.GetOrders(r.Context(), userId, query)
+	if err != nil {
+		apperror.Write(w, r, err)
 		return
 	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, orders)
 }
 
 func (h *OrderHandler) createOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var createOrderPayload types.CreateOrderPayload
 	if err := utils.ParseJSONBody(r.Body, &createOrderPayload); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err)
+		apperror.Write(w, r, apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid JSON body"))
+		return
+	}
+	if userId, err := middleware.GetUserIdFromContext(ctx); err != nil || userId != createOrderPayload.UserID {
+		apperror.Write(w, r, apperror.PermissionDenied("orders can only be created for the authenticated user"))
 		return
 	}
 
 	order, err := h.orderService.CreateOrder(createOrderPayload, ctx)
 	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err)
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -1,29 +1,23 @@
 package middleware
 
 import (
-	"errors"
+	"crypto/subtle"
 	"net/http"
-	"os"
 
-	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
 )
 
-// GatewayAuth enforces that requests came through the API Gateway.
-// If GATEWAY_SECRET_KEY is not set, this middleware is a no-op (local/dev friendly).
-func GatewayAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		expected := os.Getenv("GATEWAY_SECRET_KEY")
-		if expected == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		got := r.Header.Get("x-gateway-key")
-		if got == "" || got != expected {
-			utils.WriteError(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-			return
-		}
+// GatewayAuth enforces that requests came through the API Gateway, which sends secret as x-gateway-key.
+func GatewayAuth(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get("x-gateway-key")
+			if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+				apperror.Write(w, r, apperror.Unauthenticated("gateway authentication required"))
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
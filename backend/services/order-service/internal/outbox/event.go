@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+)
+
+// NewEvent turns an envelope into an outbox row. The row id is the event id,
+// so every publish attempt of the same row carries the same id and consumers can deduplicate on it.
+// The trace context of ctx is kept in the metadata, the relay publishes long after the request is gone.
+func NewEvent(ctx context.Context, aggregateType string, aggregateID string, envelope kafka.Envelope) (models.ServiceOutbox, error) {
+	topic, ok := kafka.TopicFor(envelope.EventType)
+	if !ok {
+		return models.ServiceOutbox{}, fmt.Errorf("no topic registered for event type %s", envelope.EventType)
+	}
+
+	var payload utils.JSONB
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		return models.ServiceOutbox{}, fmt.Errorf("outbox payload must be a json object: %w", err)
+	}
+
+	metadata := utils.JSONB{
+		"topic":         topic,
+		"source":        envelope.Source,
+		"schemaVersion": envelope.SchemaVersion,
+		"correlationId": envelope.CorrelationID,
+		"occurredAt":    envelope.OccurredAt.Format(time.RFC3339Nano),
+	}
+	if traceContext, ok := tracing.TraceContextFromContext(ctx); ok {
+		metadata["traceparent"] = traceContext.String()
+	}
+
+	return models.ServiceOutbox{
+		ID:            envelope.EventID,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     string(envelope.EventType),
+		Payload:       payload,
+		Metadata:      metadata,
+		CreatedAt:     envelope.OccurredAt,
+	}, nil
+}
+
+// publishContext restores the trace and correlation id of the request that wrote the row.
+func publishContext(ctx context.Context, row models.ServiceOutbox, envelope kafka.Envelope) context.Context {
+	if traceContext, err := tracing.ParseTraceparent(utils.GetStringFromJSONB(row.Metadata, "traceparent")); err == nil {
+		ctx = tracing.WithTraceContext(ctx, traceContext)
+	}
+	if envelope.CorrelationID != "" {
+		ctx = tracing.WithCorrelationID(ctx, envelope.CorrelationID)
+	}
+
+	return ctx
+}
+
+// toEnvelope rebuilds the envelope that NewEvent stored.
+func toEnvelope(row models.ServiceOutbox) (kafka.Envelope, string, error) {
+	payload, err := json.Marshal(row.Payload)
+	if err != nil {
+		return kafka.Envelope{}, "", fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	occurredAt := row.CreatedAt
+	if parsed, err := time.Parse(time.RFC3339Nano, utils.GetStringFromJSONB(row.Metadata, "occurredAt")); err == nil {
+		occurredAt = parsed
+	}
+
+	topic := utils.GetStringFromJSONB(row.Metadata, "topic")
+	if topic == "" {
+		var ok bool
+		if topic, ok = kafka.TopicFor(kafka.EventType(row.EventType)); !ok {
+			return kafka.Envelope{}, "", fmt.Errorf("no topic for outbox event type %s", row.EventType)
+		}
+	}
+
+	return kafka.Envelope{
+		EventID:       row.ID,
+		EventType:     kafka.EventType(row.EventType),
+		Source:        utils.GetStringFromJSONB(row.Metadata, "source"),
+		OccurredAt:    occurredAt,
+		SchemaVersion: utils.GetIntFromJSONB(row.Metadata, "schemaVersion"),
+		CorrelationID: utils.GetStringFromJSONB(row.Metadata, "correlationId"),
+		Payload:       payload,
+	}, topic, nil
+}
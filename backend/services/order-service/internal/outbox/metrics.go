@@ -0,0 +1,27 @@
+package outbox
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	publishedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "outbox",
+		Name:      "events_published_total",
+		Help:      "Outbox rows published to kafka by event type.",
+	}, []string{"event_type"})
+
+	publishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "outbox",
+		Name:      "publish_failures_total",
+		Help:      "Failed publishes of outbox rows by event type, each one counts against the row's retries.",
+	}, []string{"event_type"})
+
+	deadLetteredEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "outbox",
+		Name:      "events_dead_lettered_total",
+		Help:      "Outbox rows that used up their retries by event type, later events of their aggregate wait for an operator.",
+	}, []string{"event_type"})
+)
+
+func init() {
+	prometheus.MustRegister(publishedEvents, publishFailures, deadLetteredEvents)
+}
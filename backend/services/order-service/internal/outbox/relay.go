@@ -0,0 +1,206 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/repository"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+)
+
+type RelayConfig struct {
+	PollInterval    time.Duration // how often pending rows are picked up when the outbox is idle
+	BatchSize       int
+	MaxRetries      int           // rows that failed this many polls are dead lettered for manual inspection
+	PublishAttempts int           // in-process attempts per row before the failure is recorded
+	MaxBackoff      time.Duration // upper bound for the poll delay while the broker keeps failing
+	ClaimLease      time.Duration // how long a claimed batch is reserved, another relay takes over afterwards
+}
+
+// Relay publishes outbox rows to kafka. Rows are only marked published after the broker acknowledged them,
+// so delivery is at-least-once: a crash between publish and marking, or a claim that outlived its lease,
+// resends the row with the same event id.
+type Relay struct {
+	repository *repository.OutboxRepository
+	broker     kafka.Broker
+	config     RelayConfig
+	producers  map[string]kafka.Producer
+}
+
+func NewRelay(repository *repository.OutboxRepository, broker kafka.Broker, config RelayConfig) *Relay {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 10
+	}
+	if config.PublishAttempts <= 0 {
+		config.PublishAttempts = 3
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.ClaimLease <= 0 {
+		config.ClaimLease = 5 * time.Minute
+	}
+
+	return &Relay{
+		repository: repository,
+		broker:     broker,
+		config:     config,
+		producers:  make(map[string]kafka.Producer),
+	}
+}
+
+// Run polls the outbox until ctx is cancelled. A full batch is followed immediately by the next one,
+// a failed batch backs off exponentially up to MaxBackoff.
+func (r *Relay) Run(ctx context.Context) {
+	defer r.close()
+
+	delay := r.config.PollInterval
+	for {
+		claimed, err := r.processBatch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logging.L().Error("outbox relay failed", zap.Error(err))
+		}
+		delay = r.nextDelay(delay, claimed, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// nextDelay is the wait before the next poll: none after a full batch, doubling from PollInterval
+// up to MaxBackoff while batches fail and PollInterval otherwise.
+func (r *Relay) nextDelay(delay time.Duration, claimed int, err error) time.Duration {
+	switch {
+	case err != nil:
+		return min(max(delay*2, r.config.PollInterval), r.config.MaxBackoff)
+	case claimed == r.config.BatchSize:
+		return 0
+	default:
+		return r.config.PollInterval
+	}
+}
+
+// processBatch claims a batch and publishes it outside of any transaction, so no row lock is held while
+// waiting for the broker. After a failure the remaining rows of that aggregate are skipped to keep its order,
+// rows of other aggregates carry on.
+func (r *Relay) processBatch(ctx context.Context) (int, error) {
+	token, rows, err := r.repository.ClaimPending(ctx, r.config.BatchSize, r.config.ClaimLease)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	defer func() {
+		//skipped rows and the rest of the batch after a shutdown go back right away instead of after the lease
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := r.repository.ReleaseClaims(releaseCtx, token); err != nil {
+			logging.L().Error("outbox relay: failed to release claims", zap.Error(err))
+		}
+	}()
+
+	failedAggregates := make(map[string]bool)
+	var failures []error
+	for _, row := range rows {
+		aggregate := row.AggregateType + "/" + row.AggregateID
+		if failedAggregates[aggregate] {
+			continue
+		}
+
+		publishErr := r.publish(ctx, row)
+		if ctx.Err() != nil {
+			return len(rows), ctx.Err()
+		}
+		if publishErr != nil {
+			failedAggregates[aggregate] = true
+			deadLetter := row.RetryCount+1 >= r.config.MaxRetries
+			if err := r.repository.RecordFailure(ctx, token, row.ID, publishErr.Error(), deadLetter); err != nil {
+				return len(rows), err
+			}
+
+			publishFailures.WithLabelValues(row.EventType).Inc()
+			if deadLetter {
+				deadLetteredEvents.WithLabelValues(row.EventType).Inc()
+				logging.L().Error("outbox row dead lettered, later events of its aggregate are held back",
+					zap.String("outbox_id", row.ID),
+					zap.String("event_type", row.EventType),
+					zap.String("aggregate", aggregate),
+					zap.Error(publishErr),
+				)
+			}
+			failures = append(failures, fmt.Errorf("failed to publish outbox row %s: %w", row.ID, publishErr))
+			continue
+		}
+
+		if err := r.repository.MarkPublished(ctx, token, row.ID); err != nil {
+			return len(rows), err
+		}
+		publishedEvents.WithLabelValues(row.EventType).Inc()
+	}
+
+	return len(rows), errors.Join(failures...)
+}
+
+func (r *Relay) publish(ctx context.Context, row models.ServiceOutbox) error {
+	envelope, topic, err := toEnvelope(row)
+	if err != nil {
+		return err
+	}
+
+	producer := r.producer(topic)
+	publishCtx := publishContext(ctx, row, envelope)
+	backoff := 200 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		err = producer.PublishEvent(publishCtx, row.AggregateID, envelope)
+		if err == nil || attempt == r.config.PublishAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *Relay) producer(topic string) kafka.Producer {
+	producer, ok := r.producers[topic]
+	if !ok {
+		producer = r.broker.NewProducer(topic)
+		r.producers[topic] = producer
+	}
+
+	return producer
+}
+
+func (r *Relay) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for topic, producer := range r.producers {
+		if err := producer.Flush(ctx); err != nil {
+			logging.L().Error("outbox relay: failed to flush producer", zap.String("topic", topic), zap.Error(err))
+		}
+		if err := producer.Close(); err != nil {
+			logging.L().Error("outbox relay: failed to close producer", zap.String("topic", topic), zap.Error(err))
+		}
+	}
+}
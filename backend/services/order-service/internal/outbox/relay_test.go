@@ -0,0 +1,362 @@
+package outbox
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/repository"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var testConfig = RelayConfig{
+	BatchSize:       10,
+	MaxRetries:      3,
+	PublishAttempts: 2,
+	ClaimLease:      time.Minute,
+}
+
+func newTestRelay(t *testing.T, broker kafka.Broker, config RelayConfig) (*Relay, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+
+	return NewRelay(repository.NewOutboxRepository(db), broker, config), mock
+}
+
+func newOrderCreated(t *testing.T, orderID string, retryCount int) models.ServiceOutbox {
+	t.Helper()
+
+	envelope, err := kafka.NewEnvelope(kafka.EventOrderCreated, "order-service", "correlation-"+orderID, kafka.OrderCreatedEvent{OrderID: orderID})
+	if err != nil {
+		t.Fatalf("NewEnvelope: %v", err)
+	}
+	row, err := NewEvent(context.Background(), "order", orderID, envelope)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	row.RetryCount = retryCount
+
+	return row
+}
+
+// claimToken records the token ClaimPending writes and only matches that token afterwards.
+type claimToken struct {
+	value *string
+}
+
+func (c claimToken) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return false
+	}
+	if *c.value == "" {
+		*c.value = s
+	}
+	return s == *c.value
+}
+
+func expectClaim(t *testing.T, mock sqlmock.Sqlmock, token *string, pending ...models.ServiceOutbox) {
+	t.Helper()
+
+	rows := sqlmock.NewRows([]string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "metadata", "retry_count", "created_at"})
+	args := []driver.Value{claimToken{token}, sqlmock.AnyArg()}
+	for _, row := range pending {
+		payload, err := json.Marshal(row.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		metadata, err := json.Marshal(row.Metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.AddRow(row.ID, row.AggregateType, row.AggregateID, row.EventType, payload, metadata, row.RetryCount, row.CreatedAt)
+		args = append(args, row.ID)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT o\.\* FROM service_outbox o`).WithArgs(testConfig.BatchSize).WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=now\(\)`).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, int64(len(pending))))
+	mock.ExpectCommit()
+}
+
+func expectPublished(mock sqlmock.Sqlmock, token *string, id string) {
+	mock.ExpectExec(`UPDATE "service_outbox" SET .*"is_published"=.* WHERE id = \$6 AND claim_token = \$7`).
+		WithArgs(nil, nil, true, nil, sqlmock.AnyArg(), id, claimToken{token}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectFailure(mock sqlmock.Sqlmock, token *string, id string, deadLetter bool) {
+	if deadLetter {
+		mock.ExpectExec(`UPDATE "service_outbox" SET .*"dead_lettered_at"=.*"retry_count"=retry_count \+ 1 WHERE id = \$5 AND claim_token = \$6`).
+			WithArgs(nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), id, claimToken{token}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		return
+	}
+
+	mock.ExpectExec(`UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2,"last_error"=\$3,"retry_count"=retry_count \+ 1 WHERE id = \$4 AND claim_token = \$5`).
+		WithArgs(nil, nil, sqlmock.AnyArg(), id, claimToken{token}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectRelease(mock sqlmock.Sqlmock, token *string) {
+	mock.ExpectExec(`UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2 WHERE claim_token = \$3 AND is_published = \$4`).
+		WithArgs(nil, nil, claimToken{token}, false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// flakyBroker fails the first publishes of an aggregate before handing them to the MemoryBroker.
+type flakyBroker struct {
+	*kafka.MemoryBroker
+
+	mu       sync.Mutex
+	failures map[string]int // publishes left to fail per aggregate id
+	attempts map[string]int
+}
+
+func newFlakyBroker(failures map[string]int) *flakyBroker {
+	return &flakyBroker{
+		MemoryBroker: kafka.NewMemoryBroker(1),
+		failures:     failures,
+		attempts:     make(map[string]int),
+	}
+}
+
+func (b *flakyBroker) NewProducer(topic string) kafka.Producer {
+	return &flakyProducer{Producer: b.MemoryBroker.NewProducer(topic), broker: b}
+}
+
+func (b *flakyBroker) attemptsFor(key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts[key]
+}
+
+type flakyProducer struct {
+	kafka.Producer
+	broker *flakyBroker
+}
+
+func (p *flakyProducer) PublishEvent(ctx context.Context, key string, envelope kafka.Envelope) error {
+	p.broker.mu.Lock()
+	p.broker.attempts[key]++
+	failing := p.broker.failures[key] > 0
+	if failing {
+		p.broker.failures[key]--
+	}
+	p.broker.mu.Unlock()
+
+	if failing {
+		return errors.New("broker unavailable")
+	}
+	return p.Producer.PublishEvent(ctx, key, envelope)
+}
+
+func publishedEnvelopes(t *testing.T, broker *kafka.MemoryBroker) map[string]kafka.Envelope {
+	t.Helper()
+
+	envelopes := make(map[string]kafka.Envelope)
+	for _, message := range broker.Messages(kafka.TopicOrderEvent) {
+		envelope, err := kafka.DecodeEnvelope(message.Value)
+		if err != nil {
+			t.Fatalf("DecodeEnvelope: %v", err)
+		}
+		envelopes[string(message.Key)] = envelope
+	}
+
+	return envelopes
+}
+
+func TestRelayPublishesAndMarksSent(t *testing.T) {
+	broker := kafka.NewMemoryBroker(1)
+	relay, mock := newTestRelay(t, broker, testConfig)
+	first := newOrderCreated(t, uuid.NewString(), 0)
+	second := newOrderCreated(t, uuid.NewString(), 0)
+
+	var token string
+	expectClaim(t, mock, &token, first, second)
+	expectPublished(mock, &token, first.ID)
+	expectPublished(mock, &token, second.ID)
+	expectRelease(mock, &token)
+
+	claimed, err := relay.processBatch(context.Background())
+	if err != nil || claimed != 2 {
+		t.Fatalf("got %d claimed, error %v, want 2 published rows", claimed, err)
+	}
+
+	//the row is the event, key and event id survive the round trip through the outbox
+	envelopes := publishedEnvelopes(t, broker)
+	for _, row := range []models.ServiceOutbox{first, second} {
+		envelope, ok := envelopes[row.AggregateID]
+		if !ok {
+			t.Fatalf("nothing published for aggregate %s", row.AggregateID)
+		}
+		if envelope.EventID != row.ID || envelope.EventType != kafka.EventOrderCreated || envelope.Source != "order-service" ||
+			envelope.SchemaVersion != 1 || envelope.CorrelationID != "correlation-"+row.AggregateID {
+			t.Fatalf("got envelope %+v for row %s", envelope, row.ID)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayClaimTokenFencing(t *testing.T) {
+	relay, mock := newTestRelay(t, kafka.NewMemoryBroker(1), testConfig)
+	first := newOrderCreated(t, uuid.NewString(), 0)
+	second := newOrderCreated(t, uuid.NewString(), 0)
+
+	//every batch finishes its rows with the token of its own claim
+	var firstToken, secondToken string
+	expectClaim(t, mock, &firstToken, first)
+	expectPublished(mock, &firstToken, first.ID)
+	expectRelease(mock, &firstToken)
+	expectClaim(t, mock, &secondToken, second)
+	expectPublished(mock, &secondToken, second.ID)
+	expectRelease(mock, &secondToken)
+
+	for range 2 {
+		if _, err := relay.processBatch(context.Background()); err != nil {
+			t.Fatalf("processBatch: %v", err)
+		}
+	}
+	if firstToken == secondToken {
+		t.Fatalf("both batches were claimed with token %q", firstToken)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayRetriesWithinBatch(t *testing.T) {
+	row := newOrderCreated(t, uuid.NewString(), 0)
+	broker := newFlakyBroker(map[string]int{row.AggregateID: 1})
+	relay, mock := newTestRelay(t, broker, testConfig)
+
+	var token string
+	expectClaim(t, mock, &token, row)
+	expectPublished(mock, &token, row.ID)
+	expectRelease(mock, &token)
+
+	if _, err := relay.processBatch(context.Background()); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if got := broker.attemptsFor(row.AggregateID); got != 2 {
+		t.Fatalf("got %d attempts, want the failed publish retried once", got)
+	}
+	if _, ok := publishedEnvelopes(t, broker.MemoryBroker)[row.AggregateID]; !ok {
+		t.Fatal("the retried row was not published")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayRecordsFailureAndHoldsBackAggregate(t *testing.T) {
+	failing := newOrderCreated(t, uuid.NewString(), 0)
+	later := newOrderCreated(t, failing.AggregateID, 0)
+	other := newOrderCreated(t, uuid.NewString(), 0)
+	broker := newFlakyBroker(map[string]int{failing.AggregateID: 100})
+	relay, mock := newTestRelay(t, broker, testConfig)
+
+	//the later row of the failing aggregate is not published ahead of it, it goes back with the release
+	var token string
+	expectClaim(t, mock, &token, failing, later, other)
+	expectFailure(mock, &token, failing.ID, false)
+	expectPublished(mock, &token, other.ID)
+	expectRelease(mock, &token)
+
+	claimed, err := relay.processBatch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), failing.ID) {
+		t.Fatalf("got %v, want the failure of row %s", err, failing.ID)
+	}
+	if claimed != 3 {
+		t.Fatalf("got %d claimed, want 3", claimed)
+	}
+	if got := broker.attemptsFor(failing.AggregateID); got != testConfig.PublishAttempts {
+		t.Fatalf("got %d attempts, want %d for the failing row only", got, testConfig.PublishAttempts)
+	}
+
+	envelopes := publishedEnvelopes(t, broker.MemoryBroker)
+	if _, ok := envelopes[failing.AggregateID]; ok || len(envelopes) != 1 {
+		t.Fatalf("got published %+v, want only the other aggregate", envelopes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayDeadLettersAfterMaxRetries(t *testing.T) {
+	row := newOrderCreated(t, uuid.NewString(), testConfig.MaxRetries-1)
+	relay, mock := newTestRelay(t, newFlakyBroker(map[string]int{row.AggregateID: 100}), testConfig)
+
+	var token string
+	expectClaim(t, mock, &token, row)
+	expectFailure(mock, &token, row.ID, true)
+	expectRelease(mock, &token)
+
+	if _, err := relay.processBatch(context.Background()); err == nil {
+		t.Fatal("got no error for a row that kept failing")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRelayNextDelay(t *testing.T) {
+	relay := NewRelay(nil, nil, RelayConfig{PollInterval: time.Second, BatchSize: 10, MaxBackoff: 5 * time.Second})
+	failed := errors.New("broker unavailable")
+
+	for _, tc := range []struct {
+		name    string
+		delay   time.Duration
+		claimed int
+		err     error
+		want    time.Duration
+	}{
+		{"idle outbox polls at the interval", time.Second, 0, nil, time.Second},
+		{"full batch goes on right away", time.Second, 10, nil, 0},
+		{"partial batch polls at the interval", 0, 4, nil, time.Second},
+		{"first failure backs off from the interval", 0, 0, failed, time.Second},
+		{"failures double the delay", 2 * time.Second, 10, failed, 4 * time.Second},
+		{"backoff is capped", 4 * time.Second, 0, failed, 5 * time.Second},
+		{"success after failures resets the delay", 5 * time.Second, 1, nil, time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := relay.nextDelay(tc.delay, tc.claimed, tc.err); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -5,32 +5,37 @@ import (
 	"fmt"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"gorm.io/gorm"
 )
 
 type OrderRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	cursorKey []byte
 }
 
-func NewOrderRepository(db *gorm.DB) *OrderRepository {
+// NewOrderRepository signs the cursors of order pages with cursorKey, see utils.Paginate.
+func NewOrderRepository(db *gorm.DB, cursorKey []byte) *OrderRepository {
 	// db = db.Session(&gorm.Session{
 	// 	PrepareStmt: false,
 	// })
-	return &OrderRepository{db: db}
+	return &OrderRepository{db: db, cursorKey: cursorKey}
 }
 
-func (r *OrderRepository) GetOrders(jsonPayload map[string]interface{}) ([]models.Order, error) {
-	var orders []models.Order
-
-	// Preload order items with the order
-	results := r.db.Model(&models.Order{}).
-		Preload("Items").
-		Where(jsonPayload).
-		Find(&orders)
-	return orders, results.Error
+// GetOrders returns a page of the user's orders with their items, see utils.Paginate. The orders
+// and their items are read from a replica when there is one, see postgres.ReadReplica.
+func (r *OrderRepository) GetOrders(ctx context.Context, userID string, query *utils.ListQuery) (utils.Paginated[models.Order], error) {
+	//preloads run as separate statements that do not inherit the clauses of the parent query
+	q := postgres.ReadReplica(r.db).Model(&models.Order{}).Where("user_id = ?", userID).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return postgres.ReadReplica(db)
+	})
+	return utils.Paginate[models.Order](ctx, q, query, r.cursorKey)
 }
 
-func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+// CreateOrder inserts the order, its items and any outbox events in one transaction,
+// so an event is only ever published for an order that was actually committed.
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order, events ...models.ServiceOutbox) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Create the order row first
 		if err := tx.Omit("Items").Create(order).Error; err != nil {
@@ -47,6 +52,12 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order *models.Order)
 			}
 		}
 
+		if len(events) > 0 {
+			if err := tx.Create(&events).Error; err != nil {
+				return fmt.Errorf("failed to write order events to outbox: %w", err)
+			}
+		}
+
 		return nil
 	})
 }
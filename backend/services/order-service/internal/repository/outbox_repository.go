@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// outboxClaimLock is the pg_advisory_xact_lock key that serializes claiming between relay instances
+const outboxClaimLock int64 = 0x6f7574626f78
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimPending claims up to limit unpublished rows for lease and returns them in creation order with the claim token.
+// A row is skipped while an earlier row of its aggregate is claimed by another relay or dead lettered, so every
+// aggregate is published by one relay at a time and in order. Nothing stays locked once ClaimPending returns,
+// the rows are published outside of any transaction and finished with MarkPublished or RecordFailure.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) (string, []models.ServiceOutbox, error) {
+	token := uuid.NewString()
+	var rows []models.ServiceOutbox
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//claims of concurrent relays would not see each other before they commit
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", outboxClaimLock).Error; err != nil {
+			return fmt.Errorf("failed to take outbox claim lock: %w", err)
+		}
+
+		if err := tx.Raw(`SELECT o.* FROM service_outbox o
+			WHERE o.is_published = false
+				AND o.dead_lettered_at IS NULL
+				AND (o.claimed_until IS NULL OR o.claimed_until < now())
+				AND NOT EXISTS (
+					SELECT 1 FROM service_outbox earlier
+					WHERE earlier.aggregate_type = o.aggregate_type
+						AND earlier.aggregate_id = o.aggregate_id
+						AND earlier.is_published = false
+						AND (earlier.created_at, earlier.id) < (o.created_at, o.id)
+						AND (earlier.dead_lettered_at IS NOT NULL OR earlier.claimed_until >= now())
+				)
+			ORDER BY o.created_at ASC, o.id ASC
+			LIMIT ?`, limit).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to fetch pending outbox rows: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+		if err := tx.Model(&models.ServiceOutbox{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"claim_token":   token,
+				"claimed_until": gorm.Expr("now() + make_interval(secs => ?)", lease.Seconds()),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to claim outbox rows: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, rows, nil
+}
+
+// MarkPublished finishes a claimed row. A row whose claim expired and was taken over is left to the new claim.
+func (r *OutboxRepository) MarkPublished(ctx context.Context, token string, id string) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(&models.ServiceOutbox{}).
+		Where("id = ? AND claim_token = ?", id, token).
+		Updates(map[string]interface{}{
+			"is_published":  true,
+			"published_at":  now,
+			"last_error":    nil,
+			"claim_token":   nil,
+			"claimed_until": nil,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to mark outbox row as published: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed publish and releases the row. A dead lettered row is not claimed again
+// and holds back later rows of its aggregate until dead_lettered_at is cleared.
+func (r *OutboxRepository) RecordFailure(ctx context.Context, token string, id string, lastError string, deadLetter bool) error {
+	updates := map[string]interface{}{
+		"retry_count":   gorm.Expr("retry_count + 1"),
+		"last_error":    lastError,
+		"claim_token":   nil,
+		"claimed_until": nil,
+	}
+	if deadLetter {
+		updates["dead_lettered_at"] = time.Now()
+	}
+
+	if err := r.db.WithContext(ctx).Model(&models.ServiceOutbox{}).
+		Where("id = ? AND claim_token = ?", id, token).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record outbox failure: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseClaims gives the unfinished rows of a claim back, e.g. the rows after a failure of their aggregate.
+func (r *OutboxRepository) ReleaseClaims(ctx context.Context, token string) error {
+	if err := r.db.WithContext(ctx).Model(&models.ServiceOutbox{}).
+		Where("claim_token = ? AND is_published = ?", token, false).
+		Updates(map[string]interface{}{
+			"claim_token":   nil,
+			"claimed_until": nil,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to release outbox claims: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockOutboxRepository(t *testing.T) (*OutboxRepository, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+
+	return NewOutboxRepository(db), mock
+}
+
+// capturedString records the first string it is matched against and only matches that string afterwards.
+type capturedString struct {
+	value *string
+}
+
+func (c capturedString) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return false
+	}
+	if *c.value == "" {
+		*c.value = s
+	}
+	return s == *c.value
+}
+
+func TestOutboxRepositoryClaimPending(t *testing.T) {
+	repository, mock := newMockOutboxRepository(t)
+	createdAt := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	var written string
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(outboxClaimLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT o\.\* FROM service_outbox o .* ORDER BY o\.created_at ASC, o\.id ASC\s+LIMIT \$1`).
+		WithArgs(25).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at"}).
+			AddRow("row-1", "order", "order-1", "order.created", []byte(`{"orderId":"order-1"}`), createdAt).
+			AddRow("row-2", "order", "order-2", "order.created", []byte(`{"orderId":"order-2"}`), createdAt))
+	mock.ExpectExec(`UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=now\(\) \+ make_interval\(secs => \$2\) WHERE id IN \(\$3,\$4\)`).
+		WithArgs(capturedString{&written}, 90.0, "row-1", "row-2").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	token, rows, err := repository.ClaimPending(context.Background(), 25, 90*time.Second)
+	if err != nil {
+		t.Fatalf("ClaimPending: %v", err)
+	}
+	if token == "" || token != written {
+		t.Fatalf("got token %q, want the token %q written to the rows", token, written)
+	}
+	if len(rows) != 2 || rows[0].ID != "row-1" || rows[1].ID != "row-2" || rows[0].Payload["orderId"] != "order-1" {
+		t.Fatalf("got rows %+v", rows)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxRepositoryClaimPendingWithoutRows(t *testing.T) {
+	repository, mock := newMockOutboxRepository(t)
+
+	//nothing is claimed when no row is pending
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT o\.\* FROM service_outbox o`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	_, rows, err := repository.ClaimPending(context.Background(), 25, time.Minute)
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("got rows %+v, error %v, want none", rows, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxRepositoryClaimTokenFencing(t *testing.T) {
+	ctx := context.Background()
+
+	//every write after the claim only touches rows that still carry its token, a row taken over
+	//by another relay after the lease ran out is left alone
+	for _, tc := range []struct {
+		name string
+		call func(repository *OutboxRepository) error
+		sql  string
+		args []driver.Value
+	}{
+		{"mark published", func(repository *OutboxRepository) error {
+			return repository.MarkPublished(ctx, "token", "row-1")
+		}, `UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2,"is_published"=\$3,"last_error"=\$4,"published_at"=\$5 WHERE id = \$6 AND claim_token = \$7`,
+			[]driver.Value{nil, nil, true, nil, sqlmock.AnyArg(), "row-1", "token"}},
+		{"record failure", func(repository *OutboxRepository) error {
+			return repository.RecordFailure(ctx, "token", "row-1", "broker down", false)
+		}, `UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2,"last_error"=\$3,"retry_count"=retry_count \+ 1 WHERE id = \$4 AND claim_token = \$5`,
+			[]driver.Value{nil, nil, "broker down", "row-1", "token"}},
+		{"dead letter", func(repository *OutboxRepository) error {
+			return repository.RecordFailure(ctx, "token", "row-1", "broker down", true)
+		}, `UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2,"dead_lettered_at"=\$3,"last_error"=\$4,"retry_count"=retry_count \+ 1 WHERE id = \$5 AND claim_token = \$6`,
+			[]driver.Value{nil, nil, sqlmock.AnyArg(), "broker down", "row-1", "token"}},
+		{"release claims", func(repository *OutboxRepository) error {
+			return repository.ReleaseClaims(ctx, "token")
+		}, `UPDATE "service_outbox" SET "claim_token"=\$1,"claimed_until"=\$2 WHERE claim_token = \$3 AND is_published = \$4`,
+			[]driver.Value{nil, nil, "token", false}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repository, mock := newMockOutboxRepository(t)
+
+			mock.ExpectExec(tc.sql).WithArgs(tc.args...).WillReturnResult(sqlmock.NewResult(0, 1))
+			if err := tc.call(repository); err != nil {
+				t.Fatalf("got %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
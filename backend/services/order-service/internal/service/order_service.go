@@ -3,71 +3,70 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
 	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/clients"
-	"github.com/Flow-Indo/LAKOO/backend/services/order-service/config"
+	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/outbox"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/internal/repository"
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/models"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/order-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/httpclient"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/idempotency"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 )
 
 type OrderService struct {
 	orderRepository *repository.OrderRepository
-	producer        *kafka.KafkaProducer
 	cartClient      *clients.CartClient
 }
 
-func NewService(orderRepository *repository.OrderRepository) *OrderService {
-	brokers := strings.Split(config.Envs.KAFKA_BROKERS, ",")
-	if len(brokers) == 0 || brokers[0] == "" {
-		brokers = []string{"localhost:9092"} // local dev fallback
-	}
+func NewService(orderRepository *repository.OrderRepository, cartClient *clients.CartClient) *OrderService {
 	return &OrderService{
 		orderRepository: orderRepository,
-		producer: kafka.NewProducer(
-			brokers,
-			"order_event",
-		),
-		cartClient: clients.NewCartClient(),
+		cartClient:      cartClient,
 	}
 }
 
-func (service *OrderService) GetOrders(filterPaylod types.OrderFilterPayload) ([]types.OrderResponse, error) {
-	jsonPayload, err := utils.PayloadToMap(filterPaylod)
+func (service *OrderService) GetOrders(ctx context.Context, userID string, query *utils.ListQuery) (types.PaginatedOrdersResponse, error) {
+	orders, err := service.orderRepository.GetOrders(ctx, userID, query)
 	if err != nil {
-		return []types.OrderResponse{}, err
+		return types.PaginatedOrdersResponse{}, err
 	}
 
-	orders, err := service.orderRepository.GetOrders(jsonPayload)
-	if err != nil {
-		return []types.OrderResponse{}, err
+	response := types.PaginatedOrdersResponse{
+		Data:       service.parseToOrderResponse(orders.Data),
+		Pagination: orders.Pagination,
+	}
+	if response.Data == nil {
+		response.Data = []types.OrderResponse{}
 	}
 
-	return service.parseToOrderResponse(orders), nil
+	return response, nil
 }
 
 func (service *OrderService) CreateOrder(createOrderPayload types.CreateOrderPayload, ctx context.Context) (*models.Order, error) {
 	// Validate basic UUID format early
 	if _, err := uuid.Parse(createOrderPayload.UserID); err != nil {
-		return nil, fmt.Errorf("invalid userId: %w", err)
+		return nil, apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid userId")
 	}
 
 	// Fetch cart snapshot for pricing + product snapshot fields
-	cart, err := service.cartClient.GetCart(createOrderPayload.UserID)
+	cart, err := service.cartClient.GetCart(ctx, createOrderPayload.UserID)
 	if err != nil {
-		return nil, err
+		return nil, httpclient.AppError(err, "cart is not available, please try again")
 	}
 
 	now := time.Now()
 	order := &models.Order{
+		ID:             uuid.NewString(), //generated here so the outbox event can reference the order inside the same transaction
 		OrderNumber:    fmt.Sprintf("ORD-%d", now.UnixNano()),
 		UserID:         createOrderPayload.UserID,
 		OrderSource:    models.OrderSourceBrand,
@@ -100,6 +99,11 @@ func (service *OrderService) CreateOrder(createOrderPayload types.CreateOrderPay
 		order.ShippingPostalCode = "00000"
 	}
 
+	// Retries with the same Idempotency-Key are answered by the middleware, the key stays on the order for support
+	if key := idempotency.KeyFromContext(ctx); key != "" {
+		order.IdempotencyKey = &key
+	}
+
 	// Build order items from cart snapshot (fallback to request items if cart is empty)
 	var items []models.OrderItem
 	var subtotal decimal.Decimal
@@ -143,18 +147,23 @@ func (service *OrderService) CreateOrder(createOrderPayload types.CreateOrderPay
 	order.Subtotal = subtotal
 	order.TotalAmount = subtotal
 
-	if err := service.orderRepository.CreateOrder(ctx, order); err != nil {
+	envelope, err := kafka.NewEnvelope(kafka.EventOrderCreated, "order-service", tracing.CorrelationIDFromContext(ctx), service.toOrderCreatedEvent(order))
+	if err != nil {
+		return nil, err
+	}
+	orderCreated, err := outbox.NewEvent(ctx, "Order", order.ID, envelope)
+	if err != nil {
 		return nil, err
 	}
 
-	// IMPORTANT (MVP): clear cart after successful order creation.
-	// If this fails, we log but do not fail the order (can be retried).
-	if err := service.cartClient.ClearCart(createOrderPayload.UserID); err != nil {
-		log.Printf("Warning: failed to clear cart for user %s: %v", createOrderPayload.UserID, err)
+	if err := service.orderRepository.CreateOrder(ctx, order, orderCreated); err != nil {
+		return nil, err
 	}
 
-	if err := service.producer.PublishMessage(ctx, []byte("testing"), []byte("Created Order")); err != nil {
-		log.Printf("Could not publish message in Create Order, %v", err)
+	// IMPORTANT (MVP): clear cart after successful order creation.
+	// If this fails, we log but do not fail the order (can be retried).
+	if err := service.cartClient.ClearCart(ctx, createOrderPayload.UserID); err != nil {
+		logging.FromContext(ctx).Warn("failed to clear cart", zap.String("user_id", createOrderPayload.UserID), zap.Error(err))
 	}
 
 	return order, nil
@@ -257,5 +266,33 @@ func (service *OrderService) toOrderItemResponses(orderItems []models.OrderItem)
 	return responses
 }
 
+func (service *OrderService) toOrderCreatedEvent(order *models.Order) kafka.OrderCreatedEvent {
+	items := make([]kafka.OrderItemEvent, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = kafka.OrderItemEvent{
+			ProductID: item.ProductID,
+			SellerID:  item.SellerID,
+			BrandID:   item.BrandID,
+			Name:      item.SnapshotProductName,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice.InexactFloat64(),
+		}
+	}
+
+	return kafka.OrderCreatedEvent{
+		OrderID:       order.ID,
+		OrderNumber:   order.OrderNumber,
+		UserID:        order.UserID,
+		SellerID:      order.SellerID,
+		OrderSource:   string(order.OrderSource),
+		TotalAmount:   order.TotalAmount.InexactFloat64(),
+		Currency:      order.Currency,
+		CustomerName:  order.CustomerName,
+		CustomerPhone: order.CustomerPhone,
+		Items:         items,
+		CreatedAt:     order.CreatedAt,
+	}
+}
+
 // Note: User model doesn't exist in order-service
 // User data should be fetched from auth-service via HTTP client if needed
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/shopspring/decimal"
 )
 
@@ -44,7 +45,7 @@ const (
 type Order struct {
 	ID          string      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	OrderNumber string      `gorm:"type:varchar(50);uniqueIndex;not null;column:order_number" json:"order_number"`
-	UserID      string      `gorm:"type:uuid;not null;column:user_id" json:"user_id"`
+	UserID      string      `gorm:"type:uuid;not null;column:user_id;uniqueIndex:idx_order_user_idempotency_key" json:"user_id"`
 	OrderSource OrderSource `gorm:"type:varchar(20);not null;default:'brand';column:order_source" json:"order_source"`
 
 	// Source references (mutually exclusive based on orderSource)
@@ -112,7 +113,7 @@ type Order struct {
 	LiveSessionID *string `gorm:"type:uuid;column:live_session_id" json:"live_session_id"`
 
 	// Idempotency
-	IdempotencyKey *string `gorm:"type:varchar(100);uniqueIndex;column:idempotency_key" json:"idempotency_key"`
+	IdempotencyKey *string `gorm:"type:varchar(100);uniqueIndex:idx_order_user_idempotency_key;column:idempotency_key" json:"idempotency_key"`
 
 	// Audit
 	CreatedBy *string   `gorm:"type:uuid;column:created_by" json:"created_by"`
@@ -176,3 +177,28 @@ type OrderItem struct {
 func (OrderItem) TableName() string {
 	return "order_item"
 }
+
+// ServiceOutbox holds domain events written in the same transaction as the aggregate change.
+// The outbox relay claims pending rows, publishes them to kafka and marks them as published.
+// Rows that failed MaxRetries times are dead lettered and hold back later events of their aggregate
+// until an operator clears dead_lettered_at and retry_count.
+type ServiceOutbox struct {
+	ID             string      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	AggregateType  string      `gorm:"type:varchar(100);not null;column:aggregate_type;index:idx_service_outbox_aggregate" json:"aggregate_type"`
+	AggregateID    string      `gorm:"type:uuid;not null;column:aggregate_id;index:idx_service_outbox_aggregate" json:"aggregate_id"`
+	EventType      string      `gorm:"type:varchar(100);not null;column:event_type" json:"event_type"`
+	Payload        utils.JSONB `gorm:"type:jsonb;not null" json:"payload"`
+	Metadata       utils.JSONB `gorm:"type:jsonb" json:"metadata"`
+	IsPublished    bool        `gorm:"not null;default:false;column:is_published;index:idx_service_outbox_published" json:"is_published"`
+	PublishedAt    *time.Time  `gorm:"type:timestamptz;column:published_at" json:"published_at"`
+	RetryCount     int         `gorm:"not null;default:0;column:retry_count" json:"retry_count"`
+	LastError      *string     `gorm:"type:text;column:last_error" json:"last_error"`
+	ClaimToken     *string     `gorm:"type:uuid;column:claim_token" json:"-"`
+	ClaimedUntil   *time.Time  `gorm:"type:timestamptz;column:claimed_until" json:"-"`
+	DeadLetteredAt *time.Time  `gorm:"type:timestamptz;column:dead_lettered_at" json:"dead_lettered_at"`
+	CreatedAt      time.Time   `gorm:"type:timestamptz;not null;default:now();column:created_at;index:idx_service_outbox_published" json:"created_at"`
+}
+
+func (ServiceOutbox) TableName() string {
+	return "service_outbox"
+}
@@ -1,19 +1,5 @@
 package types
 
-import "time"
-
-type OrderFilterPayload struct {
-	userId        string    `schema:"user_id"`
-	factoryId     string    `schema:"factory_id"`
-	status        string    `schema:"status"`
-	isGroupBuying bool      `schema:"is_group_buying"`
-	search        string    `schema:"search"`
-	page          int       `schema:"page"`
-	limit         int       `schema:"limit"`
-	startDate     time.Time `schema:"start_date"`
-	endDate       time.Time `schema:"end_date"`
-}
-
 type CreateOrderPayload struct {
 	UserID          string                 `json:"userId" validate:"required,uuid4"`
 	Items           []OrderItemPayload     `json:"items" validate:"required,min=1,dive"`
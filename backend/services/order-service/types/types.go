@@ -1,5 +1,7 @@
 package types
 
+import "github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+
 type ProductSnapshot struct {
 	Factory  ProductSnapshotFactory  `json:"factory"`
 	Product  ProductSnapshotProduct  `json:"product"`
@@ -32,14 +34,4 @@ type ProductSnapshotCategory struct {
 	Slug string `json:"slug"`
 }
 
-type PaginatedOrdersResponse struct {
-	Data       []OrderResponse `json:"data"`
-	Pagination Pagination      `json:"pagination"`
-}
-
-type Pagination struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	Total      int `json:"total"`
-	TotalPages int `json:"totalPages"`
-}
+type PaginatedOrdersResponse = utils.Paginated[OrderResponse]
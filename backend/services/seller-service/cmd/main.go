@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/config"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/db"
@@ -10,7 +12,10 @@ import (
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/internal/repository"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/internal/service"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/internal/storage"
-	"github.com/Flow-Indo/LAKOO/backend/shared/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/migrate"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -29,10 +34,41 @@ import (
 // @host localhost:3015
 // @BasePath /api/sellers
 func main() {
+	// Initialize logger, the standard log package is routed through it too
+	if err := config.LoadLogging(); err != nil {
+		log.Fatal("invalid logging configuration: ", err)
+	}
+	logger, err := logging.Init("seller-service", config.Envs.LOG)
+	if err != nil {
+		log.Fatal("Failed to create logger: ", err)
+	}
+	defer logger.Sync()
+
+	if err := config.Load(); err != nil {
+		logger.Fatal("invalid configuration", zap.Error(err))
+	}
+
 	// Initialize database connection
 	database, err := db.NewPostgresStore()
 	if err != nil {
-		log.Fatal("Failed to connect to database: ", err)
+		logger.Fatal("failed to connect to database", zap.Error(err))
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		logger.Fatal("failed to load migrations", zap.Error(err))
+	}
+	//"<binary> migrate [up | down [steps] | status]" only migrates and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate.Command(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+	if config.Envs.DB_AUTO_MIGRATE {
+		if _, err := migrator.Up(context.Background()); err != nil {
+			logger.Fatal("failed to apply migrations", zap.Error(err))
+		}
 	}
 
 	initDatabase(database)
@@ -40,40 +76,64 @@ func main() {
 	// Initialize S3 Uploader
 	s3Uploader, err := storage.NewS3Uploader()
 	if err != nil {
-		log.Fatal("Failed to initialize S3 uploader: ", err)
+		logger.Fatal("failed to initialize S3 uploader", zap.Error(err))
+	}
+
+	userAuth, err := config.Envs.USER_AUTH.Config()
+	if err != nil {
+		logger.Fatal("invalid user auth configuration", zap.Error(err))
 	}
 
 	// Initialize API server
 	apiServer := api.NewServer(api.ServerConfig{
 		Addr:        config.Envs.SELLER_SERVICE_PORT,
+		MetricsAddr: config.Envs.METRICS_ADDR,
 		DB:          database,
 		ServiceName: "seller-service",
 		APIPrefix:   "sellers",
+		Timeouts:    config.Envs.HTTP_TIMEOUTS,
+		UserAuth:    userAuth,
 	})
 
+	// Readiness: the database is checked by the server, uploads need the bucket
+	apiServer.AddHealthCheck(api.CheckFunc("s3", s3Uploader.Ping))
+
 	// Initialize dependencies
-	sellerRepo := repository.NewSellerRepository(database)
+	sellerRepo := repository.NewSellerRepository(database, []byte(config.Envs.PAGINATION_CURSOR_KEY))
 	sellerService := service.NewSellerService(sellerRepo, s3Uploader)
-	sellerHandler := controller.NewSellerHandler(sellerService)
+	sellerHandler := controller.NewSellerHandler(sellerService, controller.SellerHandlerConfig{
+		FinanceReauthWindow: config.Envs.FINANCE_REAUTH_WINDOW,
+		Authenticated:       apiServer.Authenticated,
+		Idempotent:          apiServer.Idempotent,
+	})
 
 	// Register routes
 	apiServer.RegisterRoutes(sellerHandler.RegisterRoutes)
 
-	// Start server
-	if err := apiServer.Start(); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	// Close the pool once in-flight requests are done
+	apiServer.OnShutdown("database", func(ctx context.Context) error {
+		sqlDB, err := database.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+
+	// Start server, returns after a graceful shutdown on SIGTERM
+	if err := apiServer.Run(context.Background()); err != nil {
+		logger.Fatal("server stopped", zap.Error(err))
 	}
 }
 
 func initDatabase(gormDB *gorm.DB) {
 	db, err := gormDB.DB()
 	if err != nil {
-		log.Fatal("Unable to get generic DB from gorm: ", err)
+		logging.L().Fatal("unable to get generic DB from gorm", zap.Error(err))
 	}
 
 	if err := db.Ping(); err != nil {
-		log.Fatal("Unable to connect to database: ", err)
+		logging.L().Fatal("unable to connect to database", zap.Error(err))
 	}
 
-	log.Println("DB: Successfully connected")
+	logging.L().Info("database connected")
 }
@@ -1,43 +1,60 @@
 package config
 
 import (
-	"github.com/Flow-Indo/LAKOO/backend/shared/env"
-	"github.com/lpernett/godotenv"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/api"
+	sharedconfig "github.com/Flow-Indo/LAKOO/backend/shared/go/config"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
 )
 
 type Config struct {
-	SELLER_SERVICE_PORT string
-	DB_USER             string
-	DB_PASSWORD         string
-	DB_NAME             string
-	DB_HOST             string
-	DB_PORT             string
-	DB_SSL              string
-
-	AWS_REGION            string
-	AWS_S3_BUCKET         string
-	AWS_ACCESS_KEY_ID     string
-	AWS_SECRET_ACCESS_KEY string
-	AWS_S3_PREFIX         string
+	SELLER_SERVICE_PORT string `env:"SELLER_SERVICE_PORT" default:":3015"`
+	METRICS_ADDR        string `env:"METRICS_ADDR" default:":9115"`
+	DB_USER             string `env:"DB_USER,required"`
+	DB_PASSWORD         string `env:"DB_PASSWORD,required,secret"`
+	DB_NAME             string `env:"DB_NAME,required"`
+	DB_HOST             string `env:"DB_HOST" default:"localhost"`
+	DB_PORT             int    `env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+	DB_SSL              string `env:"DB_SSL" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+	DB_AUTO_MIGRATE     bool   `env:"DB_AUTO_MIGRATE" default:"false"`
+
+	//the access keys may be left out when the instance role grants access to the bucket
+	AWS_REGION            string `env:"AWS_REGION,required"`
+	AWS_S3_BUCKET         string `env:"AWS_S3_BUCKET,required"`
+	AWS_ACCESS_KEY_ID     string `env:"AWS_ACCESS_KEY_ID"`
+	AWS_SECRET_ACCESS_KEY string `env:"AWS_SECRET_ACCESS_KEY,secret"`
+	AWS_S3_PREFIX         string `env:"AWS_S3_PREFIX" default:"seller-verification/"`
+
+	FINANCE_REAUTH_WINDOW time.Duration `env:"SELLER_FINANCE_REAUTH_WINDOW" default:"10m" validate:"gt=0"`
+
+	//signs the ?cursor= of list endpoints, the same on every instance
+	PAGINATION_CURSOR_KEY string `env:"PAGINATION_CURSOR_KEY,required,secret" validate:"min=32"`
+
+	//pool sizes, statement timeout, slow query threshold and read replicas, see postgres.Options
+	DB_POOL postgres.Options
+
+	//JWKS for bearer tokens and the secret of the gateway headers, see middleware.UserAuthOptions
+	USER_AUTH middleware.UserAuthOptions
+
+	//read, write, idle and shutdown timeouts of the server, see api.Timeouts
+	HTTP_TIMEOUTS api.Timeouts
+
+	//loaded before the rest, see LoadLogging
+	LOG logging.Options
 }
 
-func initConfig() *Config {
-	godotenv.Load(".env")
-	return &Config{
-		SELLER_SERVICE_PORT: env.GetEnv("SELLER_SERVICE_PORT", ":3015"),
-		DB_USER:             env.GetEnv("DB_USER", "user"),
-		DB_PASSWORD:         env.GetEnv("DB_PASSWORD", "password"),
-		DB_NAME:             env.GetEnv("DB_NAME", "dbname"),
-		DB_HOST:             env.GetEnv("DB_HOST", "localhost"),
-		DB_PORT:             env.GetEnv("DB_PORT", "5432"),
-		DB_SSL:              env.GetEnv("DB_SSL", "disable"),
-
-		AWS_REGION:            env.GetEnv("AWS_REGION", ""),
-		AWS_S3_BUCKET:         env.GetEnv("AWS_S3_BUCKET", ""),
-		AWS_ACCESS_KEY_ID:     env.GetEnv("AWS_ACCESS_KEY_ID", ""),
-		AWS_SECRET_ACCESS_KEY: env.GetEnv("AWS_SECRET_ACCESS_KEY", ""),
-		AWS_S3_PREFIX:         env.GetEnv("AWS_S3_PREFIX", "seller-verification/"),
-	}
+// Envs is filled by Load at startup.
+var Envs = &Config{}
+
+// Load reads the config from the environment, CONFIG_FILE and Docker secrets, see sharedconfig.Load.
+func Load() error {
+	return sharedconfig.Load(Envs, sharedconfig.Options{})
 }
 
-var Envs = initConfig()
+// LoadLogging only fills the LOG options of Envs, quietly, so the logger is up before Load logs the config.
+func LoadLogging() error {
+	return sharedconfig.Load(&Envs.LOG, sharedconfig.Options{Quiet: true})
+}
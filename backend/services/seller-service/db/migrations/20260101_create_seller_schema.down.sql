@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS seller_payout_schedule;
+DROP TABLE IF EXISTS seller_payout_item;
+DROP TABLE IF EXISTS seller_payout;
+DROP TABLE IF EXISTS seller_document;
+DROP TABLE IF EXISTS seller;
+
+DROP TYPE IF EXISTS payout_frequency;
+DROP TYPE IF EXISTS payout_status;
+DROP TYPE IF EXISTS doc_status;
+DROP TYPE IF EXISTS seller_doc_type;
+DROP TYPE IF EXISTS seller_status;
+DROP TYPE IF EXISTS verification_status;
+DROP TYPE IF EXISTS business_type;
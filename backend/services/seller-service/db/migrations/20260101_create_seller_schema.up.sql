@@ -0,0 +1,168 @@
+-- Seller schema: sellers, their verification documents and payouts.
+-- Written for databases created before migrations were tracked too, so it is safe to re-run.
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+DO $$ BEGIN
+  CREATE TYPE business_type AS ENUM ('individual', 'company');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE verification_status AS ENUM ('pending', 'in_review', 'verified', 'rejected');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE seller_status AS ENUM ('pending', 'active', 'suspended', 'closed');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE seller_doc_type AS ENUM ('ktp', 'npwp', 'nib', 'siup', 'bank_statement', 'other');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE doc_status AS ENUM ('pending', 'approved', 'rejected', 'expired');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE payout_status AS ENUM ('pending', 'approved', 'processing', 'paid', 'rejected', 'failed');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+DO $$ BEGIN
+  CREATE TYPE payout_frequency AS ENUM ('daily', 'weekly', 'biweekly', 'monthly');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE TABLE IF NOT EXISTS seller (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL UNIQUE,
+  seller_code varchar(50) NOT NULL UNIQUE,
+  shop_name varchar(255) NOT NULL,
+  shop_slug varchar(255) NOT NULL UNIQUE,
+  shop_description text,
+  shop_logo_url text,
+  shop_banner_url text,
+  shop_announcement varchar(500),
+  business_name varchar(255),
+  business_type business_type NOT NULL DEFAULT 'individual',
+  business_license varchar(100),
+  tax_id varchar(100),
+  contact_name varchar(255) NOT NULL,
+  contact_email varchar(255) NOT NULL,
+  contact_phone varchar(20) NOT NULL,
+  contact_whatsapp varchar(20),
+  address text,
+  district varchar(100),
+  city varchar(100),
+  province varchar(100),
+  postal_code varchar(10),
+  bank_name varchar(100),
+  bank_account_name varchar(255),
+  bank_account_number varchar(50),
+  bank_branch varchar(100),
+  commission_rate numeric(5,2) NOT NULL DEFAULT 0.00,
+  total_products integer NOT NULL DEFAULT 0,
+  total_orders integer NOT NULL DEFAULT 0,
+  total_revenue numeric(15,2) NOT NULL DEFAULT 0,
+  avg_rating numeric(3,2),
+  review_count integer NOT NULL DEFAULT 0,
+  response_rate numeric(5,2),
+  response_time integer,
+  badges text[],
+  is_featured boolean NOT NULL DEFAULT false,
+  verification_status verification_status NOT NULL DEFAULT 'pending',
+  verified_at timestamptz,
+  verified_by uuid,
+  status seller_status NOT NULL DEFAULT 'pending',
+  suspended_at timestamptz,
+  suspend_reason varchar(500),
+  auto_accept_orders boolean NOT NULL DEFAULT true,
+  vacation_mode boolean NOT NULL DEFAULT false,
+  vacation_message varchar(500),
+  vacation_until timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_deleted_at ON seller(deleted_at);
+
+CREATE TABLE IF NOT EXISTS seller_document (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  seller_id uuid NOT NULL REFERENCES seller(id) ON DELETE CASCADE,
+  document_type seller_doc_type NOT NULL,
+  document_number varchar(100),
+  file_url text NOT NULL,
+  file_name varchar(255) NOT NULL,
+  file_size integer,
+  status doc_status NOT NULL DEFAULT 'pending',
+  verified_at timestamptz,
+  verified_by uuid,
+  rejected_at timestamptz,
+  rejection_reason varchar(500),
+  expires_at timestamptz,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_document_seller_id ON seller_document(seller_id);
+
+CREATE TABLE IF NOT EXISTS seller_payout (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  seller_id uuid NOT NULL REFERENCES seller(id) ON DELETE CASCADE,
+  payout_number varchar(50) NOT NULL UNIQUE,
+  period_start date NOT NULL,
+  period_end date NOT NULL,
+  gross_amount numeric(15,2) NOT NULL,
+  commission_amount numeric(15,2) NOT NULL DEFAULT 0,
+  adjustment_amount numeric(15,2) NOT NULL DEFAULT 0,
+  net_amount numeric(15,2) NOT NULL,
+  order_count integer NOT NULL,
+  item_count integer NOT NULL,
+  bank_name varchar(100) NOT NULL,
+  bank_account_name varchar(255) NOT NULL,
+  bank_account_number varchar(50) NOT NULL,
+  status payout_status NOT NULL DEFAULT 'pending',
+  approved_at timestamptz,
+  approved_by uuid,
+  processed_at timestamptz,
+  paid_at timestamptz,
+  rejected_at timestamptz,
+  rejection_reason varchar(500),
+  transfer_reference varchar(255),
+  transfer_proof_url text,
+  notes text,
+  internal_notes text,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_payout_seller_id ON seller_payout(seller_id);
+CREATE INDEX IF NOT EXISTS idx_seller_payout_status ON seller_payout(status);
+
+CREATE TABLE IF NOT EXISTS seller_payout_item (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  payout_id uuid NOT NULL REFERENCES seller_payout(id) ON DELETE CASCADE,
+  order_id uuid NOT NULL,
+  order_number varchar(50) NOT NULL,
+  order_date timestamptz NOT NULL,
+  gross_amount numeric(15,2) NOT NULL,
+  commission numeric(15,2) NOT NULL DEFAULT 0,
+  net_amount numeric(15,2) NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_payout_item_payout_id ON seller_payout_item(payout_id);
+CREATE INDEX IF NOT EXISTS idx_seller_payout_item_order_id ON seller_payout_item(order_id);
+
+CREATE TABLE IF NOT EXISTS seller_payout_schedule (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  seller_id uuid NOT NULL UNIQUE REFERENCES seller(id) ON DELETE CASCADE,
+  frequency payout_frequency NOT NULL DEFAULT 'weekly',
+  day_of_week integer CHECK (day_of_week BETWEEN 0 AND 6),
+  day_of_month integer CHECK (day_of_month BETWEEN 1 AND 31),
+  min_payout_amount numeric(15,2) NOT NULL DEFAULT 50000,
+  is_active boolean NOT NULL DEFAULT true,
+  next_payout_date date,
+  last_payout_date date,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
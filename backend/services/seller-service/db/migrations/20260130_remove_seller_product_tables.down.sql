@@ -0,0 +1,73 @@
+-- Recreate the seller-owned product tables, empty: the rows dropped by the up migration are not restored.
+-- Added after the up migration shipped, which is why its header still says there is no down migration.
+
+DO $$ BEGIN
+  CREATE TYPE product_status AS ENUM ('draft', 'active', 'inactive');
+EXCEPTION WHEN duplicate_object THEN NULL; END $$;
+
+CREATE TABLE IF NOT EXISTS seller_product (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  seller_id uuid NOT NULL REFERENCES seller(id) ON DELETE CASCADE,
+  warehouse_product_id uuid,
+  sku varchar(100),
+  name varchar(255) NOT NULL,
+  slug varchar(255) NOT NULL UNIQUE,
+  description text,
+  short_description varchar(500),
+  category_id uuid,
+  category_name varchar(255),
+  price numeric(15,2) NOT NULL,
+  compare_price numeric(15,2),
+  cost_price numeric(15,2),
+  track_inventory boolean NOT NULL DEFAULT true,
+  quantity integer NOT NULL DEFAULT 0,
+  low_stock_threshold integer DEFAULT 5,
+  weight_grams integer,
+  length_cm numeric(10,2),
+  width_cm numeric(10,2),
+  height_cm numeric(10,2),
+  images jsonb,
+  primary_image_url text,
+  has_variants boolean NOT NULL DEFAULT false,
+  status product_status NOT NULL DEFAULT 'draft',
+  published_at timestamptz,
+  meta_title varchar(255),
+  meta_description text,
+  tags text[],
+  view_count integer NOT NULL DEFAULT 0,
+  sold_count integer NOT NULL DEFAULT 0,
+  avg_rating numeric(3,2),
+  review_count integer NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_product_seller_id ON seller_product(seller_id);
+CREATE INDEX IF NOT EXISTS idx_seller_product_category_id ON seller_product(category_id);
+CREATE INDEX IF NOT EXISTS idx_seller_product_deleted_at ON seller_product(deleted_at);
+
+CREATE TABLE IF NOT EXISTS seller_product_variant (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  product_id uuid NOT NULL REFERENCES seller_product(id) ON DELETE CASCADE,
+  sku varchar(100),
+  name varchar(255) NOT NULL,
+  option1_name varchar(50),
+  option1_value varchar(100),
+  option2_name varchar(50),
+  option2_value varchar(100),
+  option3_name varchar(50),
+  option3_value varchar(100),
+  price numeric(15,2) NOT NULL,
+  compare_price numeric(15,2),
+  cost_price numeric(15,2),
+  quantity integer NOT NULL DEFAULT 0,
+  weight_grams integer,
+  image_url text,
+  is_active boolean NOT NULL DEFAULT true,
+  sort_order integer NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_product_variant_product_id ON seller_product_variant(product_id);
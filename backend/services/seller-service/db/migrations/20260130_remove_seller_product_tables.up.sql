@@ -1,12 +1,8 @@
 -- Remove legacy seller-owned product tables.
 -- Products now live in product_db (product-service).
 --
--- This migration is designed to be safe to re-run.
-
-BEGIN;
+-- This migration is designed to be safe to re-run. It has no down migration, the dropped rows are gone.
 
 DROP TABLE IF EXISTS seller_product_variant CASCADE;
 DROP TABLE IF EXISTS seller_product CASCADE;
 
-COMMIT;
-
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleStaff = "staff"
+)
+
+// permission describes who may call a route on a seller: the seller's own user and/or platform roles,
+// optionally only shortly after the user re-entered their credentials.
+type permission struct {
+	name   string
+	owner  bool
+	roles  []string
+	reauth bool
+}
+
+var (
+	// shop, products, analytics
+	permSellerRead  = permission{name: "seller:read", owner: true, roles: []string{RoleAdmin, RoleStaff}}
+	permSellerWrite = permission{name: "seller:write", owner: true, roles: []string{RoleAdmin}}
+
+	// bank details, balance and payouts, never visible to staff
+	permFinanceRead = permission{name: "finance:read", owner: true, roles: []string{RoleAdmin}}
+	// changing where money goes or moving it out is only done by the seller, right after logging in again
+	permFinanceWrite = permission{name: "finance:write", owner: true, reauth: true}
+)
+
+// authorize authenticates the user and lets the request through when permission allows
+// them to act on the seller in the path.
+func (h *SellerHandler) authorize(perm permission, next http.HandlerFunc) http.Handler {
+	return h.config.Authenticated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sellerID := mux.Vars(r)["sellerId"]
+
+		principal, err := middleware.GetPrincipalFromContext(r.Context())
+		if err != nil {
+			apperror.Write(w, r, apperror.Unauthenticated("authentication required"))
+			return
+		}
+
+		seller, err := h.service.GetSellerProfile(r.Context(), sellerID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				apperror.Write(w, r, apperror.NotFound("seller not found"))
+				return
+			}
+			apperror.Write(w, r, err)
+			return
+		}
+
+		isOwner := seller.UserID == principal.UserID || (principal.SellerID != "" && principal.SellerID == seller.ID)
+		allowed := (perm.owner && isOwner) || slices.ContainsFunc(perm.roles, func(role string) bool {
+			return principal.HasRole(role)
+		})
+		if !allowed {
+			denySeller(r, principal, perm, "not the seller owner and no role grants it")
+			apperror.Write(w, r, apperror.PermissionDenied("not allowed to access this seller"))
+			return
+		}
+
+		if perm.reauth && !recentlyAuthenticated(principal, h.config.FinanceReauthWindow) {
+			denySeller(r, principal, perm, "re-authentication required")
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication", max_age="`+
+				strconv.Itoa(int(h.config.FinanceReauthWindow.Seconds()))+`"`)
+			apperror.Write(w, r, apperror.Unauthenticated("please log in again to continue"))
+			return
+		}
+
+		next(w, r)
+	}))
+}
+
+func recentlyAuthenticated(principal *auth.Principal, window time.Duration) bool {
+	return !principal.AuthTime.IsZero() && time.Since(principal.AuthTime) <= window
+}
+
+func denySeller(r *http.Request, principal *auth.Principal, perm permission, reason string) {
+	logging.FromContext(r.Context()).Warn("audit: seller access denied",
+		zap.String("user_id", principal.UserID),
+		zap.Strings("roles", principal.Roles),
+		zap.String("permission", perm.name),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("reason", reason),
+	)
+}
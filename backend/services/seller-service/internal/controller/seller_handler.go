@@ -5,72 +5,90 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/internal/service"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/models"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"gorm.io/gorm"
 )
 
+type SellerHandlerConfig struct {
+	FinanceReauthWindow time.Duration                   // how recent a login has to be for bank changes and withdrawals
+	Authenticated       func(http.Handler) http.Handler // authenticates the end user, see authorize
+	Idempotent          func(http.Handler) http.Handler // honours Idempotency-Key on withdrawals
+}
+
 type SellerHandler struct {
 	service *service.SellerService
+	config  SellerHandlerConfig
 }
 
-func NewSellerHandler(service *service.SellerService) *SellerHandler {
-	return &SellerHandler{service: service}
+func NewSellerHandler(service *service.SellerService, config SellerHandlerConfig) *SellerHandler {
+	if config.FinanceReauthWindow <= 0 {
+		config.FinanceReauthWindow = 10 * time.Minute
+	}
+	if config.Idempotent == nil {
+		config.Idempotent = func(next http.Handler) http.Handler { return next }
+	}
+
+	return &SellerHandler{service: service, config: config}
 }
 
-func (h *SellerHandler) RegisterRoutes(r *mux.Router) {
+// RegisterRoutes only uses the external router, every seller route checks the caller with authorize.
+func (h *SellerHandler) RegisterRoutes(r *mux.Router, internal *mux.Router) {
 	// Swagger
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	// Seller Profile & Settings
-	r.HandleFunc("/{sellerId}", h.GetSellerProfile).Methods("GET")
-	r.HandleFunc("/{sellerId}/shop", h.UpdateShopInfo).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/shop/logo", h.UploadShopLogo).Methods("POST")
-	r.HandleFunc("/{sellerId}/bank", h.UpdateBank).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/bank", h.GetBankInfo).Methods("GET")
-	r.HandleFunc("/{sellerId}/business", h.UpdateBusinessInfo).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/verification", h.GetVerificationStatus).Methods("GET")
-	r.HandleFunc("/{sellerId}/stats/overview", h.GetStatsOverview).Methods("GET")
+	r.Handle("/{sellerId}", h.authorize(permSellerRead, h.GetSellerProfile)).Methods("GET")
+	r.Handle("/{sellerId}/shop", h.authorize(permSellerWrite, h.UpdateShopInfo)).Methods("PATCH")
+	r.Handle("/{sellerId}/shop/logo", h.authorize(permSellerWrite, h.UploadShopLogo)).Methods("POST")
+	r.Handle("/{sellerId}/bank", h.authorize(permFinanceWrite, h.UpdateBank)).Methods("PATCH")
+	r.Handle("/{sellerId}/bank", h.authorize(permFinanceRead, h.GetBankInfo)).Methods("GET")
+	r.Handle("/{sellerId}/business", h.authorize(permSellerWrite, h.UpdateBusinessInfo)).Methods("PATCH")
+	r.Handle("/{sellerId}/verification", h.authorize(permSellerRead, h.GetVerificationStatus)).Methods("GET")
+	r.Handle("/{sellerId}/stats/overview", h.authorize(permSellerRead, h.GetStatsOverview)).Methods("GET")
 
 	// Document endpoints
-	r.HandleFunc("/{sellerId}/verification/documents", h.UploadVerificationDocument).Methods("POST")
-	r.HandleFunc("/{sellerId}/verification/documents", h.ListLatestVerificationDocumentsByType).Methods("GET")
+	r.Handle("/{sellerId}/verification/documents", h.authorize(permSellerWrite, h.UploadVerificationDocument)).Methods("POST")
+	r.Handle("/{sellerId}/verification/documents", h.authorize(permSellerRead, h.ListLatestVerificationDocumentsByType)).Methods("GET")
 
 	// Product endpoints
-	r.HandleFunc("/{sellerId}/products", h.CreateSellerProduct).Methods("POST")
-	r.HandleFunc("/{sellerId}/products", h.ListSellerProducts).Methods("GET")
-	r.HandleFunc("/{sellerId}/products/{productId}", h.GetSellerProduct).Methods("GET")
-	r.HandleFunc("/{sellerId}/products/{productId}", h.UpdateSellerProduct).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/products/{productId}", h.SoftDeleteSellerProduct).Methods("DELETE")
-	r.HandleFunc("/{sellerId}/products/{productId}/publish", h.PublishSellerProduct).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/products/{productId}/unpublish", h.UnpublishSellerProduct).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/products/{productId}/copy", h.CopySellerProduct).Methods("POST")
+	r.Handle("/{sellerId}/products", h.authorize(permSellerWrite, h.CreateSellerProduct)).Methods("POST")
+	r.Handle("/{sellerId}/products", h.authorize(permSellerRead, h.ListSellerProducts)).Methods("GET")
+	r.Handle("/{sellerId}/products/{productId}", h.authorize(permSellerRead, h.GetSellerProduct)).Methods("GET")
+	r.Handle("/{sellerId}/products/{productId}", h.authorize(permSellerWrite, h.UpdateSellerProduct)).Methods("PATCH")
+	r.Handle("/{sellerId}/products/{productId}", h.authorize(permSellerWrite, h.SoftDeleteSellerProduct)).Methods("DELETE")
+	r.Handle("/{sellerId}/products/{productId}/publish", h.authorize(permSellerWrite, h.PublishSellerProduct)).Methods("PATCH")
+	r.Handle("/{sellerId}/products/{productId}/unpublish", h.authorize(permSellerWrite, h.UnpublishSellerProduct)).Methods("PATCH")
+	r.Handle("/{sellerId}/products/{productId}/copy", h.authorize(permSellerWrite, h.CopySellerProduct)).Methods("POST")
 
 	// Product Image Upload
-	r.HandleFunc("/{sellerId}/products/{productId}/images", h.UploadProductImage).Methods("POST")
+	r.Handle("/{sellerId}/products/{productId}/images", h.authorize(permSellerWrite, h.UploadProductImage)).Methods("POST")
 
 	// Product Variants
-	r.HandleFunc("/{sellerId}/products/{productId}/variants", h.CreateProductVariant).Methods("POST")
-	r.HandleFunc("/{sellerId}/products/{productId}/variants", h.ListProductVariants).Methods("GET")
-	r.HandleFunc("/{sellerId}/products/{productId}/variants/{variantId}", h.GetProductVariant).Methods("GET")
-	r.HandleFunc("/{sellerId}/products/{productId}/variants/{variantId}", h.UpdateProductVariant).Methods("PATCH")
-	r.HandleFunc("/{sellerId}/products/{productId}/variants/{variantId}", h.DeleteProductVariant).Methods("DELETE")
+	r.Handle("/{sellerId}/products/{productId}/variants", h.authorize(permSellerWrite, h.CreateProductVariant)).Methods("POST")
+	r.Handle("/{sellerId}/products/{productId}/variants", h.authorize(permSellerRead, h.ListProductVariants)).Methods("GET")
+	r.Handle("/{sellerId}/products/{productId}/variants/{variantId}", h.authorize(permSellerRead, h.GetProductVariant)).Methods("GET")
+	r.Handle("/{sellerId}/products/{productId}/variants/{variantId}", h.authorize(permSellerWrite, h.UpdateProductVariant)).Methods("PATCH")
+	r.Handle("/{sellerId}/products/{productId}/variants/{variantId}", h.authorize(permSellerWrite, h.DeleteProductVariant)).Methods("DELETE")
 
 	// Analytics
-	r.HandleFunc("/{sellerId}/analytics/overview", h.GetAnalyticsOverview).Methods("GET")
-	r.HandleFunc("/{sellerId}/analytics/top-products", h.GetTopSellingProducts).Methods("GET")
+	r.Handle("/{sellerId}/analytics/overview", h.authorize(permSellerRead, h.GetAnalyticsOverview)).Methods("GET")
+	r.Handle("/{sellerId}/analytics/top-products", h.authorize(permSellerRead, h.GetTopSellingProducts)).Methods("GET")
 
 	// Finance endpoints
-	r.HandleFunc("/{sellerId}/finance/balance", h.GetSellerBalance).Methods("GET")
-	r.HandleFunc("/{sellerId}/finance/payouts", h.GetSellerPayouts).Methods("GET")
-	r.HandleFunc("/{sellerId}/finance/payouts/{payoutId}", h.GetPayoutDetails).Methods("GET")
-	r.HandleFunc("/{sellerId}/finance/withdraw", h.RequestWithdrawal).Methods("POST")
-	r.HandleFunc("/{sellerId}/finance/payout-schedule", h.GetPayoutSchedule).Methods("GET")
-	r.HandleFunc("/{sellerId}/finance/payout-schedule", h.UpdatePayoutSchedule).Methods("PATCH")
+	r.Handle("/{sellerId}/finance/balance", h.authorize(permFinanceRead, h.GetSellerBalance)).Methods("GET")
+	r.Handle("/{sellerId}/finance/payouts", h.authorize(permFinanceRead, h.GetSellerPayouts)).Methods("GET")
+	r.Handle("/{sellerId}/finance/payouts/{payoutId}", h.authorize(permFinanceRead, h.GetPayoutDetails)).Methods("GET")
+	r.Handle("/{sellerId}/finance/withdraw", h.authorize(permFinanceWrite, h.config.Idempotent(http.HandlerFunc(h.RequestWithdrawal)).ServeHTTP)).Methods("POST")
+	r.Handle("/{sellerId}/finance/payout-schedule", h.authorize(permFinanceRead, h.GetPayoutSchedule)).Methods("GET")
+	r.Handle("/{sellerId}/finance/payout-schedule", h.authorize(permFinanceWrite, h.UpdatePayoutSchedule)).Methods("PATCH")
 }
 
 // @Summary Get Analytics Overview
@@ -79,20 +97,20 @@ func (h *SellerHandler) RegisterRoutes(r *mux.Router) {
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.SellerAnalyticsOverviewResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/analytics/overview [get]
 func (h *SellerHandler) GetAnalyticsOverview(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	stats, err := h.service.GetStatsOverview(sellerID)
+	stats, err := h.service.GetStatsOverview(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
-	topProducts, err := h.service.GetTopSellingProducts(sellerID, 5)
+	topProducts, err := h.service.GetTopSellingProducts(r.Context(), sellerID, 5)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -112,7 +130,7 @@ func (h *SellerHandler) GetAnalyticsOverview(w http.ResponseWriter, r *http.Requ
 // @Param sellerId path string true "Seller ID"
 // @Param limit query int false "Max number of products to return" default(10)
 // @Success 200 {object} types.TopSellingProductsResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/analytics/top-products [get]
 func (h *SellerHandler) GetTopSellingProducts(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
@@ -124,9 +142,9 @@ func (h *SellerHandler) GetTopSellingProducts(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	products, err := h.service.GetTopSellingProducts(sellerID, limit)
+	products, err := h.service.GetTopSellingProducts(r.Context(), sellerID, limit)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -142,12 +160,6 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func writeError(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, map[string]interface{}{
-		"error": msg,
-	})
-}
-
 func toProfileDTO(s models.Seller) types.SellerProfileResponseDTO {
 	return types.SellerProfileResponseDTO{
 		ID:                 s.ID,
@@ -226,19 +238,19 @@ func toSellerProductDTO(p models.SellerProduct) types.SellerProductResponseDTO {
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.SellerProfileResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId} [get]
 func (h *SellerHandler) GetSellerProfile(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	seller, err := h.service.GetSellerProfile(sellerID)
+	seller, err := h.service.GetSellerProfile(r.Context(), sellerID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "seller not found")
+			apperror.Write(w, r, apperror.NotFound("seller not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -253,27 +265,27 @@ func (h *SellerHandler) GetSellerProfile(w http.ResponseWriter, r *http.Request)
 // @Param sellerId path string true "Seller ID"
 // @Param file formData file true "The logo image file"
 // @Success 200 {object} types.SellerProfileResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/shop/logo [post]
 func (h *SellerHandler) UploadShopLogo(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	if err := r.ParseMultipartForm(5 << 20); err != nil { // 5MB limit
-		writeError(w, http.StatusBadRequest, "file too large or invalid form")
+		apperror.Write(w, r, apperror.InvalidArgument("file too large or invalid form"))
 		return
 	}
 
 	file, handler, err := r.FormFile("file")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "missing 'file' field in multipart form")
+		apperror.Write(w, r, apperror.InvalidArgument("missing 'file' field in multipart form"))
 		return
 	}
 	defer file.Close()
 
 	seller, err := h.service.UploadShopLogo(r.Context(), sellerID, handler.Filename, file)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -288,21 +300,21 @@ func (h *SellerHandler) UploadShopLogo(w http.ResponseWriter, r *http.Request) {
 // @Param sellerId path string true "Seller ID"
 // @Param shopInfo body types.UpdateShopInfoPayload true "Shop info to update"
 // @Success 200 {object} types.SellerProfileResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/shop [patch]
 func (h *SellerHandler) UpdateShopInfo(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.UpdateShopInfoPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	seller, err := h.service.UpdateShopInfo(sellerID, payload)
+	seller, err := h.service.UpdateShopInfo(r.Context(), sellerID, payload)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -317,21 +329,21 @@ func (h *SellerHandler) UpdateShopInfo(w http.ResponseWriter, r *http.Request) {
 // @Param sellerId path string true "Seller ID"
 // @Param bankInfo body types.UpdateBankAccountPayload true "Bank info to update"
 // @Success 200 {object} types.SellerProfileResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/bank [patch]
 func (h *SellerHandler) UpdateBank(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.UpdateBankAccountPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	seller, err := h.service.UpdateBank(sellerID, payload)
+	seller, err := h.service.UpdateBank(r.Context(), sellerID, payload)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -344,14 +356,14 @@ func (h *SellerHandler) UpdateBank(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.SellerBankResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/bank [get]
 func (h *SellerHandler) GetBankInfo(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	resp, err := h.service.GetBankInfo(sellerID)
+	resp, err := h.service.GetBankInfo(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -366,21 +378,21 @@ func (h *SellerHandler) GetBankInfo(w http.ResponseWriter, r *http.Request) {
 // @Param sellerId path string true "Seller ID"
 // @Param businessInfo body types.UpdateBusinessInfoPayload true "Business info to update"
 // @Success 200 {object} types.SellerProfileResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/business [patch]
 func (h *SellerHandler) UpdateBusinessInfo(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.UpdateBusinessInfoPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	seller, err := h.service.UpdateBusinessInfo(sellerID, payload)
+	seller, err := h.service.UpdateBusinessInfo(r.Context(), sellerID, payload)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -393,14 +405,14 @@ func (h *SellerHandler) UpdateBusinessInfo(w http.ResponseWriter, r *http.Reques
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.VerificationStatusResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/verification [get]
 func (h *SellerHandler) GetVerificationStatus(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	resp, err := h.service.GetVerificationStatus(sellerID)
+	resp, err := h.service.GetVerificationStatus(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -413,14 +425,14 @@ func (h *SellerHandler) GetVerificationStatus(w http.ResponseWriter, r *http.Req
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.SellerStatsOverviewResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/stats/overview [get]
 func (h *SellerHandler) GetStatsOverview(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	resp, err := h.service.GetStatsOverview(sellerID)
+	resp, err := h.service.GetStatsOverview(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -435,21 +447,21 @@ func (h *SellerHandler) GetStatsOverview(w http.ResponseWriter, r *http.Request)
 // @Param sellerId path string true "Seller ID"
 // @Param documentInfo body types.UploadSellerDocumentPayload true "Document info"
 // @Success 201 {object} types.SellerDocumentResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/verification/documents [post]
 func (h *SellerHandler) UploadVerificationDocument(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.UploadSellerDocumentPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	doc, err := h.service.UploadVerificationDocument(sellerID, payload)
+	doc, err := h.service.UploadVerificationDocument(r.Context(), sellerID, payload)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -462,14 +474,14 @@ func (h *SellerHandler) UploadVerificationDocument(w http.ResponseWriter, r *htt
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/verification/documents [get]
 func (h *SellerHandler) ListLatestVerificationDocumentsByType(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	docs, err := h.service.ListLatestVerificationDocumentsByType(sellerID)
+	docs, err := h.service.ListLatestVerificationDocumentsByType(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -490,80 +502,84 @@ func (h *SellerHandler) ListLatestVerificationDocumentsByType(w http.ResponseWri
 // @Param sellerId path string true "Seller ID"
 // @Param product body types.CreateSellerProductPayload true "Product information"
 // @Success 201 {object} types.SellerProductResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 409 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products [post]
 func (h *SellerHandler) CreateSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.CreateSellerProductPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	product, err := h.service.CreateSellerProduct(sellerID, payload)
+	product, err := h.service.CreateSellerProduct(r.Context(), sellerID, payload)
 	if err != nil {
-		if errors.Is(err, service.ErrSellerProductSlugExists) {
-			writeError(w, http.StatusConflict, err.Error())
-			return
-		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, toSellerProductDTO(product))
 }
 
+// sellerProductListSpec is what the product list can be sorted and filtered by.
+var sellerProductListSpec = utils.ListSpec{
+	Sorts: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"name":       "name",
+		"price":      "price",
+		"quantity":   "quantity",
+		"sold_count": "sold_count",
+	},
+	DefaultSort: "-created_at",
+	Filters: []utils.Filter{
+		{Param: "status", Columns: []string{"status"}, Op: utils.OpIn},
+		{Param: "category_id", Columns: []string{"category_id"}, Op: utils.OpEq, Kind: utils.KindUUID},
+		{Param: "search", Columns: []string{"name", "sku"}, Op: utils.OpSearch},
+		{Param: "price", Columns: []string{"price"}, Op: utils.OpRange, Kind: utils.KindNumber},
+		{Param: "created", Columns: []string{"created_at"}, Op: utils.OpRange, Kind: utils.KindTime},
+	},
+}
+
 // @Summary List Seller Products
-// @Description List products for a seller with optional filters and pagination.
+// @Description List products for a seller with optional filters, sorted and paginated with cursors.
 // @Tags Products
 // @Produce json
 // @Param sellerId path string true "Seller ID"
-// @Param status query string false "Filter by product status (e.g., draft, active)"
+// @Param status query string false "Filter by product status, comma separated (e.g., draft,active)"
+// @Param category_id query string false "Filter by category"
 // @Param search query string false "Search by product name or SKU"
-// @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
+// @Param price_from query number false "Minimum price"
+// @Param price_to query number false "Maximum price"
+// @Param created_from query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created at or before (RFC 3339 or YYYY-MM-DD)"
+// @Param sort query string false "Sort fields, comma separated, '-' for descending (default -created_at)"
+// @Param cursor query string false "next_cursor or prev_cursor of a previous page"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Param include_total query bool false "Count all matching products"
 // @Success 200 {object} types.ListSellerProductsResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products [get]
 func (h *SellerHandler) ListSellerProducts(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	page := 1
-	limit := 20
-	status := r.URL.Query().Get("status")
-	search := r.URL.Query().Get("search")
-
-	if v := r.URL.Query().Get("page"); v != "" {
-		if p, err := strconv.Atoi(v); err == nil && p > 0 {
-			page = p
-		}
-	}
-	if v := r.URL.Query().Get("limit"); v != "" {
-		if l, err := strconv.Atoi(v); err == nil && l > 0 {
-			limit = l
-		}
-	}
-
-	products, total, err := h.service.ListSellerProducts(sellerID, &status, &search, page, limit)
+	query, err := utils.ParseListQuery(r, sellerProductListSpec)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
-	resp := types.ListSellerProductsResponseDTO{
-		Products: make([]types.SellerProductResponseDTO, 0, len(products)),
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
-	}
-	for _, p := range products {
-		resp.Products = append(resp.Products, toSellerProductDTO(p))
+	products, err := h.service.ListSellerProducts(r.Context(), sellerID, query)
+	if err != nil {
+		apperror.Write(w, r, err)
+		return
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	writeJSON(w, http.StatusOK, utils.MapPage(products, toSellerProductDTO))
 }
 
 // @Summary Get Seller Product
@@ -573,20 +589,20 @@ func (h *SellerHandler) ListSellerProducts(w http.ResponseWriter, r *http.Reques
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 200 {object} types.SellerProductResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId} [get]
 func (h *SellerHandler) GetSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	product, err := h.service.GetSellerProduct(sellerID, productID)
+	product, err := h.service.GetSellerProduct(r.Context(), sellerID, productID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -602,10 +618,10 @@ func (h *SellerHandler) GetSellerProduct(w http.ResponseWriter, r *http.Request)
 // @Param productId path string true "Product ID"
 // @Param productInfo body types.UpdateSellerProductPayload true "Product info to update"
 // @Success 200 {object} types.SellerProductResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 409 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId} [patch]
 func (h *SellerHandler) UpdateSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
@@ -613,21 +629,17 @@ func (h *SellerHandler) UpdateSellerProduct(w http.ResponseWriter, r *http.Reque
 
 	var payload types.UpdateSellerProductPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	product, err := h.service.UpdateSellerProduct(sellerID, productID, payload)
+	product, err := h.service.UpdateSellerProduct(r.Context(), sellerID, productID, payload)
 	if err != nil {
-		if errors.Is(err, service.ErrSellerProductSlugExists) {
-			writeError(w, http.StatusConflict, err.Error())
-			return
-		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -641,15 +653,15 @@ func (h *SellerHandler) UpdateSellerProduct(w http.ResponseWriter, r *http.Reque
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 200 {object} types.SellerProductResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/publish [patch]
 func (h *SellerHandler) PublishSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	product, err := h.service.PublishSellerProduct(sellerID, productID)
+	product, err := h.service.PublishSellerProduct(r.Context(), sellerID, productID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -663,15 +675,15 @@ func (h *SellerHandler) PublishSellerProduct(w http.ResponseWriter, r *http.Requ
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 200 {object} types.SellerProductResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/unpublish [patch]
 func (h *SellerHandler) UnpublishSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	product, err := h.service.UnpublishSellerProduct(sellerID, productID)
+	product, err := h.service.UnpublishSellerProduct(r.Context(), sellerID, productID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -685,20 +697,20 @@ func (h *SellerHandler) UnpublishSellerProduct(w http.ResponseWriter, r *http.Re
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 201 {object} types.SellerProductResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/copy [post]
 func (h *SellerHandler) CopySellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	product, err := h.service.CopySellerProduct(sellerID, productID)
+	product, err := h.service.CopySellerProduct(r.Context(), sellerID, productID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -711,14 +723,14 @@ func (h *SellerHandler) CopySellerProduct(w http.ResponseWriter, r *http.Request
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 204 "No Content"
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId} [delete]
 func (h *SellerHandler) SoftDeleteSellerProduct(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	if err := h.service.SoftDeleteSellerProduct(sellerID, productID); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+	if err := h.service.SoftDeleteSellerProduct(r.Context(), sellerID, productID); err != nil {
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -735,22 +747,22 @@ func (h *SellerHandler) SoftDeleteSellerProduct(w http.ResponseWriter, r *http.R
 // @Param file formData file true "The image file"
 // @Param is_primary formData boolean false "Set as primary image"
 // @Success 200 {object} types.SellerProductResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/images [post]
 func (h *SellerHandler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
 	if err := r.ParseMultipartForm(5 << 20); err != nil { // 5MB limit
-		writeError(w, http.StatusBadRequest, "file too large or invalid form")
+		apperror.Write(w, r, apperror.InvalidArgument("file too large or invalid form"))
 		return
 	}
 
 	file, handler, err := r.FormFile("file")
 	if err != nil {
-		writeError(w, http.StatusBadRequest, "missing 'file' field in multipart form")
+		apperror.Write(w, r, apperror.InvalidArgument("missing 'file' field in multipart form"))
 		return
 	}
 	defer file.Close()
@@ -760,10 +772,10 @@ func (h *SellerHandler) UploadProductImage(w http.ResponseWriter, r *http.Reques
 	product, err := h.service.UploadProductImage(r.Context(), sellerID, productID, handler.Filename, file, isPrimary)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -808,9 +820,9 @@ func toProductVariantDTO(v models.SellerProductVariant) types.ProductVariantResp
 // @Param productId path string true "Product ID"
 // @Param variant body types.CreateProductVariantPayload true "Variant information"
 // @Success 201 {object} types.ProductVariantResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/variants [post]
 func (h *SellerHandler) CreateProductVariant(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
@@ -818,17 +830,17 @@ func (h *SellerHandler) CreateProductVariant(w http.ResponseWriter, r *http.Requ
 
 	var payload types.CreateProductVariantPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	variant, err := h.service.CreateProductVariant(sellerID, productID, payload)
+	variant, err := h.service.CreateProductVariant(r.Context(), sellerID, productID, payload)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -842,20 +854,20 @@ func (h *SellerHandler) CreateProductVariant(w http.ResponseWriter, r *http.Requ
 // @Param sellerId path string true "Seller ID"
 // @Param productId path string true "Product ID"
 // @Success 200 {object} types.ListProductVariantsResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/variants [get]
 func (h *SellerHandler) ListProductVariants(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 
-	variants, err := h.service.ListProductVariants(sellerID, productID)
+	variants, err := h.service.ListProductVariants(r.Context(), sellerID, productID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "product not found")
+			apperror.Write(w, r, apperror.NotFound("product not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -877,21 +889,21 @@ func (h *SellerHandler) ListProductVariants(w http.ResponseWriter, r *http.Reque
 // @Param productId path string true "Product ID"
 // @Param variantId path string true "Variant ID"
 // @Success 200 {object} types.ProductVariantResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/variants/{variantId} [get]
 func (h *SellerHandler) GetProductVariant(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 	variantID := mux.Vars(r)["variantId"]
 
-	variant, err := h.service.GetProductVariant(sellerID, productID, variantID)
+	variant, err := h.service.GetProductVariant(r.Context(), sellerID, productID, variantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "variant not found")
+			apperror.Write(w, r, apperror.NotFound("variant not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -908,9 +920,9 @@ func (h *SellerHandler) GetProductVariant(w http.ResponseWriter, r *http.Request
 // @Param variantId path string true "Variant ID"
 // @Param variantInfo body types.UpdateProductVariantPayload true "Variant info to update"
 // @Success 200 {object} types.ProductVariantResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/variants/{variantId} [patch]
 func (h *SellerHandler) UpdateProductVariant(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
@@ -919,17 +931,17 @@ func (h *SellerHandler) UpdateProductVariant(w http.ResponseWriter, r *http.Requ
 
 	var payload types.UpdateProductVariantPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid JSON body"))
 		return
 	}
 
-	variant, err := h.service.UpdateProductVariant(sellerID, productID, variantID, payload)
+	variant, err := h.service.UpdateProductVariant(r.Context(), sellerID, productID, variantID, payload)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "variant not found")
+			apperror.Write(w, r, apperror.NotFound("variant not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -943,20 +955,20 @@ func (h *SellerHandler) UpdateProductVariant(w http.ResponseWriter, r *http.Requ
 // @Param productId path string true "Product ID"
 // @Param variantId path string true "Variant ID"
 // @Success 204 "No Content"
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/products/{productId}/variants/{variantId} [delete]
 func (h *SellerHandler) DeleteProductVariant(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	productID := mux.Vars(r)["productId"]
 	variantID := mux.Vars(r)["variantId"]
 
-	if err := h.service.DeleteProductVariant(sellerID, productID, variantID); err != nil {
+	if err := h.service.DeleteProductVariant(r.Context(), sellerID, productID, variantID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "variant not found")
+			apperror.Write(w, r, apperror.NotFound("variant not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -973,14 +985,14 @@ func (h *SellerHandler) DeleteProductVariant(w http.ResponseWriter, r *http.Requ
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.SellerBalanceResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/balance [get]
 func (h *SellerHandler) GetSellerBalance(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	balance, err := h.service.GetSellerBalance(sellerID)
+	balance, err := h.service.GetSellerBalance(r.Context(), sellerID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -996,7 +1008,7 @@ func (h *SellerHandler) GetSellerBalance(w http.ResponseWriter, r *http.Request)
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(20)
 // @Success 200 {object} types.ListSellerPayoutsResponseDTO
-// @Failure 500 {object} map[string]interface{}
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/payouts [get]
 func (h *SellerHandler) GetSellerPayouts(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
@@ -1015,9 +1027,9 @@ func (h *SellerHandler) GetSellerPayouts(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	payouts, total, err := h.service.GetSellerPayouts(sellerID, status, page, limit)
+	payouts, total, err := h.service.GetSellerPayouts(r.Context(), sellerID, status, page, limit)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -1036,20 +1048,20 @@ func (h *SellerHandler) GetSellerPayouts(w http.ResponseWriter, r *http.Request)
 // @Param sellerId path string true "Seller ID"
 // @Param payoutId path string true "Payout ID"
 // @Success 200 {object} types.SellerPayoutResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/payouts/{payoutId} [get]
 func (h *SellerHandler) GetPayoutDetails(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 	payoutID := mux.Vars(r)["payoutId"]
 
-	payout, err := h.service.GetPayoutDetails(sellerID, payoutID)
+	payout, err := h.service.GetPayoutDetails(r.Context(), sellerID, payoutID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "payout not found")
+			apperror.Write(w, r, apperror.NotFound("payout not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -1063,27 +1075,29 @@ func (h *SellerHandler) GetPayoutDetails(w http.ResponseWriter, r *http.Request)
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Param payload body types.RequestWithdrawalPayload true "Withdrawal request"
+// @Param Idempotency-Key header string false "Replays the first response for retries with the same key"
 // @Success 200 {object} types.SellerPayoutResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 409 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/withdraw [post]
 func (h *SellerHandler) RequestWithdrawal(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.RequestWithdrawalPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid request body"))
 		return
 	}
 
 	if payload.Amount <= 0 {
-		writeError(w, http.StatusBadRequest, "amount must be greater than 0")
+		apperror.Write(w, r, apperror.InvalidArgument("amount must be greater than 0"))
 		return
 	}
 
-	payout, err := h.service.RequestWithdrawal(sellerID, payload.Amount, payload.Notes)
+	payout, err := h.service.RequestWithdrawal(r.Context(), sellerID, payload.Amount, payload.Notes)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -1096,19 +1110,19 @@ func (h *SellerHandler) RequestWithdrawal(w http.ResponseWriter, r *http.Request
 // @Produce json
 // @Param sellerId path string true "Seller ID"
 // @Success 200 {object} types.PayoutScheduleResponseDTO
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/payout-schedule [get]
 func (h *SellerHandler) GetPayoutSchedule(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
-	schedule, err := h.service.GetPayoutSchedule(sellerID)
+	schedule, err := h.service.GetPayoutSchedule(r.Context(), sellerID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "payout schedule not found")
+			apperror.Write(w, r, apperror.NotFound("payout schedule not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
@@ -1123,26 +1137,26 @@ func (h *SellerHandler) GetPayoutSchedule(w http.ResponseWriter, r *http.Request
 // @Param sellerId path string true "Seller ID"
 // @Param payload body types.UpdatePayoutSchedulePayload true "Schedule update"
 // @Success 200 {object} types.PayoutScheduleResponseDTO
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} apperror.Problem
+// @Failure 404 {object} apperror.Problem
+// @Failure 500 {object} apperror.Problem
 // @Router /{sellerId}/finance/payout-schedule [patch]
 func (h *SellerHandler) UpdatePayoutSchedule(w http.ResponseWriter, r *http.Request) {
 	sellerID := mux.Vars(r)["sellerId"]
 
 	var payload types.UpdatePayoutSchedulePayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+		apperror.Write(w, r, apperror.InvalidArgument("invalid request body"))
 		return
 	}
 
-	schedule, err := h.service.UpdatePayoutSchedule(sellerID, payload)
+	schedule, err := h.service.UpdatePayoutSchedule(r.Context(), sellerID, payload)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			writeError(w, http.StatusNotFound, "payout schedule not found")
+			apperror.Write(w, r, apperror.NotFound("payout schedule not found"))
 			return
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
+		apperror.Write(w, r, err)
 		return
 	}
 
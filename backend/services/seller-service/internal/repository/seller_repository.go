@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,25 +10,29 @@ import (
 
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/models"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/postgres"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 type SellerRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	cursorKey []byte
 }
 
-func NewSellerRepository(db *gorm.DB) *SellerRepository {
-	return &SellerRepository{db: db}
+// NewSellerRepository signs the cursors of product pages with cursorKey, see utils.Paginate.
+func NewSellerRepository(db *gorm.DB, cursorKey []byte) *SellerRepository {
+	return &SellerRepository{db: db, cursorKey: cursorKey}
 }
 
-func (r *SellerRepository) GetByID(id string) (models.Seller, error) {
+func (r *SellerRepository) GetByID(ctx context.Context, id string) (models.Seller, error) {
 	var seller models.Seller
-	err := r.db.Model(&models.Seller{}).Where("id = ?", id).First(&seller).Error
+	err := r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", id).First(&seller).Error
 	return seller, err
 }
 
-func (r *SellerRepository) UpdateShopInfo(id string, payload types.UpdateShopInfoPayload) (models.Seller, error) {
+func (r *SellerRepository) UpdateShopInfo(ctx context.Context, id string, payload types.UpdateShopInfoPayload) (models.Seller, error) {
 	updates := map[string]interface{}{}
 	if payload.ShopName != nil {
 		updates["shop_name"] = *payload.ShopName
@@ -48,18 +53,18 @@ func (r *SellerRepository) UpdateShopInfo(id string, payload types.UpdateShopInf
 		updates["shop_announcement"] = *payload.ShopAnnouncement
 	}
 
-	if err := r.db.Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		return models.Seller{}, err
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *SellerRepository) UpdateShopLogoURL(sellerID, logoURL string) error {
-	return r.db.Model(&models.Seller{}).Where("id = ?", sellerID).Update("shop_logo_url", logoURL).Error
+func (r *SellerRepository) UpdateShopLogoURL(ctx context.Context, sellerID, logoURL string) error {
+	return r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", sellerID).Update("shop_logo_url", logoURL).Error
 }
 
-func (r *SellerRepository) UpdateBank(id string, payload types.UpdateBankAccountPayload) (models.Seller, error) {
+func (r *SellerRepository) UpdateBank(ctx context.Context, id string, payload types.UpdateBankAccountPayload) (models.Seller, error) {
 	updates := map[string]interface{}{}
 	if payload.BankName != nil {
 		updates["bank_name"] = *payload.BankName
@@ -74,14 +79,14 @@ func (r *SellerRepository) UpdateBank(id string, payload types.UpdateBankAccount
 		updates["bank_branch"] = *payload.BankBranch
 	}
 
-	if err := r.db.Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		return models.Seller{}, err
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *SellerRepository) UpdateBusinessInfo(id string, payload types.UpdateBusinessInfoPayload) (models.Seller, error) {
+func (r *SellerRepository) UpdateBusinessInfo(ctx context.Context, id string, payload types.UpdateBusinessInfoPayload) (models.Seller, error) {
 	updates := map[string]interface{}{}
 
 	if payload.BusinessName != nil {
@@ -126,15 +131,15 @@ func (r *SellerRepository) UpdateBusinessInfo(id string, payload types.UpdateBus
 		updates["postal_code"] = *payload.PostalCode
 	}
 
-	if err := r.db.Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		return models.Seller{}, err
 	}
 
-	return r.GetByID(id)
+	return r.GetByID(ctx, id)
 }
 
-func (r *SellerRepository) GetStatsOverview(id string) (types.SellerStatsOverviewResponseDTO, error) {
-	seller, err := r.GetByID(id)
+func (r *SellerRepository) GetStatsOverview(ctx context.Context, id string) (types.SellerStatsOverviewResponseDTO, error) {
+	seller, err := r.GetByID(ctx, id)
 	if err != nil {
 		return types.SellerStatsOverviewResponseDTO{}, err
 	}
@@ -153,23 +158,23 @@ func (r *SellerRepository) GetStatsOverview(id string) (types.SellerStatsOvervie
 	}, nil
 }
 
-func (r *SellerRepository) CreateSellerDocument(doc *models.SellerDocument) error {
-	return r.db.Create(doc).Error
+func (r *SellerRepository) CreateSellerDocument(ctx context.Context, doc *models.SellerDocument) error {
+	return r.db.WithContext(ctx).Create(doc).Error
 }
 
-func (r *SellerRepository) SetSellerVerificationStatusInReview(id string) error {
-	return r.db.Model(&models.Seller{}).Where("id = ?", id).Update("verification_status", "in_review").Error
+func (r *SellerRepository) SetSellerVerificationStatusInReview(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&models.Seller{}).Where("id = ?", id).Update("verification_status", "in_review").Error
 }
 
-func (r *SellerRepository) ListLatestSellerDocumentsByType(sellerID string) ([]models.SellerDocument, error) {
+func (r *SellerRepository) ListLatestSellerDocumentsByType(ctx context.Context, sellerID string) ([]models.SellerDocument, error) {
 	var docs []models.SellerDocument
 
-	sub := r.db.Model(&models.SellerDocument{}).
+	sub := r.db.WithContext(ctx).Model(&models.SellerDocument{}).
 		Select("document_type, MAX(created_at) AS max_created_at").
 		Where("seller_id = ?", sellerID).
 		Group("document_type")
 
-	err := r.db.Model(&models.SellerDocument{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerDocument{}).
 		Joins("JOIN (?) latest ON latest.document_type = seller_document.document_type AND latest.max_created_at = seller_document.created_at", sub).
 		Where("seller_document.seller_id = ?", sellerID).
 		Order("seller_document.created_at DESC").
@@ -182,9 +187,12 @@ func (r *SellerRepository) ListLatestSellerDocumentsByType(sellerID string) ([]m
 // Seller Products (MVP)
 // --------------------
 
-func (r *SellerRepository) IsSellerProductSlugExists(slug string) (bool, error) {
+//seller_product and seller_product_variant are dropped by migration 20260130_remove_seller_product_tables,
+//the queries below only work until the product routes move to product-service, see models.SellerProduct
+
+func (r *SellerRepository) IsSellerProductSlugExists(ctx context.Context, slug string) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.SellerProduct{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProduct{}).
 		Where("slug = ?", slug).
 		Where("deleted_at IS NULL").
 		Count(&count).Error
@@ -194,8 +202,8 @@ func (r *SellerRepository) IsSellerProductSlugExists(slug string) (bool, error)
 	return count > 0, nil
 }
 
-func (r *SellerRepository) CreateSellerProduct(product *models.SellerProduct) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *SellerRepository) CreateSellerProduct(ctx context.Context, product *models.SellerProduct) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(product).Error; err != nil {
 			return err
 		}
@@ -206,9 +214,9 @@ func (r *SellerRepository) CreateSellerProduct(product *models.SellerProduct) er
 	})
 }
 
-func (r *SellerRepository) GetSellerProductByID(sellerID, productID string) (models.SellerProduct, error) {
+func (r *SellerRepository) GetSellerProductByID(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
 	var product models.SellerProduct
-	err := r.db.Model(&models.SellerProduct{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProduct{}).
 		Where("id = ?", productID).
 		Where("seller_id = ?", sellerID).
 		Where("deleted_at IS NULL").
@@ -216,40 +224,18 @@ func (r *SellerRepository) GetSellerProductByID(sellerID, productID string) (mod
 	return product, err
 }
 
-func (r *SellerRepository) ListSellerProducts(sellerID string, status *string, search *string, page int, limit int) ([]models.SellerProduct, int64, error) {
-	var products []models.SellerProduct
-	var total int64
-
-	q := r.db.Model(&models.SellerProduct{}).
+// ListSellerProducts returns a page of the seller's products that are not deleted, see utils.Paginate.
+// It reads from a replica when there is one, see postgres.ReadReplica.
+func (r *SellerRepository) ListSellerProducts(ctx context.Context, sellerID string, query *utils.ListQuery) (utils.Paginated[models.SellerProduct], error) {
+	q := postgres.ReadReplica(r.db.WithContext(ctx)).Model(&models.SellerProduct{}).
 		Where("seller_id = ?", sellerID).
 		Where("deleted_at IS NULL")
 
-	if status != nil && *status != "" {
-		q = q.Where("status = ?", *status)
-	}
-	if search != nil && *search != "" {
-		like := "%" + *search + "%"
-		q = q.Where("name ILIKE ? OR sku ILIKE ?", like, like)
-	}
-
-	if err := q.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 20
-	}
-	offset := (page - 1) * limit
-
-	err := q.Order("created_at DESC").Offset(offset).Limit(limit).Find(&products).Error
-	return products, total, err
+	return utils.Paginate[models.SellerProduct](ctx, q, query, r.cursorKey)
 }
 
-func (r *SellerRepository) UpdateSellerProduct(sellerID, productID string, updates map[string]interface{}) (models.SellerProduct, error) {
-	if err := r.db.Model(&models.SellerProduct{}).
+func (r *SellerRepository) UpdateSellerProduct(ctx context.Context, sellerID, productID string, updates map[string]interface{}) (models.SellerProduct, error) {
+	if err := r.db.WithContext(ctx).Model(&models.SellerProduct{}).
 		Where("id = ?", productID).
 		Where("seller_id = ?", sellerID).
 		Where("deleted_at IS NULL").
@@ -257,27 +243,27 @@ func (r *SellerRepository) UpdateSellerProduct(sellerID, productID string, updat
 		return models.SellerProduct{}, err
 	}
 
-	return r.GetSellerProductByID(sellerID, productID)
+	return r.GetSellerProductByID(ctx, sellerID, productID)
 }
 
-func (r *SellerRepository) PublishSellerProduct(sellerID, productID string) (models.SellerProduct, error) {
+func (r *SellerRepository) PublishSellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
 	updates := map[string]interface{}{
 		"status":       "active",
 		"published_at": time.Now(),
 	}
-	return r.UpdateSellerProduct(sellerID, productID, updates)
+	return r.UpdateSellerProduct(ctx, sellerID, productID, updates)
 }
 
-func (r *SellerRepository) UnpublishSellerProduct(sellerID, productID string) (models.SellerProduct, error) {
+func (r *SellerRepository) UnpublishSellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
 	updates := map[string]interface{}{
 		"status":       "inactive",
 		"published_at": nil,
 	}
-	return r.UpdateSellerProduct(sellerID, productID, updates)
+	return r.UpdateSellerProduct(ctx, sellerID, productID, updates)
 }
 
-func (r *SellerRepository) SoftDeleteSellerProduct(sellerID, productID string) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *SellerRepository) SoftDeleteSellerProduct(ctx context.Context, sellerID, productID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		res := tx.Model(&models.SellerProduct{}).
 			Where("id = ?", productID).
 			Where("seller_id = ?", sellerID).
@@ -296,9 +282,9 @@ func (r *SellerRepository) SoftDeleteSellerProduct(sellerID, productID string) e
 	})
 }
 
-func (r *SellerRepository) AddProductImage(sellerID, productID, imageURL string, isPrimary bool) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		product, err := r.GetSellerProductByID(sellerID, productID)
+func (r *SellerRepository) AddProductImage(ctx context.Context, sellerID, productID, imageURL string, isPrimary bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		product, err := r.GetSellerProductByID(ctx, sellerID, productID)
 		if err != nil {
 			return err
 		}
@@ -322,9 +308,9 @@ func (r *SellerRepository) AddProductImage(sellerID, productID, imageURL string,
 	})
 }
 
-func (r *SellerRepository) GetTopSellingProducts(sellerID string, limit int) ([]models.SellerProduct, error) {
+func (r *SellerRepository) GetTopSellingProducts(ctx context.Context, sellerID string, limit int) ([]models.SellerProduct, error) {
 	var products []models.SellerProduct
-	err := r.db.Model(&models.SellerProduct{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProduct{}).
 		Where("seller_id = ?", sellerID).
 		Where("status = ?", "active").
 		Where("deleted_at IS NULL").
@@ -338,13 +324,13 @@ func (r *SellerRepository) GetTopSellingProducts(sellerID string, limit int) ([]
 // Seller Product Variants
 // --------------------
 
-func (r *SellerRepository) CreateProductVariant(variant *models.SellerProductVariant) error {
-	return r.db.Create(variant).Error
+func (r *SellerRepository) CreateProductVariant(ctx context.Context, variant *models.SellerProductVariant) error {
+	return r.db.WithContext(ctx).Create(variant).Error
 }
 
-func (r *SellerRepository) GetProductVariantByID(sellerID, productID, variantID string) (models.SellerProductVariant, error) {
+func (r *SellerRepository) GetProductVariantByID(ctx context.Context, sellerID, productID, variantID string) (models.SellerProductVariant, error) {
 	var variant models.SellerProductVariant
-	err := r.db.Model(&models.SellerProductVariant{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Joins("JOIN seller_product ON seller_product_variant.product_id = seller_product.id").
 		Where("seller_product_variant.id = ?", variantID).
 		Where("seller_product_variant.product_id = ?", productID).
@@ -354,9 +340,9 @@ func (r *SellerRepository) GetProductVariantByID(sellerID, productID, variantID
 	return variant, err
 }
 
-func (r *SellerRepository) ListProductVariants(sellerID, productID string) ([]models.SellerProductVariant, error) {
+func (r *SellerRepository) ListProductVariants(ctx context.Context, sellerID, productID string) ([]models.SellerProductVariant, error) {
 	var variants []models.SellerProductVariant
-	err := r.db.Model(&models.SellerProductVariant{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Joins("JOIN seller_product ON seller_product_variant.product_id = seller_product.id").
 		Where("seller_product_variant.product_id = ?", productID).
 		Where("seller_product.seller_id = ?", sellerID).
@@ -366,11 +352,11 @@ func (r *SellerRepository) ListProductVariants(sellerID, productID string) ([]mo
 	return variants, err
 }
 
-func (r *SellerRepository) UpdateProductVariant(sellerID, productID, variantID string, updates map[string]interface{}) (models.SellerProductVariant, error) {
+func (r *SellerRepository) UpdateProductVariant(ctx context.Context, sellerID, productID, variantID string, updates map[string]interface{}) (models.SellerProductVariant, error) {
 	var variant models.SellerProductVariant
 	
 	// First verify the variant belongs to the seller's product
-	if err := r.db.Model(&models.SellerProductVariant{}).
+	if err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Joins("JOIN seller_product ON seller_product_variant.product_id = seller_product.id").
 		Where("seller_product_variant.id = ?", variantID).
 		Where("seller_product_variant.product_id = ?", productID).
@@ -381,24 +367,24 @@ func (r *SellerRepository) UpdateProductVariant(sellerID, productID, variantID s
 	}
 
 	// Update the variant
-	if err := r.db.Model(&models.SellerProductVariant{}).
+	if err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Where("id = ?", variantID).
 		Updates(updates).Error; err != nil {
 		return models.SellerProductVariant{}, err
 	}
 
 	// Fetch updated variant
-	if err := r.db.Where("id = ?", variantID).First(&variant).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", variantID).First(&variant).Error; err != nil {
 		return models.SellerProductVariant{}, err
 	}
 
 	return variant, nil
 }
 
-func (r *SellerRepository) DeleteProductVariant(sellerID, productID, variantID string) error {
+func (r *SellerRepository) DeleteProductVariant(ctx context.Context, sellerID, productID, variantID string) error {
 	// Verify the variant belongs to the seller's product before deleting
 	var count int64
-	err := r.db.Model(&models.SellerProductVariant{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Joins("JOIN seller_product ON seller_product_variant.product_id = seller_product.id").
 		Where("seller_product_variant.id = ?", variantID).
 		Where("seller_product_variant.product_id = ?", productID).
@@ -415,12 +401,12 @@ func (r *SellerRepository) DeleteProductVariant(sellerID, productID, variantID s
 	}
 
 	// Delete the variant
-	return r.db.Delete(&models.SellerProductVariant{}, "id = ?", variantID).Error
+	return r.db.WithContext(ctx).Delete(&models.SellerProductVariant{}, "id = ?", variantID).Error
 }
 
-func (r *SellerRepository) CountProductVariants(productID string) (int64, error) {
+func (r *SellerRepository) CountProductVariants(ctx context.Context, productID string) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.SellerProductVariant{}).
+	err := r.db.WithContext(ctx).Model(&models.SellerProductVariant{}).
 		Where("product_id = ?", productID).
 		Count(&count).Error
 	return count, err
@@ -430,9 +416,9 @@ func (r *SellerRepository) CountProductVariants(productID string) (int64, error)
 // Seller Finance & Payouts
 // --------------------
 
-func (r *SellerRepository) GetSellerBalance(sellerID string) (types.SellerBalanceResponseDTO, error) {
+func (r *SellerRepository) GetSellerBalance(ctx context.Context, sellerID string) (types.SellerBalanceResponseDTO, error) {
 	var seller models.Seller
-	if err := r.db.Where("id = ?", sellerID).First(&seller).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ?", sellerID).First(&seller).Error; err != nil {
 		return types.SellerBalanceResponseDTO{}, err
 	}
 
@@ -441,7 +427,7 @@ func (r *SellerRepository) GetSellerBalance(sellerID string) (types.SellerBalanc
 
 	// Total paid out: sum of all payouts with status 'paid'
 	var totalPaidOut float64
-	r.db.Model(&models.SellerPayout{}).
+	r.db.WithContext(ctx).Model(&models.SellerPayout{}).
 		Where("seller_id = ?", sellerID).
 		Where("status = ?", "paid").
 		Select("COALESCE(SUM(net_amount), 0)").
@@ -449,7 +435,7 @@ func (r *SellerRepository) GetSellerBalance(sellerID string) (types.SellerBalanc
 
 	// Pending payouts: sum of payouts with status IN ('pending', 'approved', 'processing')
 	var pendingPayouts float64
-	r.db.Model(&models.SellerPayout{}).
+	r.db.WithContext(ctx).Model(&models.SellerPayout{}).
 		Where("seller_id = ?", sellerID).
 		Where("status IN ?", []string{"pending", "approved", "processing"}).
 		Select("COALESCE(SUM(net_amount), 0)").
@@ -469,8 +455,8 @@ func (r *SellerRepository) GetSellerBalance(sellerID string) (types.SellerBalanc
 	}, nil
 }
 
-func (r *SellerRepository) GetSellerPayouts(sellerID, status string, page, limit int) ([]types.SellerPayoutResponseDTO, int64, error) {
-	query := r.db.Model(&models.SellerPayout{}).
+func (r *SellerRepository) GetSellerPayouts(ctx context.Context, sellerID, status string, page, limit int) ([]types.SellerPayoutResponseDTO, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.SellerPayout{}).
 		Where("seller_id = ?", sellerID)
 
 	if status != "" {
@@ -499,9 +485,9 @@ func (r *SellerRepository) GetSellerPayouts(sellerID, status string, page, limit
 	return result, total, nil
 }
 
-func (r *SellerRepository) GetPayoutDetails(sellerID, payoutID string) (types.SellerPayoutResponseDTO, error) {
+func (r *SellerRepository) GetPayoutDetails(ctx context.Context, sellerID, payoutID string) (types.SellerPayoutResponseDTO, error) {
 	var payout models.SellerPayout
-	if err := r.db.Where("id = ? AND seller_id = ?", payoutID, sellerID).First(&payout).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id = ? AND seller_id = ?", payoutID, sellerID).First(&payout).Error; err != nil {
 		return types.SellerPayoutResponseDTO{}, err
 	}
 
@@ -509,7 +495,7 @@ func (r *SellerRepository) GetPayoutDetails(sellerID, payoutID string) (types.Se
 
 	// Load items
 	var items []models.SellerPayoutItem
-	if err := r.db.Where("payout_id = ?", payoutID).Find(&items).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("payout_id = ?", payoutID).Find(&items).Error; err != nil {
 		return types.SellerPayoutResponseDTO{}, err
 	}
 
@@ -530,7 +516,7 @@ func (r *SellerRepository) GetPayoutDetails(sellerID, payoutID string) (types.Se
 	return dto, nil
 }
 
-func (r *SellerRepository) CreateWithdrawalPayout(sellerID string, amount float64, notes *string, seller models.Seller) (types.SellerPayoutResponseDTO, error) {
+func (r *SellerRepository) CreateWithdrawalPayout(ctx context.Context, sellerID string, amount float64, notes *string, seller models.Seller) (types.SellerPayoutResponseDTO, error) {
 	// Generate payout number
 	payoutNumber := fmt.Sprintf("PAY-%s-%s", time.Now().Format("20060102"), generateRandomString(5))
 
@@ -553,16 +539,16 @@ func (r *SellerRepository) CreateWithdrawalPayout(sellerID string, amount float6
 		Notes:             notes,
 	}
 
-	if err := r.db.Create(&payout).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(&payout).Error; err != nil {
 		return types.SellerPayoutResponseDTO{}, err
 	}
 
 	return toSellerPayoutDTO(payout), nil
 }
 
-func (r *SellerRepository) GetPayoutSchedule(sellerID string) (types.PayoutScheduleResponseDTO, error) {
+func (r *SellerRepository) GetPayoutSchedule(ctx context.Context, sellerID string) (types.PayoutScheduleResponseDTO, error) {
 	var schedule models.SellerPayoutSchedule
-	err := r.db.Where("seller_id = ?", sellerID).First(&schedule).Error
+	err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).First(&schedule).Error
 	
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// Create default schedule with automatic payouts disabled
@@ -572,7 +558,7 @@ func (r *SellerRepository) GetPayoutSchedule(sellerID string) (types.PayoutSched
 			MinPayoutAmount: 50000,
 			IsActive:        false, // Automatic payouts disabled by default
 		}
-		if err := r.db.Create(&schedule).Error; err != nil {
+		if err := r.db.WithContext(ctx).Create(&schedule).Error; err != nil {
 			return types.PayoutScheduleResponseDTO{}, err
 		}
 	} else if err != nil {
@@ -592,9 +578,9 @@ func (r *SellerRepository) GetPayoutSchedule(sellerID string) (types.PayoutSched
 	}, nil
 }
 
-func (r *SellerRepository) UpdatePayoutSchedule(sellerID string, payload types.UpdatePayoutSchedulePayload) (types.PayoutScheduleResponseDTO, error) {
+func (r *SellerRepository) UpdatePayoutSchedule(ctx context.Context, sellerID string, payload types.UpdatePayoutSchedulePayload) (types.PayoutScheduleResponseDTO, error) {
 	var schedule models.SellerPayoutSchedule
-	if err := r.db.Where("seller_id = ?", sellerID).First(&schedule).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).First(&schedule).Error; err != nil {
 		return types.PayoutScheduleResponseDTO{}, err
 	}
 
@@ -615,12 +601,12 @@ func (r *SellerRepository) UpdatePayoutSchedule(sellerID string, payload types.U
 		updates["is_active"] = *payload.IsActive
 	}
 
-	if err := r.db.Model(&schedule).Updates(updates).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&schedule).Updates(updates).Error; err != nil {
 		return types.PayoutScheduleResponseDTO{}, err
 	}
 
 	// Reload to get updated data
-	if err := r.db.Where("seller_id = ?", sellerID).First(&schedule).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).First(&schedule).Error; err != nil {
 		return types.PayoutScheduleResponseDTO{}, err
 	}
 
@@ -13,12 +13,16 @@ import (
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/internal/storage"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/models"
 	"github.com/Flow-Indo/LAKOO/backend/services/seller-service/types"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
 	"github.com/lib/pq"
+	"go.uber.org/zap"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-var ErrSellerProductSlugExists = errors.New("product slug already exists")
+var ErrSellerProductSlugExists = apperror.Conflict("product slug already exists")
 
 type SellerService struct {
 	repo       *repository.SellerRepository
@@ -31,24 +35,24 @@ func NewSellerService(repo *repository.SellerRepository, s3Uploader *storage.S3U
 
 // ... (existing seller profile methods) ...
 
-func (s *SellerService) GetSellerProfile(id string) (models.Seller, error) {
-	return s.repo.GetByID(id)
+func (s *SellerService) GetSellerProfile(ctx context.Context, id string) (models.Seller, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *SellerService) UpdateShopInfo(id string, payload types.UpdateShopInfoPayload) (models.Seller, error) {
-	return s.repo.UpdateShopInfo(id, payload)
+func (s *SellerService) UpdateShopInfo(ctx context.Context, id string, payload types.UpdateShopInfoPayload) (models.Seller, error) {
+	return s.repo.UpdateShopInfo(ctx, id, payload)
 }
 
-func (s *SellerService) UpdateBank(id string, payload types.UpdateBankAccountPayload) (models.Seller, error) {
-	return s.repo.UpdateBank(id, payload)
+func (s *SellerService) UpdateBank(ctx context.Context, id string, payload types.UpdateBankAccountPayload) (models.Seller, error) {
+	return s.repo.UpdateBank(ctx, id, payload)
 }
 
-func (s *SellerService) UpdateBusinessInfo(id string, payload types.UpdateBusinessInfoPayload) (models.Seller, error) {
-	return s.repo.UpdateBusinessInfo(id, payload)
+func (s *SellerService) UpdateBusinessInfo(ctx context.Context, id string, payload types.UpdateBusinessInfoPayload) (models.Seller, error) {
+	return s.repo.UpdateBusinessInfo(ctx, id, payload)
 }
 
-func (s *SellerService) GetVerificationStatus(id string) (types.VerificationStatusResponseDTO, error) {
-	seller, err := s.repo.GetByID(id)
+func (s *SellerService) GetVerificationStatus(ctx context.Context, id string) (types.VerificationStatusResponseDTO, error) {
+	seller, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return types.VerificationStatusResponseDTO{}, err
 	}
@@ -61,8 +65,8 @@ func (s *SellerService) GetVerificationStatus(id string) (types.VerificationStat
 	}, nil
 }
 
-func (s *SellerService) GetBankInfo(id string) (types.SellerBankResponseDTO, error) {
-	seller, err := s.repo.GetByID(id)
+func (s *SellerService) GetBankInfo(ctx context.Context, id string) (types.SellerBankResponseDTO, error) {
+	seller, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return types.SellerBankResponseDTO{}, err
 	}
@@ -78,19 +82,19 @@ func (s *SellerService) GetBankInfo(id string) (types.SellerBankResponseDTO, err
 	}, nil
 }
 
-func (s *SellerService) GetStatsOverview(id string) (types.SellerStatsOverviewResponseDTO, error) {
-	return s.repo.GetStatsOverview(id)
+func (s *SellerService) GetStatsOverview(ctx context.Context, id string) (types.SellerStatsOverviewResponseDTO, error) {
+	return s.repo.GetStatsOverview(ctx, id)
 }
 
-func (s *SellerService) UploadVerificationDocument(sellerID string, payload types.UploadSellerDocumentPayload) (types.SellerDocumentResponseDTO, error) {
+func (s *SellerService) UploadVerificationDocument(ctx context.Context, sellerID string, payload types.UploadSellerDocumentPayload) (types.SellerDocumentResponseDTO, error) {
 	if payload.DocumentType == "" {
-		return types.SellerDocumentResponseDTO{}, errors.New("document_type is required")
+		return types.SellerDocumentResponseDTO{}, apperror.InvalidArgument("document_type is required")
 	}
 	if payload.FileURL == "" {
-		return types.SellerDocumentResponseDTO{}, errors.New("file_url is required")
+		return types.SellerDocumentResponseDTO{}, apperror.InvalidArgument("file_url is required")
 	}
 	if payload.FileName == "" {
-		return types.SellerDocumentResponseDTO{}, errors.New("file_name is required")
+		return types.SellerDocumentResponseDTO{}, apperror.InvalidArgument("file_name is required")
 	}
 
 	doc := models.SellerDocument{
@@ -104,19 +108,19 @@ func (s *SellerService) UploadVerificationDocument(sellerID string, payload type
 		Status:          "pending",
 	}
 
-	if err := s.repo.CreateSellerDocument(&doc); err != nil {
+	if err := s.repo.CreateSellerDocument(ctx, &doc); err != nil {
 		return types.SellerDocumentResponseDTO{}, err
 	}
 
-	if err := s.repo.SetSellerVerificationStatusInReview(sellerID); err != nil {
+	if err := s.repo.SetSellerVerificationStatusInReview(ctx, sellerID); err != nil {
 		return types.SellerDocumentResponseDTO{}, err
 	}
 
 	return toSellerDocumentDTO(doc), nil
 }
 
-func (s *SellerService) ListLatestVerificationDocumentsByType(sellerID string) ([]types.SellerDocumentResponseDTO, error) {
-	docs, err := s.repo.ListLatestSellerDocumentsByType(sellerID)
+func (s *SellerService) ListLatestVerificationDocumentsByType(ctx context.Context, sellerID string) ([]types.SellerDocumentResponseDTO, error) {
+	docs, err := s.repo.ListLatestSellerDocumentsByType(ctx, sellerID)
 	if err != nil {
 		return nil, err
 	}
@@ -135,15 +139,15 @@ func (s *SellerService) UploadShopLogo(ctx context.Context, sellerID, fileName s
 		return models.Seller{}, err
 	}
 
-	if err := s.repo.UpdateShopLogoURL(sellerID, s3URI); err != nil {
+	if err := s.repo.UpdateShopLogoURL(ctx, sellerID, s3URI); err != nil {
 		return models.Seller{}, err
 	}
 
-	return s.repo.GetByID(sellerID)
+	return s.repo.GetByID(ctx, sellerID)
 }
 
 func (s *SellerService) UploadProductImage(ctx context.Context, sellerID, productID, fileName string, file io.Reader, isPrimary bool) (models.SellerProduct, error) {
-	product, err := s.repo.GetSellerProductByID(sellerID, productID)
+	product, err := s.repo.GetSellerProductByID(ctx, sellerID, productID)
 	if err != nil {
 		return models.SellerProduct{}, err
 	}
@@ -153,24 +157,24 @@ func (s *SellerService) UploadProductImage(ctx context.Context, sellerID, produc
 		return models.SellerProduct{}, err
 	}
 
-	if err := s.repo.AddProductImage(sellerID, product.ID, s3URI, isPrimary); err != nil {
+	if err := s.repo.AddProductImage(ctx, sellerID, product.ID, s3URI, isPrimary); err != nil {
 		return models.SellerProduct{}, err
 	}
 
-	return s.repo.GetSellerProductByID(sellerID, productID)
+	return s.repo.GetSellerProductByID(ctx, sellerID, productID)
 }
 
 // --------------------
 // Seller Products (MVP)
 // --------------------
 
-func (s *SellerService) CreateSellerProduct(sellerID string, payload types.CreateSellerProductPayload) (models.SellerProduct, error) {
+func (s *SellerService) CreateSellerProduct(ctx context.Context, sellerID string, payload types.CreateSellerProductPayload) (models.SellerProduct, error) {
 	name := strings.TrimSpace(payload.Name)
 	if name == "" {
-		return models.SellerProduct{}, errors.New("name is required")
+		return models.SellerProduct{}, apperror.InvalidArgument("name is required")
 	}
 	if payload.Price < 0 {
-		return models.SellerProduct{}, errors.New("price must be >= 0")
+		return models.SellerProduct{}, apperror.InvalidArgument("price must be >= 0")
 	}
 
 	slug := ""
@@ -178,9 +182,9 @@ func (s *SellerService) CreateSellerProduct(sellerID string, payload types.Creat
 	if clientProvidedSlug {
 		slug = slugify(*payload.Slug)
 		if slug == "" {
-			return models.SellerProduct{}, errors.New("slug is invalid")
+			return models.SellerProduct{}, apperror.InvalidArgument("slug is invalid")
 		}
-		exists, err := s.repo.IsSellerProductSlugExists(slug)
+		exists, err := s.repo.IsSellerProductSlugExists(ctx, slug)
 		if err != nil {
 			return models.SellerProduct{}, err
 		}
@@ -192,7 +196,7 @@ func (s *SellerService) CreateSellerProduct(sellerID string, payload types.Creat
 		if base == "" {
 			return models.SellerProduct{}, errors.New("failed to generate slug")
 		}
-		unique, err := s.generateUniqueProductSlug(base)
+		unique, err := s.generateUniqueProductSlug(ctx, base)
 		if err != nil {
 			return models.SellerProduct{}, err
 		}
@@ -238,28 +242,28 @@ func (s *SellerService) CreateSellerProduct(sellerID string, payload types.Creat
 		Tags:               pqStringArray(payload.Tags),
 	}
 
-	if err := s.repo.CreateSellerProduct(&product); err != nil {
+	if err := s.repo.CreateSellerProduct(ctx, &product); err != nil {
 		return models.SellerProduct{}, err
 	}
 
 	return product, nil
 }
 
-func (s *SellerService) ListSellerProducts(sellerID string, status *string, search *string, page int, limit int) ([]models.SellerProduct, int64, error) {
-	return s.repo.ListSellerProducts(sellerID, status, search, page, limit)
+func (s *SellerService) ListSellerProducts(ctx context.Context, sellerID string, query *utils.ListQuery) (utils.Paginated[models.SellerProduct], error) {
+	return s.repo.ListSellerProducts(ctx, sellerID, query)
 }
 
-func (s *SellerService) GetSellerProduct(sellerID, productID string) (models.SellerProduct, error) {
-	return s.repo.GetSellerProductByID(sellerID, productID)
+func (s *SellerService) GetSellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
+	return s.repo.GetSellerProductByID(ctx, sellerID, productID)
 }
 
-func (s *SellerService) UpdateSellerProduct(sellerID, productID string, payload types.UpdateSellerProductPayload) (models.SellerProduct, error) {
+func (s *SellerService) UpdateSellerProduct(ctx context.Context, sellerID, productID string, payload types.UpdateSellerProductPayload) (models.SellerProduct, error) {
 	updates := map[string]interface{}{}
 
 	if payload.Name != nil {
 		name := strings.TrimSpace(*payload.Name)
 		if name == "" {
-			return models.SellerProduct{}, errors.New("name cannot be empty")
+			return models.SellerProduct{}, apperror.InvalidArgument("name cannot be empty")
 		}
 		updates["name"] = name
 	}
@@ -280,7 +284,7 @@ func (s *SellerService) UpdateSellerProduct(sellerID, productID string, payload
 	}
 	if payload.Price != nil {
 		if *payload.Price < 0 {
-			return models.SellerProduct{}, errors.New("price must be >= 0")
+			return models.SellerProduct{}, apperror.InvalidArgument("price must be >= 0")
 		}
 		updates["price"] = *payload.Price
 	}
@@ -295,7 +299,7 @@ func (s *SellerService) UpdateSellerProduct(sellerID, productID string, payload
 	}
 	if payload.Quantity != nil {
 		if *payload.Quantity < 0 {
-			return models.SellerProduct{}, errors.New("quantity must be >= 0")
+			return models.SellerProduct{}, apperror.InvalidArgument("quantity must be >= 0")
 		}
 		updates["quantity"] = *payload.Quantity
 	}
@@ -330,9 +334,9 @@ func (s *SellerService) UpdateSellerProduct(sellerID, productID string, payload
 	if payload.Slug != nil {
 		slug := slugify(*payload.Slug)
 		if slug == "" {
-			return models.SellerProduct{}, errors.New("slug is invalid")
+			return models.SellerProduct{}, apperror.InvalidArgument("slug is invalid")
 		}
-		exists, err := s.repo.IsSellerProductSlugExists(slug)
+		exists, err := s.repo.IsSellerProductSlugExists(ctx, slug)
 		if err != nil {
 			return models.SellerProduct{}, err
 		}
@@ -342,29 +346,29 @@ func (s *SellerService) UpdateSellerProduct(sellerID, productID string, payload
 		updates["slug"] = slug
 	}
 
-	return s.repo.UpdateSellerProduct(sellerID, productID, updates)
+	return s.repo.UpdateSellerProduct(ctx, sellerID, productID, updates)
 }
 
-func (s *SellerService) PublishSellerProduct(sellerID, productID string) (models.SellerProduct, error) {
-	return s.repo.PublishSellerProduct(sellerID, productID)
+func (s *SellerService) PublishSellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
+	return s.repo.PublishSellerProduct(ctx, sellerID, productID)
 }
 
-func (s *SellerService) UnpublishSellerProduct(sellerID, productID string) (models.SellerProduct, error) {
-	return s.repo.UnpublishSellerProduct(sellerID, productID)
+func (s *SellerService) UnpublishSellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
+	return s.repo.UnpublishSellerProduct(ctx, sellerID, productID)
 }
 
-func (s *SellerService) SoftDeleteSellerProduct(sellerID, productID string) error {
-	return s.repo.SoftDeleteSellerProduct(sellerID, productID)
+func (s *SellerService) SoftDeleteSellerProduct(ctx context.Context, sellerID, productID string) error {
+	return s.repo.SoftDeleteSellerProduct(ctx, sellerID, productID)
 }
 
-func (s *SellerService) CopySellerProduct(sellerID, productID string) (models.SellerProduct, error) {
-	original, err := s.repo.GetSellerProductByID(sellerID, productID)
+func (s *SellerService) CopySellerProduct(ctx context.Context, sellerID, productID string) (models.SellerProduct, error) {
+	original, err := s.repo.GetSellerProductByID(ctx, sellerID, productID)
 	if err != nil {
 		return models.SellerProduct{}, err
 	}
 
 	newSlugBase := slugify(original.Name + "-copy")
-	newSlug, err := s.generateUniqueProductSlug(newSlugBase)
+	newSlug, err := s.generateUniqueProductSlug(ctx, newSlugBase)
 	if err != nil {
 		return models.SellerProduct{}, fmt.Errorf("failed to generate unique slug for copy: %w", err)
 	}
@@ -380,7 +384,7 @@ func (s *SellerService) CopySellerProduct(sellerID, productID string) (models.Se
 	newProduct.UpdatedAt = time.Time{}
 	newProduct.DeletedAt = gorm.DeletedAt{}
 
-	if err := s.repo.CreateSellerProduct(&newProduct); err != nil {
+	if err := s.repo.CreateSellerProduct(ctx, &newProduct); err != nil {
 		return models.SellerProduct{}, err
 	}
 
@@ -391,8 +395,8 @@ func (s *SellerService) CopySellerProduct(sellerID, productID string) (models.Se
 // Seller Analytics (MVP)
 // --------------------
 
-func (s *SellerService) GetTopSellingProducts(sellerID string, limit int) ([]types.TopProductDTO, error) {
-	products, err := s.repo.GetTopSellingProducts(sellerID, limit)
+func (s *SellerService) GetTopSellingProducts(ctx context.Context, sellerID string, limit int) ([]types.TopProductDTO, error) {
+	products, err := s.repo.GetTopSellingProducts(ctx, sellerID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -414,21 +418,21 @@ func (s *SellerService) GetTopSellingProducts(sellerID string, limit int) ([]typ
 // Seller Product Variants
 // --------------------
 
-func (s *SellerService) CreateProductVariant(sellerID, productID string, payload types.CreateProductVariantPayload) (models.SellerProductVariant, error) {
+func (s *SellerService) CreateProductVariant(ctx context.Context, sellerID, productID string, payload types.CreateProductVariantPayload) (models.SellerProductVariant, error) {
 	// Verify product belongs to seller
-	product, err := s.repo.GetSellerProductByID(sellerID, productID)
+	product, err := s.repo.GetSellerProductByID(ctx, sellerID, productID)
 	if err != nil {
 		return models.SellerProductVariant{}, err
 	}
 
 	// Validate price
 	if payload.Price < 0 {
-		return models.SellerProductVariant{}, errors.New("price must be >= 0")
+		return models.SellerProductVariant{}, apperror.InvalidArgument("price must be >= 0")
 	}
 
 	// Validate quantity if provided
 	if payload.Quantity != nil && *payload.Quantity < 0 {
-		return models.SellerProductVariant{}, errors.New("quantity must be >= 0")
+		return models.SellerProductVariant{}, apperror.InvalidArgument("quantity must be >= 0")
 	}
 
 	// Create variant
@@ -462,43 +466,43 @@ func (s *SellerService) CreateProductVariant(sellerID, productID string, payload
 		variant.SortOrder = *payload.SortOrder
 	}
 
-	if err := s.repo.CreateProductVariant(&variant); err != nil {
+	if err := s.repo.CreateProductVariant(ctx, &variant); err != nil {
 		return models.SellerProductVariant{}, err
 	}
 
 	// Update product's has_variants flag if this is the first variant
 	if !product.HasVariants {
-		if _, err := s.repo.UpdateSellerProduct(sellerID, productID, map[string]interface{}{
+		if _, err := s.repo.UpdateSellerProduct(ctx, sellerID, productID, map[string]interface{}{
 			"has_variants": true,
 		}); err != nil {
 			// Log error but don't fail the variant creation
-			fmt.Printf("Warning: failed to update has_variants flag: %v\n", err)
+			logging.L().Warn("failed to update has_variants flag", zap.String("product_id", productID), zap.Error(err))
 		}
 	}
 
 	return variant, nil
 }
 
-func (s *SellerService) ListProductVariants(sellerID, productID string) ([]models.SellerProductVariant, error) {
+func (s *SellerService) ListProductVariants(ctx context.Context, sellerID, productID string) ([]models.SellerProductVariant, error) {
 	// Verify product belongs to seller
-	if _, err := s.repo.GetSellerProductByID(sellerID, productID); err != nil {
+	if _, err := s.repo.GetSellerProductByID(ctx, sellerID, productID); err != nil {
 		return nil, err
 	}
 
-	return s.repo.ListProductVariants(sellerID, productID)
+	return s.repo.ListProductVariants(ctx, sellerID, productID)
 }
 
-func (s *SellerService) GetProductVariant(sellerID, productID, variantID string) (models.SellerProductVariant, error) {
-	return s.repo.GetProductVariantByID(sellerID, productID, variantID)
+func (s *SellerService) GetProductVariant(ctx context.Context, sellerID, productID, variantID string) (models.SellerProductVariant, error) {
+	return s.repo.GetProductVariantByID(ctx, sellerID, productID, variantID)
 }
 
-func (s *SellerService) UpdateProductVariant(sellerID, productID, variantID string, payload types.UpdateProductVariantPayload) (models.SellerProductVariant, error) {
+func (s *SellerService) UpdateProductVariant(ctx context.Context, sellerID, productID, variantID string, payload types.UpdateProductVariantPayload) (models.SellerProductVariant, error) {
 	updates := map[string]interface{}{}
 
 	if payload.Name != nil {
 		name := strings.TrimSpace(*payload.Name)
 		if name == "" {
-			return models.SellerProductVariant{}, errors.New("name cannot be empty")
+			return models.SellerProductVariant{}, apperror.InvalidArgument("name cannot be empty")
 		}
 		updates["name"] = name
 	}
@@ -525,7 +529,7 @@ func (s *SellerService) UpdateProductVariant(sellerID, productID, variantID stri
 	}
 	if payload.Price != nil {
 		if *payload.Price < 0 {
-			return models.SellerProductVariant{}, errors.New("price must be >= 0")
+			return models.SellerProductVariant{}, apperror.InvalidArgument("price must be >= 0")
 		}
 		updates["price"] = *payload.Price
 	}
@@ -537,7 +541,7 @@ func (s *SellerService) UpdateProductVariant(sellerID, productID, variantID stri
 	}
 	if payload.Quantity != nil {
 		if *payload.Quantity < 0 {
-			return models.SellerProductVariant{}, errors.New("quantity must be >= 0")
+			return models.SellerProductVariant{}, apperror.InvalidArgument("quantity must be >= 0")
 		}
 		updates["quantity"] = *payload.Quantity
 	}
@@ -556,33 +560,33 @@ func (s *SellerService) UpdateProductVariant(sellerID, productID, variantID stri
 
 	if len(updates) == 0 {
 		// No updates provided, return current variant
-		return s.repo.GetProductVariantByID(sellerID, productID, variantID)
+		return s.repo.GetProductVariantByID(ctx, sellerID, productID, variantID)
 	}
 
-	return s.repo.UpdateProductVariant(sellerID, productID, variantID, updates)
+	return s.repo.UpdateProductVariant(ctx, sellerID, productID, variantID, updates)
 }
 
-func (s *SellerService) DeleteProductVariant(sellerID, productID, variantID string) error {
+func (s *SellerService) DeleteProductVariant(ctx context.Context, sellerID, productID, variantID string) error {
 	// Delete the variant
-	if err := s.repo.DeleteProductVariant(sellerID, productID, variantID); err != nil {
+	if err := s.repo.DeleteProductVariant(ctx, sellerID, productID, variantID); err != nil {
 		return err
 	}
 
 	// Check if there are any remaining variants
-	count, err := s.repo.CountProductVariants(productID)
+	count, err := s.repo.CountProductVariants(ctx, productID)
 	if err != nil {
 		// Log error but don't fail the deletion
-		fmt.Printf("Warning: failed to count remaining variants: %v\n", err)
+		logging.L().Warn("failed to count remaining variants", zap.String("product_id", productID), zap.Error(err))
 		return nil
 	}
 
 	// Update product's has_variants flag if no variants remain
 	if count == 0 {
-		if _, err := s.repo.UpdateSellerProduct(sellerID, productID, map[string]interface{}{
+		if _, err := s.repo.UpdateSellerProduct(ctx, sellerID, productID, map[string]interface{}{
 			"has_variants": false,
 		}); err != nil {
 			// Log error but don't fail the deletion
-			fmt.Printf("Warning: failed to update has_variants flag: %v\n", err)
+			logging.L().Warn("failed to update has_variants flag", zap.String("product_id", productID), zap.Error(err))
 		}
 	}
 
@@ -593,10 +597,10 @@ func (s *SellerService) DeleteProductVariant(sellerID, productID, variantID stri
 // Helpers
 // --------------------
 
-func (s *SellerService) generateUniqueProductSlug(base string) (string, error) {
+func (s *SellerService) generateUniqueProductSlug(ctx context.Context, base string) (string, error) {
 	slug := base
 	for i := 0; i < 50; i++ {
-		exists, err := s.repo.IsSellerProductSlugExists(slug)
+		exists, err := s.repo.IsSellerProductSlugExists(ctx, slug)
 		if err != nil {
 			return "", err
 		}
@@ -676,47 +680,47 @@ func toSellerDocumentDTO(doc models.SellerDocument) types.SellerDocumentResponse
 // Seller Finance & Payouts
 // --------------------
 
-func (s *SellerService) GetSellerBalance(sellerID string) (types.SellerBalanceResponseDTO, error) {
-	return s.repo.GetSellerBalance(sellerID)
+func (s *SellerService) GetSellerBalance(ctx context.Context, sellerID string) (types.SellerBalanceResponseDTO, error) {
+	return s.repo.GetSellerBalance(ctx, sellerID)
 }
 
-func (s *SellerService) GetSellerPayouts(sellerID, status string, page, limit int) ([]types.SellerPayoutResponseDTO, int64, error) {
-	return s.repo.GetSellerPayouts(sellerID, status, page, limit)
+func (s *SellerService) GetSellerPayouts(ctx context.Context, sellerID, status string, page, limit int) ([]types.SellerPayoutResponseDTO, int64, error) {
+	return s.repo.GetSellerPayouts(ctx, sellerID, status, page, limit)
 }
 
-func (s *SellerService) GetPayoutDetails(sellerID, payoutID string) (types.SellerPayoutResponseDTO, error) {
-	return s.repo.GetPayoutDetails(sellerID, payoutID)
+func (s *SellerService) GetPayoutDetails(ctx context.Context, sellerID, payoutID string) (types.SellerPayoutResponseDTO, error) {
+	return s.repo.GetPayoutDetails(ctx, sellerID, payoutID)
 }
 
-func (s *SellerService) RequestWithdrawal(sellerID string, amount float64, notes *string) (types.SellerPayoutResponseDTO, error) {
+func (s *SellerService) RequestWithdrawal(ctx context.Context, sellerID string, amount float64, notes *string) (types.SellerPayoutResponseDTO, error) {
 	// Get seller to check bank info and balance
-	seller, err := s.repo.GetByID(sellerID)
+	seller, err := s.repo.GetByID(ctx, sellerID)
 	if err != nil {
 		return types.SellerPayoutResponseDTO{}, err
 	}
 
 	if seller.BankName == nil || seller.BankAccountNumber == nil {
-		return types.SellerPayoutResponseDTO{}, errors.New("bank account information is required for withdrawal")
+		return types.SellerPayoutResponseDTO{}, apperror.Conflict("bank account information is required for withdrawal")
 	}
 
 	// Check available balance
-	balance, err := s.repo.GetSellerBalance(sellerID)
+	balance, err := s.repo.GetSellerBalance(ctx, sellerID)
 	if err != nil {
 		return types.SellerPayoutResponseDTO{}, err
 	}
 
 	if amount > balance.AvailableBalance {
-		return types.SellerPayoutResponseDTO{}, errors.New("insufficient balance")
+		return types.SellerPayoutResponseDTO{}, apperror.Conflict("insufficient balance")
 	}
 
 	// Create payout with status 'pending'
-	return s.repo.CreateWithdrawalPayout(sellerID, amount, notes, seller)
+	return s.repo.CreateWithdrawalPayout(ctx, sellerID, amount, notes, seller)
 }
 
-func (s *SellerService) GetPayoutSchedule(sellerID string) (types.PayoutScheduleResponseDTO, error) {
-	return s.repo.GetPayoutSchedule(sellerID)
+func (s *SellerService) GetPayoutSchedule(ctx context.Context, sellerID string) (types.PayoutScheduleResponseDTO, error) {
+	return s.repo.GetPayoutSchedule(ctx, sellerID)
 }
 
-func (s *SellerService) UpdatePayoutSchedule(sellerID string, payload types.UpdatePayoutSchedulePayload) (types.PayoutScheduleResponseDTO, error) {
-	return s.repo.UpdatePayoutSchedule(sellerID, payload)
+func (s *SellerService) UpdatePayoutSchedule(ctx context.Context, sellerID string, payload types.UpdatePayoutSchedulePayload) (types.PayoutScheduleResponseDTO, error) {
+	return s.repo.UpdatePayoutSchedule(ctx, sellerID, payload)
 }
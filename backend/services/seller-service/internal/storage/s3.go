@@ -56,3 +56,15 @@ func (u *S3Uploader) UploadFile(ctx context.Context, sellerID, objectType, fileN
 	s3URI := fmt.Sprintf("s3://%s/%s", u.bucket, key)
 	return s3URI, nil
 }
+
+// Ping checks that the bucket exists and the credentials can reach it.
+func (u *S3Uploader) Ping(ctx context.Context) error {
+	_, err := u.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(u.bucket),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reach s3 bucket %s: %w", u.bucket, err)
+	}
+
+	return nil
+}
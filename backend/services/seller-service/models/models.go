@@ -110,6 +110,9 @@ func (SellerDocument) TableName() string {
 	return "seller_document"
 }
 
+// SellerProduct is stored in seller_product, which migration 20260130_remove_seller_product_tables
+// drops because products now live in product-service. The product routes still read and write it
+// and fail against a migrated database until they move to product-service.
 type SellerProduct struct {
 	ID string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 
@@ -168,6 +171,7 @@ func (SellerProduct) TableName() string {
 // Seller Product Variants
 // --------------------
 
+// SellerProductVariant is stored in seller_product_variant, dropped with seller_product, see SellerProduct.
 type SellerProductVariant struct {
 	ID          string  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
 	ProductID   string  `gorm:"type:uuid;not null;index" json:"product_id"`
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+)
 
 type SellerProfileResponseDTO struct {
 	ID     string `json:"id"`
@@ -126,12 +130,7 @@ type SellerProductResponseDTO struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type ListSellerProductsResponseDTO struct {
-	Products []SellerProductResponseDTO `json:"products"`
-	Total    int64                      `json:"total"`
-	Page     int                        `json:"page"`
-	Limit    int                        `json:"limit"`
-}
+type ListSellerProductsResponseDTO = utils.Paginated[SellerProductResponseDTO]
 
 // --------------------
 // Seller Analytics (MVP)
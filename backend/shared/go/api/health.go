@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Checker is one dependency checked by /readyz.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckFunc turns fn into a Checker, e.g. for S3 bucket access: api.CheckFunc("s3", uploader.Ping).
+func CheckFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkFunc{name: name, fn: fn}
+}
+
+type optionalChecker struct {
+	Checker
+}
+
+// Optional marks a dependency the service can limp along without, its failure reports
+// degraded but keeps the service ready.
+func Optional(checker Checker) Checker {
+	return optionalChecker{checker}
+}
+
+// PostgresChecker pings the pool behind db.
+func PostgresChecker(db *gorm.DB) Checker {
+	return CheckFunc("postgres", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// KafkaChecker checks that the brokers are reachable.
+func KafkaChecker(broker kafka.Broker) Checker {
+	return CheckFunc("kafka", broker.Ping)
+}
+
+// HTTPChecker expects a 2xx from url, usually the health endpoint of a downstream service.
+func HTTPChecker(name string, url string) Checker {
+	client := &http.Client{}
+	return CheckFunc(name, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s returned status: %d", url, resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+type HealthConfig struct {
+	CacheTTL time.Duration // how long a readiness result is reused, checks are not run per probe
+	Timeout  time.Duration // per check
+}
+
+// CheckResult is served on the public /readyz, so only the status leaves the service.
+// Failures are logged with their error when the check runs.
+type CheckResult struct {
+	Status    string    `json:"status"`
+	Optional  bool      `json:"optional,omitempty"`
+	LatencyMS int64     `json:"-"`
+	Error     string    `json:"-"`
+	CheckedAt time.Time `json:"-"`
+}
+
+type HealthReport struct {
+	Status  string                 `json:"status"`
+	Service string                 `json:"service"`
+	Checks  map[string]CheckResult `json:"checks,omitempty"`
+	Cached  bool                   `json:"cached,omitempty"`
+}
+
+// Health runs the registered checkers concurrently and caches the report.
+type Health struct {
+	service  string
+	config   HealthConfig
+	draining atomic.Bool
+
+	mu       sync.Mutex
+	checkers []Checker
+	report   *HealthReport
+	expires  time.Time
+}
+
+func NewHealth(service string, config HealthConfig) *Health {
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 5 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 2 * time.Second
+	}
+
+	return &Health{service: service, config: config}
+}
+
+func (h *Health) Add(checkers ...Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, checkers...)
+	h.expires = time.Time{}
+}
+
+// SetDraining makes /readyz fail so load balancers stop sending new requests during shutdown.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Ready returns the cached report, or runs the checks when the cache expired.
+// The lock is held while checking so concurrent probes share one run.
+func (h *Health) Ready(ctx context.Context) HealthReport {
+	if h.draining.Load() {
+		return HealthReport{Status: StatusUnavailable, Service: h.service}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.report != nil && time.Now().Before(h.expires) {
+		report := *h.report
+		report.Cached = true
+		return report
+	}
+
+	report := h.run(ctx)
+	h.report = &report
+	h.expires = time.Now().Add(h.config.CacheTTL)
+	return report
+}
+
+func (h *Health) run(ctx context.Context) HealthReport {
+	report := HealthReport{Status: StatusOK, Service: h.service, Checks: make(map[string]CheckResult, len(h.checkers))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, checker := range h.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := h.check(ctx, checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[checker.Name()] = result
+			switch {
+			case result.Status == StatusOK:
+			case result.Optional:
+				if report.Status == StatusOK {
+					report.Status = StatusDegraded
+				}
+			default:
+				report.Status = StatusUnavailable
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Health) check(ctx context.Context, checker Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+
+	_, optional := checker.(optionalChecker)
+	started := time.Now()
+	err := checker.Check(ctx)
+
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMS: time.Since(started).Milliseconds(),
+		Optional:  optional,
+		CheckedAt: started.UTC(),
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+		logging.L().Warn("readiness check failed", zap.String("check", checker.Name()), zap.Bool("optional", optional), zap.Int64("latency_ms", result.LatencyMS), zap.Error(err))
+	}
+
+	return result
+}
+
+// LiveHandler answers as long as the process can serve requests, dependencies are not checked
+// because restarting the service would not fix them.
+func (h *Health) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, HealthReport{Status: StatusOK, Service: h.service})
+}
+
+func (h *Health) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	//checks run detached from the probe so a probe timing out does not poison the cached result
+	report := h.Ready(context.WithoutCancel(r.Context()))
+
+	status := http.StatusOK
+	if report.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+func writeHealth(w http.ResponseWriter, status int, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
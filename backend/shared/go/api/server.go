@@ -1,41 +1,338 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/idempotency"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/metrics"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type ServerConfig struct {
 	Addr        string
+	MetricsAddr string // /metrics is only served here, keep the port off the public load balancer, empty disables it
 	DB          *gorm.DB
 	ServiceName string
 	APIPrefix   string
+	Timeouts    Timeouts
+	Health      HealthConfig
+	Idempotency idempotency.Config
+
+	// ServiceTokens verifies the service token of internal requests, without it they are all rejected.
+	// UserAuth authenticates the end users of the routes wrapped in Authenticated. Services build both
+	// from their config, see auth.ServiceAuthOptions and middleware.UserAuthOptions.
+	ServiceTokens *auth.Verifier
+	UserAuth      middleware.UserAuthConfig
+
+	// InternalPolicy decides which services may call which internal routes, e.g. loaded with
+	// auth.LoadPolicyFile. Rules can also be added with AllowInternal. Internal calls without
+	// a matching rule are denied and audited through PolicyAuditor.
+	InternalPolicy *auth.Policy
+	PolicyAuditor  middleware.PolicyAuditor
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
 }
 
 type Server struct {
 	config ServerConfig
 	router *mux.Router
+	server *http.Server
+	health *Health
+
+	metricsServer *http.Server
+
+	serviceAuth      func(http.Handler) http.Handler
+	userAuth         func(http.Handler) http.Handler
+	idempotencyStore *idempotency.Store
+
+	mu    sync.Mutex
+	hooks []shutdownHook
 }
 
 func NewServer(config ServerConfig) *Server {
+	if config.InternalPolicy == nil {
+		config.InternalPolicy = auth.NewPolicy()
+	}
+	config.Timeouts = config.Timeouts.withDefaults()
+
+	health := NewHealth(config.ServiceName, config.Health)
+	var idempotencyStore *idempotency.Store
+	if config.DB != nil {
+		idempotencyStore = idempotency.NewStore(config.DB)
+		health.Add(PostgresChecker(config.DB))
+		//databases from postgres.Open come with both, and their own slow query threshold
+		if _, ok := config.DB.Logger.(*logging.GormLogger); !ok {
+			config.DB.Logger = logging.NewGormLogger()
+		}
+		if _, ok := config.DB.Plugins[metrics.GormPlugin{}.Name()]; !ok {
+			if err := config.DB.Use(metrics.GormPlugin{}); err != nil {
+				logging.L().Warn("gorm metrics disabled", zap.Error(err))
+			}
+		}
+	}
+
+	serviceAuth := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apperror.Write(w, r, apperror.Unauthenticated("service authentication is not configured"))
+		})
+	}
+	if config.ServiceTokens != nil {
+		serviceAuth = middleware.NewServiceAuthMiddleware(config.ServiceTokens)
+	}
+
 	router := mux.NewRouter()
+	router.Use(tracing.Middleware, logging.Middleware, metrics.Middleware, middleware.RecoverMiddleware)
+	//router middlewares do not run for unmatched routes, hence the tracing wrapper for the request id
+	router.NotFoundHandler = tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apperror.Write(w, r, apperror.NotFound("route not found"))
+	}))
+	router.HandleFunc("/livez", health.LiveHandler).Methods("GET")
+	router.HandleFunc("/readyz", health.ReadyHandler).Methods("GET")
+	router.HandleFunc("/health", health.LiveHandler).Methods("GET") //kept for existing docker healthchecks
+
+	//metrics name every route and dependency, so they get their own listener instead of a public route
+	var metricsServer *http.Server
+	if config.MetricsAddr != "" {
+		metricsRouter := http.NewServeMux()
+		metricsRouter.Handle("GET /metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:              listenAddr(config.MetricsAddr),
+			Handler:           metricsRouter,
+			ReadHeaderTimeout: config.Timeouts.ReadHeader,
+		}
+	}
+
 	return &Server{
 		config: config,
 		router: router,
+		health: health,
+
+		metricsServer:    metricsServer,
+		serviceAuth:      serviceAuth,
+		userAuth:         middleware.NewUserAuthMiddleware(config.UserAuth),
+		idempotencyStore: idempotencyStore,
+		server: &http.Server{
+			Addr:              listenAddr(config.Addr),
+			Handler:           router,
+			ReadTimeout:       config.Timeouts.Read,
+			ReadHeaderTimeout: config.Timeouts.ReadHeader,
+			WriteTimeout:      config.Timeouts.Write,
+			IdleTimeout:       config.Timeouts.Idle,
+		},
+	}
+}
+
+// Router is the root router, for routes outside of /api and /internal.
+func (s *Server) Router() *mux.Router {
+	return s.router
+}
+
+// AddHealthCheck adds dependencies to /readyz, Postgres is checked already when ServerConfig.DB is set.
+func (s *Server) AddHealthCheck(checkers ...Checker) {
+	s.health.Add(checkers...)
+}
+
+// AllowInternal lets service call the internal routes at paths with methods, no methods allows any.
+// Paths are relative to the internal prefix, e.g. "/checkout/{userId}" or "/checkout/*".
+func (s *Server) AllowInternal(service string, methods []string, paths ...string) {
+	full := make([]string, 0, len(paths))
+	for _, path := range paths {
+		full = append(full, s.internalPrefix()+path)
 	}
+
+	s.config.InternalPolicy.Allow(service, methods, full...)
 }
 
+// RegisterRoutes hands out the external and the internal subrouter. Internal routes always require
+// a service token and a policy rule for the caller.
 func (s *Server) RegisterRoutes(registerFunc func(*mux.Router, *mux.Router)) {
-	external_subrouter := s.router.PathPrefix(fmt.Sprintf("/api%s", s.config.APIPrefix)).Subrouter()
-	internal_subrouter := s.router.PathPrefix(fmt.Sprintf("/internal%s", s.config.APIPrefix)).Subrouter()
+	external_subrouter := s.router.PathPrefix("/api" + s.prefix()).Subrouter()
+	internal_subrouter := s.router.PathPrefix(s.internalPrefix()).Subrouter()
+	internal_subrouter.Use(
+		s.serviceAuth,
+		middleware.InternalPolicyMiddleware(s.config.InternalPolicy, s.config.PolicyAuditor),
+	)
 
 	registerFunc(external_subrouter, internal_subrouter)
 }
 
+// Authenticated rejects requests to next without an authenticated end user and puts the user into
+// the request context, see middleware.NewUserAuthMiddleware and ServerConfig.UserAuth.
+func (s *Server) Authenticated(next http.Handler) http.Handler {
+	return s.userAuth(next)
+}
+
+// Idempotent honours the Idempotency-Key header on the POST and PATCH route handler next, see
+// idempotency.Middleware. It has to wrap the handler inside authentication, keys are scoped to the
+// caller. Without ServerConfig.DB there is nowhere to keep responses and next is returned as is.
+func (s *Server) Idempotent(next http.Handler) http.Handler {
+	if s.idempotencyStore == nil {
+		logging.L().Warn("idempotency keys are not honoured without a database")
+		return next
+	}
+
+	return idempotency.Middleware(s.idempotencyStore, s.config.Idempotency)(next)
+}
+
+// OnShutdown registers a hook that runs after in-flight requests have drained, e.g. closing
+// the DB pool or flushing a kafka producer. Hooks run in the order they were registered.
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Start serves until the server fails or is shut down, a shutdown is not reported as an error.
+// The metrics listener runs alongside, its failure is logged but keeps the service up.
 func (s *Server) Start() error {
-	fmt.Printf("Starting %s at port: %v\n", s.config.ServiceName, s.config.Addr)
-	return http.ListenAndServe(s.config.Addr, s.router)
+	if s.metricsServer != nil {
+		go s.serveMetrics()
+	}
+
+	logging.L().Info("starting server", zap.String("addr", s.server.Addr))
+	if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Run starts the server and shuts it down gracefully on SIGINT, SIGTERM or when ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Start()
+	}()
+	if s.idempotencyStore != nil {
+		go s.purgeIdempotencyKeys(ctx)
+	}
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			//still release what the hooks hold, the listener never came up
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.Timeouts.Shutdown)
+			defer cancel()
+			return errors.Join(err, s.runHooks(shutdownCtx))
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logging.L().Info("shutting down")
+	s.health.SetDraining()
+	//load balancers keep routing to us for a moment after the signal, keep serving meanwhile
+	if s.config.Timeouts.ShutdownDelay > 0 {
+		time.Sleep(s.config.Timeouts.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.Timeouts.Shutdown)
+	defer cancel()
+	return s.Shutdown(shutdownCtx)
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests until ctx is done and then runs the hooks.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to drain requests: %w", err)
+	}
+	//metrics stay up until the requests drained, so the last scrape still sees them
+	if s.metricsServer != nil {
+		if metricsErr := s.metricsServer.Shutdown(ctx); metricsErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to stop metrics listener: %w", metricsErr))
+		}
+	}
+
+	return errors.Join(err, s.runHooks(ctx))
+}
+
+func (s *Server) serveMetrics() {
+	logging.L().Info("starting metrics listener", zap.String("addr", s.metricsServer.Addr))
+	if err := s.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logging.L().Error("metrics listener failed", zap.String("addr", s.metricsServer.Addr), zap.Error(err))
+	}
+}
+
+func (s *Server) runHooks(ctx context.Context) error {
+	s.mu.Lock()
+	hooks := s.hooks
+	s.hooks = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook.fn(ctx); err != nil {
+			logging.L().Error("shutdown hook failed", zap.String("hook", hook.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s: %w", hook.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys once an hour until ctx is cancelled.
+func (s *Server) purgeIdempotencyKeys(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := s.idempotencyStore.DeleteExpired(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.L().Error("failed to purge idempotency keys", zap.Error(err))
+		} else if deleted > 0 {
+			logging.L().Info("purged idempotency keys", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) internalPrefix() string {
+	return "/internal" + s.prefix()
+}
+
+// accepts "cart" as well as "/cart"
+func (s *Server) prefix() string {
+	if s.config.APIPrefix == "" {
+		return ""
+	}
+
+	return "/" + strings.TrimPrefix(s.config.APIPrefix, "/")
+}
+
+// accepts a bare port like "3003" as well as ":3003" or "host:3003"
+func listenAddr(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+
+	return ":" + addr
 }
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"time"
+)
+
+// Timeouts bound how long a connection may take, zero values fall back to the defaults below.
+// Service configs embed them, so every service reads the same HTTP_* keys, see the config package.
+type Timeouts struct {
+	Read          time.Duration `env:"HTTP_READ_TIMEOUT" default:"15s"` // whole request including the body
+	ReadHeader    time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	Write         time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s"`    // from the end of the request headers to the end of the response
+	Idle          time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s"`    // keep-alive connections between requests
+	Shutdown      time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"30s"` // draining in-flight requests plus the shutdown hooks
+	ShutdownDelay time.Duration `env:"HTTP_SHUTDOWN_DELAY" default:"0s"`    // keep serving after the signal until load balancers stop sending traffic
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Read <= 0 {
+		t.Read = 15 * time.Second
+	}
+	if t.ReadHeader <= 0 {
+		t.ReadHeader = 5 * time.Second
+	}
+	if t.Write <= 0 {
+		t.Write = 30 * time.Second
+	}
+	if t.Idle <= 0 {
+		t.Idle = 120 * time.Second
+	}
+	if t.Shutdown <= 0 {
+		t.Shutdown = 30 * time.Second
+	}
+
+	return t
+}
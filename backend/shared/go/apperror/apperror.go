@@ -0,0 +1,190 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Code identifies the kind of failure independent of the transport, clients can branch on it.
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodePermissionDenied Code = "permission_denied"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodeUnprocessable    Code = "unprocessable"
+	CodeTooLarge         Code = "payload_too_large"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnavailable      Code = "unavailable"
+	CodeTimeout          Code = "timeout"
+	CodeInternal         Code = "internal"
+)
+
+var statuses = map[Code]int{
+	CodeInvalidArgument:  http.StatusBadRequest,
+	CodeUnauthenticated:  http.StatusUnauthorized,
+	CodePermissionDenied: http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeConflict:         http.StatusConflict,
+	CodeUnprocessable:    http.StatusUnprocessableEntity,
+	CodeTooLarge:         http.StatusRequestEntityTooLarge,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeUnavailable:      http.StatusServiceUnavailable,
+	CodeTimeout:          http.StatusGatewayTimeout,
+	CodeInternal:         http.StatusInternalServerError,
+}
+
+// messages shown when the failure has no message of its own, or when its cause must not leak
+var defaultMessages = map[Code]string{
+	CodeInvalidArgument:  "invalid request",
+	CodeUnauthenticated:  "authentication required",
+	CodePermissionDenied: "not allowed",
+	CodeNotFound:         "resource not found",
+	CodeConflict:         "conflict with the current state of the resource",
+	CodeUnprocessable:    "the request cannot be processed",
+	CodeTooLarge:         "request body too large",
+	CodeRateLimited:      "too many requests",
+	CodeUnavailable:      "service temporarily unavailable",
+	CodeTimeout:          "request timed out",
+	CodeInternal:         "internal server error",
+}
+
+// Error is a failure with a code and a message that is safe to show to clients. The cause
+// is kept for logs and errors.Is but never rendered.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  map[string]string // per field problems of invalid input
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status is the HTTP status of the error code.
+func (e *Error) Status() int {
+	return StatusOf(e.Code)
+}
+
+func StatusOf(code Code) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+func New(code Code, message string) *Error {
+	if message == "" {
+		message = defaultMessages[code]
+	}
+
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap attaches a public message to err, err itself only shows up in logs.
+func Wrap(err error, code Code, message string) *Error {
+	e := New(code, message)
+	e.Err = err
+	return e
+}
+
+func InvalidArgument(message string) *Error  { return New(CodeInvalidArgument, message) }
+func Unauthenticated(message string) *Error  { return New(CodeUnauthenticated, message) }
+func PermissionDenied(message string) *Error { return New(CodePermissionDenied, message) }
+func NotFound(message string) *Error         { return New(CodeNotFound, message) }
+func Conflict(message string) *Error         { return New(CodeConflict, message) }
+
+// Internal hides err behind the generic message.
+func Internal(err error) *Error {
+	return Wrap(err, CodeInternal, "")
+}
+
+// Validation turns validator errors into an invalid argument error listing the failed fields.
+func Validation(err error) *Error {
+	e := Wrap(err, CodeInvalidArgument, "validation failed")
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		e.Fields = make(map[string]string, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			e.Fields[fieldName(fieldErr)] = "failed on the '" + fieldErr.Tag() + "' rule"
+		}
+	}
+
+	return e
+}
+
+// From classifies any error. Errors that are not an *Error keep their cause private: record not
+// found becomes a generic not found, deadlines a timeout and everything else an internal error.
+func From(err error) *Error {
+	var e *Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return Wrap(err, CodeNotFound, "")
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, CodeTimeout, "")
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return Validation(err)
+	}
+
+	return Internal(err)
+}
+
+// FromStatus is for callers that only know the status they want to answer with. Messages of
+// client errors are kept, server errors get the generic message of their code.
+func FromStatus(status int, err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	code := CodeInvalidArgument
+	if status >= 500 {
+		code = CodeInternal
+	}
+	for candidate, candidateStatus := range statuses {
+		if candidateStatus == status {
+			code = candidate
+			break
+		}
+	}
+	if status >= 500 || err == nil {
+		return Wrap(err, code, "")
+	}
+
+	return Wrap(err, code, err.Error())
+}
+
+// the namespace without the top level struct, e.g. "items[0].quantity"
+func fieldName(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return fieldErr.Field()
+}
@@ -0,0 +1,68 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"go.uber.org/zap"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of every error response. Code, RequestID, CorrelationID and
+// Errors are extension members.
+type Problem struct {
+	Type          string            `json:"type"`
+	Title         string            `json:"title"`
+	Status        int               `json:"status"`
+	Detail        string            `json:"detail,omitempty"`
+	Instance      string            `json:"instance,omitempty"`
+	Code          Code              `json:"code"`
+	RequestID     string            `json:"request_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+// NewProblem renders e for r, r may be nil when the request is not at hand.
+func NewProblem(r *http.Request, e *Error) Problem {
+	status := e.Status()
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: e.Message,
+		Code:   e.Code,
+		Errors: e.Fields,
+	}
+	if r != nil {
+		problem.Instance = r.URL.Path
+		problem.RequestID = tracing.RequestIDFromContext(r.Context())
+		problem.CorrelationID = tracing.CorrelationIDFromContext(r.Context())
+	}
+
+	return problem
+}
+
+// Write answers r with err as problem details, see From for how plain errors are classified.
+// Server errors are logged with their cause, which the response never contains.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	if e == nil {
+		e = Internal(nil)
+	}
+
+	if e.Status() >= 500 && r != nil {
+		logging.FromContext(r.Context()).Error("request failed", zap.String("code", string(e.Code)), zap.Error(e))
+	}
+
+	WriteProblem(w, NewProblem(r, e))
+}
+
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefresh = 10 * time.Minute
+	maxJWKSBytes       = 1 << 20
+)
+
+// KeySource looks up the public key a user token was signed with.
+type KeySource interface {
+	PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS is a key source backed by a JSON Web Key Set read from a local file or a URL.
+// Keys are reloaded after the refresh interval, or right away when an unknown kid shows up,
+// so keys the issuer rotates in are picked up without a restart.
+type JWKS struct {
+	load    func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+
+	mu       sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+}
+
+func NewJWKSFile(path string) (*JWKS, error) {
+	jwks := &JWKS{
+		refresh: defaultJWKSRefresh,
+		load: func(ctx context.Context) ([]byte, error) {
+			return os.ReadFile(path)
+		},
+	}
+
+	return jwks, jwks.reload(context.Background())
+}
+
+func NewJWKSURL(url string, refresh time.Duration) (*JWKS, error) {
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	jwks := &JWKS{
+		refresh: refresh,
+		load: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("jwks endpoint returned status: %d", resp.StatusCode)
+			}
+
+			return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+		},
+	}
+
+	return jwks, jwks.reload(context.Background())
+}
+
+func (j *JWKS) PublicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	stale := time.Since(j.loadedAt) > j.refresh
+	j.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	//a failed reload keeps serving the keys we already have
+	if err := j.reload(ctx); err != nil && !ok {
+		return nil, fmt.Errorf("failed to reload jwks: %w", err)
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+}
+
+func (j *JWKS) reload(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	//several requests with an unknown kid should not all hit the source
+	if !j.loadedAt.IsZero() && time.Since(j.loadedAt) < 10*time.Second {
+		return nil
+	}
+
+	data, err := j.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	j.keys = keys
+	j.loadedAt = time.Now()
+	return nil
+}
+
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no signing keys")
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url number")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
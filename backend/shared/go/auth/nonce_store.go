@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryNonceStore keeps used nonces in memory until their token expires.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	//drop expired nonces at most once a minute so the map stays bounded by the token lifetime
+	if now.Sub(s.lastSweep) > time.Minute {
+		for key, expiry := range s.nonces {
+			if now.After(expiry) {
+				delete(s.nonces, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiry, ok := s.nonces[nonce]; ok && now.Before(expiry) {
+		return false, nil
+	}
+
+	s.nonces[nonce] = expiresAt
+	return true, nil
+}
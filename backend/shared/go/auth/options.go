@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ServiceAuthOptions are the service token settings of a service config, see the config package.
+// SERVICE_KEYS lists kid:secret pairs, a rotated out key stays listed until no caller signs with it.
+type ServiceAuthOptions struct {
+	Keys         string `env:"SERVICE_KEYS,required,secret"`
+	SigningKeyID string `env:"SERVICE_SIGNING_KEY_ID"` // default the first key
+	LegacySecret string `env:"SERVICE_SECRET,secret"`  // for services that only verify legacy tokens
+	AllowLegacy  bool   `env:"SERVICE_AUTH_ALLOW_LEGACY" default:"false"`
+}
+
+// Signer signs the calls of serviceName with v2 tokens.
+func (o ServiceAuthOptions) Signer(serviceName string) (*Signer, error) {
+	keys, err := o.keySet()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{ServiceName: serviceName, Keys: keys}, nil
+}
+
+// LegacySigner signs the calls of serviceName to services that do not verify v2 tokens yet.
+func (o ServiceAuthOptions) LegacySigner(serviceName string) (*Signer, error) {
+	if o.LegacySecret == "" {
+		return nil, errors.New("SERVICE_SECRET is required to call services that only verify legacy tokens")
+	}
+
+	return &Signer{ServiceName: serviceName, LegacySecret: o.LegacySecret}, nil
+}
+
+// Verifier verifies the v2 tokens addressed to audience, the name of the service itself.
+func (o ServiceAuthOptions) Verifier(audience string) (*Verifier, error) {
+	keys, err := o.keySet()
+	if err != nil {
+		return nil, err
+	}
+
+	config := VerifierConfig{Keys: keys, Audience: audience}
+	//legacy tokens carry no audience and can be replayed, accept them only while explicitly allowed
+	if o.AllowLegacy {
+		if o.LegacySecret == "" {
+			return nil, errors.New("SERVICE_AUTH_ALLOW_LEGACY needs SERVICE_SECRET")
+		}
+		config.LegacySecret = o.LegacySecret
+	}
+
+	return NewVerifier(config)
+}
+
+func (o ServiceAuthOptions) keySet() (*KeySet, error) {
+	keys, err := ParseKeySet(o.Keys, o.SigningKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVICE_KEYS: %w", err)
+	}
+
+	return keys, nil
+}
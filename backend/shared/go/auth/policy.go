@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AnyService in a rule matches every authenticated caller.
+const AnyService = "*"
+
+// PolicyRule lets Service call Paths with Methods. Paths are mux route templates as registered,
+// e.g. /internal/cart/checkout/{userId}; a trailing /* matches everything below the prefix.
+// No methods means any method.
+type PolicyRule struct {
+	Service string   `json:"service"`
+	Methods []string `json:"methods,omitempty"`
+	Paths   []string `json:"paths"`
+}
+
+// Policy decides which services may call which internal routes. Anything without a matching rule is denied.
+type Policy struct {
+	mu    sync.RWMutex
+	rules []PolicyRule
+}
+
+type policyFile struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+func NewPolicy(rules ...PolicyRule) *Policy {
+	policy := &Policy{}
+	for _, rule := range rules {
+		policy.Allow(rule.Service, rule.Methods, rule.Paths...)
+	}
+
+	return policy
+}
+
+// LoadPolicyFile reads a policy written as {"rules": [{"service": "order-service", "methods": ["GET"], "paths": ["/internal/cart/*"]}]}.
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var file policyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+
+	for i, rule := range file.Rules {
+		if rule.Service == "" || len(rule.Paths) == 0 {
+			return nil, fmt.Errorf("policy rule %d in %s needs a service and at least one path", i, path)
+		}
+	}
+
+	return NewPolicy(file.Rules...), nil
+}
+
+func (p *Policy) Allow(service string, methods []string, paths ...string) {
+	upper := make([]string, 0, len(methods))
+	for _, method := range methods {
+		upper = append(upper, strings.ToUpper(method))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rules = append(p.rules, PolicyRule{Service: service, Methods: upper, Paths: paths})
+}
+
+// Allowed reports whether service may call route with method. route is the matched route template.
+func (p *Policy) Allowed(service string, method string, route string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, rule := range p.rules {
+		if rule.Service != AnyService && rule.Service != service {
+			continue
+		}
+		if len(rule.Methods) > 0 && !slices.Contains(rule.Methods, method) {
+			continue
+		}
+		for _, path := range rule.Paths {
+			if matchRoute(path, route) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchRoute(pattern string, route string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return route == prefix || strings.HasPrefix(route, prefix+"/")
+	}
+
+	return pattern == route
+}
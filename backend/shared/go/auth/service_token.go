@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Service tokens v2 look like v2.<payload>.<signature>, both parts base64url without padding.
+// The payload carries the claims below, the signature is an HMAC-SHA256 over "v2.<payload>"
+// with the key named by kid, so several keys can be active while a secret is rotated.
+const (
+	tokenVersion     = "v2"
+	defaultTokenTTL  = 5 * time.Minute
+	defaultClockSkew = 30 * time.Second
+	nonceBytes       = 16
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid token format")
+	ErrTokenExpired  = errors.New("token expired")
+	ErrInvalidSig    = errors.New("invalid signature")
+	ErrUnknownKey    = errors.New("unknown signing key")
+	ErrWrongAudience = errors.New("token not issued for this service")
+	ErrReplayed      = errors.New("token already used")
+	ErrBodyMismatch  = errors.New("request body does not match token")
+)
+
+type ServiceClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	KeyID     string `json:"kid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Nonce     string `json:"nonce"`
+	BodyHash  string `json:"bh,omitempty"` //hex sha256 of the request body, empty when the body is not bound
+	Legacy    bool   `json:"-"`            //verified from a serviceName:timestamp:signature token
+}
+
+// KeySet holds the active service secrets by key id. The signing key is used for new tokens,
+// every key in the set is accepted when verifying.
+type KeySet struct {
+	keys         map[string][]byte
+	signingKeyID string
+}
+
+func NewKeySet(signingKeyID string, keys map[string]string) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one service key is required")
+	}
+
+	set := &KeySet{keys: make(map[string][]byte, len(keys)), signingKeyID: signingKeyID}
+	for id, secret := range keys {
+		if id == "" || secret == "" {
+			return nil, errors.New("service keys need an id and a secret")
+		}
+		set.keys[id] = []byte(secret)
+	}
+
+	if _, ok := set.keys[signingKeyID]; !ok {
+		return nil, fmt.Errorf("signing key %q is not in the key set", signingKeyID)
+	}
+
+	return set, nil
+}
+
+// ParseKeySet reads keys written as id:secret,id:secret. The first key signs unless signingKeyID is set.
+func ParseKeySet(value string, signingKeyID string) (*KeySet, error) {
+	keys := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, errors.New("invalid service key, expected id:secret")
+		}
+		if signingKeyID == "" {
+			signingKeyID = id
+		}
+		keys[id] = secret
+	}
+
+	return NewKeySet(signingKeyID, keys)
+}
+
+func (k *KeySet) SigningKeyID() string {
+	return k.signingKeyID
+}
+
+// GenerateServiceTokenV2 signs a single use token from serviceName to audience.
+// Pass the request body to bind the token to it, nil leaves the body unbound.
+func GenerateServiceTokenV2(serviceName string, audience string, keys *KeySet, body []byte) (string, error) {
+	if serviceName == "" || audience == "" {
+		return "", errors.New("service name and audience are required")
+	}
+
+	nonce := make([]byte, nonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	now := time.Now()
+	claims := ServiceClaims{
+		Issuer:    serviceName,
+		Audience:  audience,
+		KeyID:     keys.signingKeyID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(defaultTokenTTL).Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+	}
+	if body != nil {
+		claims.BodyHash = HashBody(body)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + sign(keys.keys[keys.signingKeyID], signed), nil
+}
+
+func HashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// NonceStore remembers nonces until they expire. Use reports false when the nonce was seen before.
+// The memory store only protects a single instance, replicas need a shared store to reject replays across them.
+type NonceStore interface {
+	Use(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+type VerifierConfig struct {
+	Keys     *KeySet
+	Audience string // the name of the verifying service, tokens for other services are rejected
+	Nonces   NonceStore
+
+	// LegacySecret still accepts serviceName:timestamp:signature tokens signed with it, without
+	// audience or replay protection. Leave it empty once every caller sends v2 tokens.
+	LegacySecret    string
+	RequireBodyHash bool
+	ClockSkew       time.Duration
+}
+
+type Verifier struct {
+	config VerifierConfig
+}
+
+func NewVerifier(config VerifierConfig) (*Verifier, error) {
+	if config.Keys == nil && config.LegacySecret == "" {
+		return nil, errors.New("service keys or a legacy secret are required")
+	}
+	if config.Keys != nil && config.Audience == "" {
+		return nil, errors.New("audience is required to verify v2 service tokens")
+	}
+	if config.Nonces == nil {
+		config.Nonces = NewMemoryNonceStore()
+	}
+	if config.ClockSkew <= 0 {
+		config.ClockSkew = defaultClockSkew
+	}
+
+	return &Verifier{config: config}, nil
+}
+
+// Verify checks a token and, when it binds a body hash, that body matches it.
+func (v *Verifier) Verify(ctx context.Context, token string, body []byte) (*ServiceClaims, error) {
+	if !strings.HasPrefix(token, tokenVersion+".") {
+		return v.verifyLegacy(token)
+	}
+	if v.config.Keys == nil {
+		return nil, ErrUnknownKey
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims ServiceClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := v.config.Keys.keys[claims.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(key, parts[0]+"."+parts[1]))) {
+		return nil, ErrInvalidSig
+	}
+
+	now := time.Now()
+	if now.Add(v.config.ClockSkew).Unix() < claims.IssuedAt || now.Add(-v.config.ClockSkew).Unix() > claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+	if claims.Audience != v.config.Audience {
+		return nil, ErrWrongAudience
+	}
+	if claims.Issuer == "" || claims.Nonce == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.BodyHash != "" {
+		if !hmac.Equal([]byte(claims.BodyHash), []byte(HashBody(body))) {
+			return nil, ErrBodyMismatch
+		}
+	} else if v.config.RequireBodyHash {
+		return nil, ErrBodyMismatch
+	}
+
+	//checked last so a forged or expired token cannot burn a nonce
+	fresh, err := v.config.Nonces.Use(ctx, claims.Issuer+":"+claims.Nonce, time.Unix(claims.ExpiresAt, 0).Add(v.config.ClockSkew))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check nonce: %w", err)
+	}
+	if !fresh {
+		return nil, ErrReplayed
+	}
+
+	return &claims, nil
+}
+
+func (v *Verifier) verifyLegacy(token string) (*ServiceClaims, error) {
+	if v.config.LegacySecret == "" {
+		return nil, ErrInvalidToken
+	}
+
+	serviceName, err := VerifyServiceToken(token, v.config.LegacySecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceClaims{Issuer: serviceName, Legacy: true}, nil
+}
+
+func sign(key []byte, message string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
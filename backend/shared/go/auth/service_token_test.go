@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustKeySet(t *testing.T, value string, signingKeyID string) *KeySet {
+	t.Helper()
+
+	keys, err := ParseKeySet(value, signingKeyID)
+	if err != nil {
+		t.Fatalf("ParseKeySet(%q): %v", value, err)
+	}
+
+	return keys
+}
+
+func mustVerifier(t *testing.T, config VerifierConfig) *Verifier {
+	t.Helper()
+
+	verifier, err := NewVerifier(config)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	return verifier
+}
+
+// claimsAt are the claims of a token from order-service to cart-service issued at issuedAt.
+func claimsAt(issuedAt time.Time) ServiceClaims {
+	return ServiceClaims{
+		Issuer:    "order-service",
+		Audience:  "cart-service",
+		KeyID:     "k1",
+		IssuedAt:  issuedAt.Unix(),
+		ExpiresAt: issuedAt.Add(defaultTokenTTL).Unix(),
+		Nonce:     "0123456789abcdef",
+	}
+}
+
+// signClaims signs claims like GenerateServiceTokenV2 does, with secret instead of the key named by kid.
+func signClaims(t *testing.T, claims ServiceClaims, secret string) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + sign([]byte(secret), signed)
+}
+
+func TestParseKeySet(t *testing.T) {
+	keys := mustKeySet(t, " k2:new , k1:old,", "")
+	if keys.SigningKeyID() != "k2" {
+		t.Fatalf("signing key %q, want the first key k2", keys.SigningKeyID())
+	}
+	if keys := mustKeySet(t, "k2:new,k1:old", "k1"); keys.SigningKeyID() != "k1" {
+		t.Fatalf("signing key %q, want k1", keys.SigningKeyID())
+	}
+
+	for _, value := range []string{"", "k1", "k1:", ":secret"} {
+		if _, err := ParseKeySet(value, ""); err == nil {
+			t.Errorf("ParseKeySet(%q) succeeded", value)
+		}
+	}
+	if _, err := ParseKeySet("k1:old", "k2"); err == nil {
+		t.Error("a signing key outside the set was accepted")
+	}
+}
+
+func TestVerifierAcceptsSignedToken(t *testing.T) {
+	keys := mustKeySet(t, "k1:secret", "")
+	verifier := mustVerifier(t, VerifierConfig{Keys: keys, Audience: "cart-service"})
+
+	body := []byte(`{"items":[]}`)
+	token, err := GenerateServiceTokenV2("order-service", "cart-service", keys, body)
+	if err != nil {
+		t.Fatalf("GenerateServiceTokenV2: %v", err)
+	}
+
+	claims, err := verifier.Verify(context.Background(), token, body)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Issuer != "order-service" || claims.KeyID != "k1" || claims.Legacy {
+		t.Fatalf("got claims %+v", claims)
+	}
+}
+
+func TestVerifierRejects(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"items":[]}`)
+
+	for _, tc := range []struct {
+		name   string
+		config VerifierConfig // Keys and Audience default to k1:secret and cart-service
+		token  func(t *testing.T) string
+		body   []byte
+		want   error
+	}{
+		{
+			name:  "undecodable payload",
+			token: func(t *testing.T) string { return "v2.!!!.signature" },
+			want:  ErrInvalidToken,
+		},
+		{
+			name: "missing signature",
+			token: func(t *testing.T) string {
+				return strings.Join(strings.Split(signClaims(t, claimsAt(now), "secret"), ".")[:2], ".")
+			},
+			want: ErrInvalidToken,
+		},
+		{
+			name: "kid no longer in the key set",
+			token: func(t *testing.T) string {
+				claims := claimsAt(now)
+				claims.KeyID = "k0"
+				return signClaims(t, claims, "rotated-out")
+			},
+			want: ErrUnknownKey,
+		},
+		{
+			name:  "signed with another secret",
+			token: func(t *testing.T) string { return signClaims(t, claimsAt(now), "forged") },
+			want:  ErrInvalidSig,
+		},
+		{
+			name: "payload changed after signing",
+			token: func(t *testing.T) string {
+				parts := strings.Split(signClaims(t, claimsAt(now), "secret"), ".")
+				claims := claimsAt(now)
+				claims.Issuer = "payment-service"
+				payload, _ := json.Marshal(claims)
+				return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+			},
+			want: ErrInvalidSig,
+		},
+		{
+			name: "issued for another service",
+			token: func(t *testing.T) string {
+				claims := claimsAt(now)
+				claims.Audience = "seller-service"
+				return signClaims(t, claims, "secret")
+			},
+			want: ErrWrongAudience,
+		},
+		{
+			name: "issued further in the future than the clock skew",
+			token: func(t *testing.T) string {
+				return signClaims(t, claimsAt(now.Add(defaultClockSkew+5*time.Second)), "secret")
+			},
+			want: ErrTokenExpired,
+		},
+		{
+			name: "expired longer ago than the clock skew",
+			token: func(t *testing.T) string {
+				return signClaims(t, claimsAt(now.Add(-defaultTokenTTL-defaultClockSkew-5*time.Second)), "secret")
+			},
+			want: ErrTokenExpired,
+		},
+		{
+			name:   "expired with a tighter clock skew",
+			config: VerifierConfig{ClockSkew: time.Second},
+			token: func(t *testing.T) string {
+				return signClaims(t, claimsAt(now.Add(-defaultTokenTTL-10*time.Second)), "secret")
+			},
+			want: ErrTokenExpired,
+		},
+		{
+			name: "missing nonce",
+			token: func(t *testing.T) string {
+				claims := claimsAt(now)
+				claims.Nonce = ""
+				return signClaims(t, claims, "secret")
+			},
+			want: ErrInvalidToken,
+		},
+		{
+			name: "body differs from the bound hash",
+			token: func(t *testing.T) string {
+				claims := claimsAt(now)
+				claims.BodyHash = HashBody(body)
+				return signClaims(t, claims, "secret")
+			},
+			body: []byte(`{"items":["extra"]}`),
+			want: ErrBodyMismatch,
+		},
+		{
+			name:   "body hash required but not bound",
+			config: VerifierConfig{RequireBodyHash: true},
+			token:  func(t *testing.T) string { return signClaims(t, claimsAt(now), "secret") },
+			body:   body,
+			want:   ErrBodyMismatch,
+		},
+		{
+			name:  "legacy token while legacy tokens are not allowed",
+			token: func(t *testing.T) string { return GenerateServiceToken("order-service", "legacy") },
+			want:  ErrInvalidToken,
+		},
+		{
+			name:   "v2 token on a verifier that only knows the legacy secret",
+			config: VerifierConfig{LegacySecret: "legacy"},
+			token:  func(t *testing.T) string { return signClaims(t, claimsAt(now), "secret") },
+			want:   ErrUnknownKey,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			if config.LegacySecret == "" {
+				config.Keys = mustKeySet(t, "k1:secret", "")
+				config.Audience = "cart-service"
+			}
+			verifier := mustVerifier(t, config)
+
+			claims, err := verifier.Verify(context.Background(), tc.token(t), tc.body)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got claims %+v and error %v, want %v", claims, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifierClockSkew(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name     string
+		issuedAt time.Time
+	}{
+		{"issued slightly in the future", now.Add(defaultClockSkew - 5*time.Second)},
+		{"expired slightly in the past", now.Add(-defaultTokenTTL - defaultClockSkew + 5*time.Second)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := mustVerifier(t, VerifierConfig{Keys: mustKeySet(t, "k1:secret", ""), Audience: "cart-service"})
+
+			if _, err := verifier.Verify(context.Background(), signClaims(t, claimsAt(tc.issuedAt), "secret"), nil); err != nil {
+				t.Fatalf("got %v within the clock skew", err)
+			}
+		})
+	}
+}
+
+func TestVerifierKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKeys := mustKeySet(t, "k1:old", "")
+	//during the rotation callers sign with k2 and both keys are accepted
+	rotating := mustKeySet(t, "k2:new,k1:old", "")
+	rotated := mustKeySet(t, "k2:new", "")
+
+	oldToken, err := GenerateServiceTokenV2("order-service", "cart-service", oldKeys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := GenerateServiceTokenV2("order-service", "cart-service", rotating, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := mustVerifier(t, VerifierConfig{Keys: rotating, Audience: "cart-service"})
+	for name, token := range map[string]string{"old key": oldToken, "new key": newToken} {
+		if _, err := verifier.Verify(ctx, token, nil); err != nil {
+			t.Fatalf("token with the %s rejected during the rotation: %v", name, err)
+		}
+	}
+
+	verifier = mustVerifier(t, VerifierConfig{Keys: rotated, Audience: "cart-service"})
+	if _, err := verifier.Verify(ctx, oldToken, nil); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("got %v for a token with the removed key, want ErrUnknownKey", err)
+	}
+}
+
+func TestVerifierNonceReuse(t *testing.T) {
+	ctx := context.Background()
+	keys := mustKeySet(t, "k1:secret", "")
+	verifier := mustVerifier(t, VerifierConfig{Keys: keys, Audience: "cart-service"})
+
+	body := []byte(`{"items":[]}`)
+	token, err := GenerateServiceTokenV2("order-service", "cart-service", keys, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a rejected request must not use up the nonce of the genuine one
+	if _, err := verifier.Verify(ctx, token, []byte("tampered")); !errors.Is(err, ErrBodyMismatch) {
+		t.Fatalf("got %v for a tampered body", err)
+	}
+	if _, err := verifier.Verify(ctx, token, body); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, token, body); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("got %v for a replayed token, want ErrReplayed", err)
+	}
+
+	//nonces are per issuer, another service may draw the same one
+	claims := claimsAt(time.Now())
+	claims.Issuer = "payment-service"
+	if _, err := verifier.Verify(ctx, signClaims(t, claims, "secret"), nil); err != nil {
+		t.Fatalf("first use by another issuer: %v", err)
+	}
+}
+
+func TestVerifierLegacyTokens(t *testing.T) {
+	ctx := context.Background()
+	verifier := mustVerifier(t, VerifierConfig{Keys: mustKeySet(t, "k1:secret", ""), Audience: "cart-service", LegacySecret: "legacy"})
+
+	claims, err := verifier.Verify(ctx, GenerateServiceToken("order-service", "legacy"), nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Issuer != "order-service" || !claims.Legacy {
+		t.Fatalf("got claims %+v", claims)
+	}
+
+	if _, err := verifier.Verify(ctx, GenerateServiceToken("order-service", "other"), nil); err == nil {
+		t.Fatal("legacy token with another secret accepted")
+	}
+}
+
+func TestServiceAuthOptionsVerifier(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options ServiceAuthOptions
+		legacy  bool // legacy tokens are accepted
+		wantErr string
+	}{
+		{"v2 only", ServiceAuthOptions{Keys: "k1:secret", LegacySecret: "legacy"}, false, ""},
+		{"legacy allowed", ServiceAuthOptions{Keys: "k1:secret", LegacySecret: "legacy", AllowLegacy: true}, true, ""},
+		{"legacy allowed without a secret", ServiceAuthOptions{Keys: "k1:secret", AllowLegacy: true}, false, "needs SERVICE_SECRET"},
+		{"malformed keys", ServiceAuthOptions{Keys: "k1"}, false, "invalid SERVICE_KEYS"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier, err := tc.options.Verifier("cart-service")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifier: %v", err)
+			}
+
+			_, err = verifier.Verify(context.Background(), GenerateServiceToken("order-service", "legacy"), nil)
+			if accepted := err == nil; accepted != tc.legacy {
+				t.Fatalf("legacy token accepted %v, want %v: %v", accepted, tc.legacy, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	GatewayAuthHeader = "X-Gateway-Auth"
+	gatewayName       = "apiGateway"
+)
+
+// Principal is the verified end user behind a request.
+type Principal struct {
+	UserID   string
+	Roles    []string
+	SellerID string
+	Phone    string
+	// AuthTime is when the user last entered their credentials, zero when the token does not say.
+	AuthTime time.Time
+	// Source is "jwt" for a verified user token or "gateway" for headers signed by the api gateway.
+	Source string
+}
+
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userClaims accepts the claim names our issuers use: sub or userId, roles or role, sellerId or seller_id.
+type userClaims struct {
+	jwt.RegisteredClaims
+	UserID        string   `json:"userId"`
+	Role          string   `json:"role"`
+	Roles         []string `json:"roles"`
+	SellerID      string   `json:"sellerId"`
+	SellerIDSnake string   `json:"seller_id"`
+	Phone         string   `json:"phoneNumberOrEmail"`
+	AuthTime      int64    `json:"auth_time"`
+	TokenType     string   `json:"type"`
+}
+
+type UserTokenConfig struct {
+	Keys     KeySource
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+}
+
+// UserTokenVerifier verifies RS256 and ES256 user tokens and checks exp, iss and aud.
+type UserTokenVerifier struct {
+	config UserTokenConfig
+	parser *jwt.Parser
+}
+
+func NewUserTokenVerifier(config UserTokenConfig) (*UserTokenVerifier, error) {
+	if config.Keys == nil {
+		return nil, errors.New("a key source is required to verify user tokens")
+	}
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("issuer and audience are required to verify user tokens")
+	}
+	if config.Leeway <= 0 {
+		config.Leeway = defaultClockSkew
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithAudience(config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	)
+
+	return &UserTokenVerifier{config: config, parser: parser}, nil
+}
+
+func (v *UserTokenVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	var claims userClaims
+	_, err := v.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.config.Keys.PublicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid user token: %w", err)
+	}
+
+	//refresh tokens are only good for getting a new access token
+	if claims.TokenType != "" && claims.TokenType != "accessToken" {
+		return nil, errors.New("invalid user token: not an access token")
+	}
+
+	principal := &Principal{
+		UserID:   claims.Subject,
+		Roles:    claims.Roles,
+		SellerID: claims.SellerID,
+		Phone:    claims.Phone,
+		Source:   "jwt",
+	}
+	if principal.UserID == "" {
+		principal.UserID = claims.UserID
+	}
+	if principal.UserID == "" {
+		return nil, errors.New("invalid user token: no subject")
+	}
+	if claims.Role != "" && !slices.Contains(principal.Roles, claims.Role) {
+		principal.Roles = append(principal.Roles, claims.Role)
+	}
+	if principal.SellerID == "" {
+		principal.SellerID = claims.SellerIDSnake
+	}
+	if claims.AuthTime > 0 {
+		principal.AuthTime = time.Unix(claims.AuthTime, 0)
+	}
+
+	return principal, nil
+}
+
+// VerifyGatewayToken checks the apiGateway:timestamp:signature token the api gateway adds to proxied requests.
+// The timestamp may be off by at most the token TTL plus the clock skew, in either direction.
+func VerifyGatewayToken(token string, secret string) error {
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 || parts[0] != gatewayName {
+		return ErrInvalidToken
+	}
+
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	//a token from the future would otherwise stay valid until its timestamp is reached
+	if age := time.Since(time.Unix(timestamp, 0)).Abs(); age > defaultTokenTTL+defaultClockSkew {
+		return ErrTokenExpired
+	}
+
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(parts[0] + ":" + parts[1]))
+	if !hmac.Equal([]byte(parts[2]), []byte(hex.EncodeToString(h.Sum(nil)))) {
+		return ErrInvalidSig
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+// Command kafka-replay reads a range of a topic and re-publishes the matching messages to another topic.
+//
+//	kafka-replay -topic order_event -from-time 2026-01-01T00:00:00Z -event-types order.created -dry-run
+//	kafka-replay -topic order_event -partitions 0 -from-offset 1200 -keys <orderId> -to-topic order_event.replay
+//
+// Routing through handlers needs the handlers, see the replay command of the consuming service.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka/replay"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := replay.Main(ctx, "kafka-replay", os.Args[1:], nil, os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatal("Replay failed: ", err)
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// DefaultSecretsDir is where Docker and Kubernetes mount secret files.
+const DefaultSecretsDir = "/run/secrets"
+
+// Source is where the value of a field came from.
+type Source string
+
+const (
+	SourceEnv     Source = "env"
+	SourceEnvFile Source = "env_file" // the file named by <KEY>_FILE
+	SourceSecret  Source = "secret"   // <SecretsDir>/<KEY>
+	SourceFile    Source = "file"     // one of the config files
+	SourceDefault Source = "default"
+	SourceUnset   Source = "unset"
+)
+
+type Options struct {
+	Files      []string // YAML, JSON or .env files by extension, later ones win, CONFIG_FILE adds to them
+	SecretsDir string   // default DefaultSecretsDir
+	Quiet      bool     // skip logging the effective config
+}
+
+// Field is a loaded field of the config, see Load.
+type Field struct {
+	Key    string
+	Value  string
+	Secret bool
+	Source Source
+}
+
+var validate = newValidator()
+
+// validation errors name the env key rather than the Go field
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		if tag, ok := field.Tag.Lookup("env"); ok {
+			return strings.Split(tag, ",")[0]
+		}
+		return field.Name
+	})
+
+	return v
+}
+
+// Load fills the struct target points to. Fields are read from the key in their env tag:
+//
+//	DB_PASSWORD string        `env:"DB_PASSWORD,required,secret"`
+//	DB_PORT     int           `env:"DB_PORT" default:"5432" validate:"min=1,max=65535"`
+//	TIMEOUT     time.Duration `env:"TIMEOUT" default:"10s"`
+//
+// The first of these wins: the environment, the file named by <KEY>_FILE, the Docker secret
+// <SecretsDir>/<KEY> (or its lower case name), the config files and the default tag. Nested structs
+// are walked. Values that did not come from the environment are exported to it, so packages reading
+// os.Getenv see the same config. Every missing or malformed value is reported at once, then the
+// validate tags are checked. On success the effective config is logged with secrets redacted.
+func Load(target any, options Options) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config target must be a pointer to a struct, got %T", target)
+	}
+
+	if options.SecretsDir == "" {
+		options.SecretsDir = DefaultSecretsDir
+	}
+	files := options.Files
+	for _, path := range strings.Split(os.Getenv("CONFIG_FILE"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			files = append(files, path)
+		}
+	}
+
+	fileValues := map[string]string{}
+	for _, path := range files {
+		values, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		for key, v := range values {
+			fileValues[strings.ToUpper(key)] = v
+		}
+	}
+
+	loader := &loader{options: options, fileValues: fileValues}
+	loader.walk(value.Elem())
+
+	//keys of the files nothing asked for are still exported, e.g. for tools run next to the service
+	for key, v := range fileValues {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, v)
+		}
+	}
+
+	if len(loader.missing) > 0 {
+		loader.errs = append([]error{fmt.Errorf("missing required configuration: %s", strings.Join(loader.missing, ", "))}, loader.errs...)
+	}
+	if err := errors.Join(loader.errs...); err != nil {
+		return err
+	}
+
+	if err := validate.Struct(target); err != nil {
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			errs := make([]error, 0, len(validationErrs))
+			for _, fieldErr := range validationErrs {
+				errs = append(errs, fmt.Errorf("invalid %s: failed %q validation", fieldErr.Field(), fieldErr.Tag()))
+			}
+			return errors.Join(errs...)
+		}
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if !options.Quiet {
+		Log(loader.fields)
+	}
+
+	return nil
+}
+
+// Log writes the effective config, secrets and anything that looks like one are redacted.
+func Log(fields []Field) {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		values[field.Key] = Redacted(field) + " (" + string(field.Source) + ")"
+	}
+
+	logging.L().Info("configuration loaded", zap.Any("config", values))
+}
+
+// Redacted returns the value of field as it may be logged.
+func Redacted(field Field) string {
+	if field.Secret && field.Value != "" {
+		return "[REDACTED]"
+	}
+
+	return logging.Redact(field.Key, field.Value)
+}
+
+type loader struct {
+	options    Options
+	fileValues map[string]string
+	fields     []Field
+	missing    []string
+	errs       []error
+}
+
+func (l *loader) walk(value reflect.Value) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		structField := valueType.Field(i)
+		fieldValue := value.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		tag, ok := structField.Tag.Lookup("env")
+		if !ok {
+			if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != reflect.TypeOf(time.Time{}) {
+				l.walk(fieldValue)
+			}
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		key := options[0]
+		field := Field{Key: key}
+		required := false
+		for _, option := range options[1:] {
+			switch option {
+			case "required":
+				required = true
+			case "secret":
+				field.Secret = true
+			default:
+				l.errs = append(l.errs, fmt.Errorf("unknown option %q in env tag of %s", option, structField.Name))
+			}
+		}
+
+		raw, source, err := l.lookup(key)
+		if err != nil {
+			l.errs = append(l.errs, err)
+			continue
+		}
+		if source == SourceUnset {
+			if def, ok := structField.Tag.Lookup("default"); ok {
+				raw, source = def, SourceDefault
+			}
+		}
+		if source == SourceUnset && required {
+			l.missing = append(l.missing, key)
+			continue
+		}
+		field.Value, field.Source = raw, source
+		l.fields = append(l.fields, field)
+
+		if source == SourceUnset {
+			continue
+		}
+		if err := setValue(fieldValue, raw); err != nil {
+			//parse errors quote the value
+			if field.Secret {
+				err = fmt.Errorf("cannot parse as %s", fieldValue.Type())
+			}
+			l.errs = append(l.errs, fmt.Errorf("invalid %s: %w", key, err))
+			continue
+		}
+		if source != SourceEnv && source != SourceDefault {
+			os.Setenv(key, raw)
+		}
+	}
+}
+
+func (l *loader) lookup(key string) (string, Source, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, SourceEnv, nil
+	}
+
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", SourceUnset, fmt.Errorf("failed to read %s_FILE: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), SourceEnvFile, nil
+	}
+
+	for _, name := range []string{key, strings.ToLower(key)} {
+		data, err := os.ReadFile(filepath.Join(l.options.SecretsDir, name))
+		if err == nil {
+			return strings.TrimRight(string(data), "\r\n"), SourceSecret, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", SourceUnset, fmt.Errorf("failed to read secret %s: %w", name, err)
+		}
+	}
+
+	if value, ok := l.fileValues[key]; ok && value != "" {
+		return value, SourceFile, nil
+	}
+
+	return "", SourceUnset, nil
+}
+
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", value.Type())
+		}
+		parts := []string{}
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		value.Set(reflect.ValueOf(parts).Convert(value.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testPool struct {
+	MaxConns int `env:"CONFIG_TEST_MAX_CONNS" default:"25" validate:"min=1"`
+}
+
+type testConfig struct {
+	Host     string        `env:"CONFIG_TEST_HOST" default:"localhost"`
+	Password string        `env:"CONFIG_TEST_PASSWORD,required,secret"`
+	Name     string        `env:"CONFIG_TEST_NAME,required"`
+	Timeout  time.Duration `env:"CONFIG_TEST_TIMEOUT" default:"10s"`
+	Brokers  []string      `env:"CONFIG_TEST_BROKERS"`
+
+	Pool testPool
+}
+
+var testKeys = []string{
+	"CONFIG_TEST_HOST", "CONFIG_TEST_PASSWORD", "CONFIG_TEST_NAME", "CONFIG_TEST_TIMEOUT",
+	"CONFIG_TEST_BROKERS", "CONFIG_TEST_MAX_CONNS", "CONFIG_FILE",
+}
+
+// clearEnv empties the test keys and their _FILE keys, t.Setenv restores them, also after Load
+// exported values. An empty variable counts as unset.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range testKeys {
+		t.Setenv(key, "")
+		t.Setenv(key+"_FILE", "")
+	}
+}
+
+func writeFile(t *testing.T, name string, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func quiet(t *testing.T) Options {
+	return Options{SecretsDir: t.TempDir(), Quiet: true}
+}
+
+func TestLoadMissingRequired(t *testing.T) {
+	clearEnv(t)
+
+	var config testConfig
+	err := Load(&config, quiet(t))
+	if err == nil || !strings.Contains(err.Error(), "missing required configuration: CONFIG_TEST_PASSWORD, CONFIG_TEST_NAME") {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestLoadDefaultsAndTypes(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_TEST_PASSWORD", "pw")
+	t.Setenv("CONFIG_TEST_NAME", "orders")
+	t.Setenv("CONFIG_TEST_TIMEOUT", "3s")
+	t.Setenv("CONFIG_TEST_BROKERS", "a:9092, b:9092,")
+
+	var config testConfig
+	if err := Load(&config, quiet(t)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if config.Host != "localhost" || config.Timeout != 3*time.Second || config.Pool.MaxConns != 25 {
+		t.Fatalf("got %+v", config)
+	}
+	if len(config.Brokers) != 2 || config.Brokers[0] != "a:9092" || config.Brokers[1] != "b:9092" {
+		t.Fatalf("got brokers %q", config.Brokers)
+	}
+}
+
+func TestLoadInvalidValues(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		key   string
+		value string
+		want  string
+	}{
+		{"unparsable duration", "CONFIG_TEST_TIMEOUT", "soon", "invalid CONFIG_TEST_TIMEOUT"},
+		{"failed validation of a nested field", "CONFIG_TEST_MAX_CONNS", "0", `invalid CONFIG_TEST_MAX_CONNS: failed "min" validation`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("CONFIG_TEST_PASSWORD", "pw")
+			t.Setenv("CONFIG_TEST_NAME", "orders")
+			t.Setenv(tc.key, tc.value)
+
+			var config testConfig
+			err := Load(&config, quiet(t))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("got error %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	//each case sets the sources from the lowest one up to the one that has to win
+	for _, tc := range []struct {
+		name string
+		upTo Source
+		want string
+	}{
+		{"default", SourceDefault, "from-default"},
+		{"config file over default", SourceFile, "from-config-file"},
+		{"docker secret over config file", SourceSecret, "from-secret"},
+		{"_FILE over docker secret", SourceEnvFile, "from-env-file"},
+		{"env over everything", SourceEnv, "from-env"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("CONFIG_TEST_PASSWORD", "pw")
+			options := quiet(t)
+
+			levels := []Source{SourceDefault, SourceFile, SourceSecret, SourceEnvFile, SourceEnv}
+			for _, level := range levels {
+				switch level {
+				case SourceFile:
+					t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "config_test:\n  name: from-config-file\n"))
+				case SourceSecret:
+					if err := os.WriteFile(filepath.Join(options.SecretsDir, "config_test_name"), []byte("from-secret\n"), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				case SourceEnvFile:
+					t.Setenv("CONFIG_TEST_NAME_FILE", writeFile(t, "name", "from-env-file\n"))
+				case SourceEnv:
+					t.Setenv("CONFIG_TEST_NAME", "from-env")
+				}
+				if level == tc.upTo {
+					break
+				}
+			}
+
+			var config struct {
+				Name string `env:"CONFIG_TEST_NAME" default:"from-default"`
+			}
+			if err := Load(&config, options); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if config.Name != tc.want {
+				t.Fatalf("got %q, want %q", config.Name, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileSecret(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_TEST_NAME", "orders")
+	t.Setenv("CONFIG_TEST_PASSWORD_FILE", writeFile(t, "password", "s3cret\r\n"))
+
+	var config testConfig
+	if err := Load(&config, quiet(t)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if config.Password != "s3cret" {
+		t.Fatalf("got password %q, the trailing newline has to be trimmed", config.Password)
+	}
+	//values from files are exported for whatever reads the environment
+	if got := os.Getenv("CONFIG_TEST_PASSWORD"); got != "s3cret" {
+		t.Fatalf("exported %q", got)
+	}
+
+	t.Setenv("CONFIG_TEST_PASSWORD", "")
+	t.Setenv("CONFIG_TEST_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := Load(&config, quiet(t)); err == nil || !strings.Contains(err.Error(), "failed to read CONFIG_TEST_PASSWORD_FILE") {
+		t.Fatalf("got error %v for a missing _FILE", err)
+	}
+}
+
+func TestLoadLogsSecretsRedacted(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_TEST_PASSWORD", "hunter2")
+	t.Setenv("CONFIG_TEST_NAME", "orders")
+	t.Setenv("CONFIG_TEST_HOST", "postgres://app:dbpass@db:5432/orders")
+
+	core, logs := observer.New(zap.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	var config testConfig
+	if err := Load(&config, Options{SecretsDir: t.TempDir()}); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	entries := logs.FilterMessage("configuration loaded").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d config log entries", len(entries))
+	}
+	values, ok := entries[0].ContextMap()["config"].(map[string]string)
+	if !ok {
+		t.Fatalf("got config field %#v", entries[0].ContextMap()["config"])
+	}
+
+	for key, want := range map[string]string{
+		"CONFIG_TEST_PASSWORD": "[REDACTED] (env)",
+		"CONFIG_TEST_NAME":     "orders (env)",
+		"CONFIG_TEST_HOST":     "postgres://app:[REDACTED]@db:5432/orders (env)",
+		"CONFIG_TEST_TIMEOUT":  "10s (default)",
+	} {
+		if values[key] != want {
+			t.Errorf("logged %s as %q, want %q", key, values[key], want)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	for _, tc := range []struct {
+		field Field
+		want  string
+	}{
+		{Field{Key: "DB_PASSWORD", Value: "pw", Secret: true}, "[REDACTED]"},
+		{Field{Key: "SERVICE_KEYS", Value: "k1:abc"}, "[REDACTED]"}, //the key name alone marks it
+		{Field{Key: "CUSTOM", Value: "x", Secret: true}, "[REDACTED]"},
+		{Field{Key: "CUSTOM", Value: "", Secret: true}, ""}, //shows that it is unset
+		{Field{Key: "DB_HOST", Value: "localhost"}, "localhost"},
+	} {
+		if got := Redacted(tc.field); got != tc.want {
+			t.Errorf("Redacted(%+v) = %q, want %q", tc.field, got, tc.want)
+		}
+	}
+}
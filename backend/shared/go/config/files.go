@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lpernett/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile reads a config file into flat keys. Nested objects are joined with "_", so
+// "db: {host: x}" fills DB_HOST, and lists become comma separated values.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".json":
+		err = json.Unmarshal(data, &document)
+	case ".env":
+		var values map[string]string
+		values, err = godotenv.Unmarshal(string(data))
+		if err == nil {
+			return values, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, use .yaml, .yml, .json or .env", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", document, values)
+
+	return values, nil
+}
+
+func flatten(prefix string, value any, values map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flatten(key, nested, values)
+		}
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		values[prefix] = strings.Join(parts, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+}
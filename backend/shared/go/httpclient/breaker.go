@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the circuit, default 5
+	OpenTimeout      time.Duration // how long calls are rejected before a probe is let through, default 30s
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+
+	return c
+}
+
+// Breaker stops calls to a target that keeps failing so callers fail fast instead of piling up
+// behind timeouts. After OpenTimeout a single probe decides whether it closes again.
+type Breaker struct {
+	target string
+	config BreakerConfig
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(target string, config BreakerConfig) *Breaker {
+	return &Breaker{target: target, config: config.withDefaults()}
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*Breaker{}
+)
+
+// breakerFor shares one breaker between all clients of target, the first config wins.
+func breakerFor(target string, config BreakerConfig) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breaker, ok := breakers[target]
+	if !ok {
+		breaker = NewBreaker(target, config)
+		breakers[target] = breaker
+	}
+
+	return breaker
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow returns ErrCircuitOpen while the circuit is open or a probe is already in flight.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.config.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		if b.state != StateClosed {
+			b.setState(StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = time.Now()
+		if b.state != StateOpen {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+// Release gives back an allowed call without an outcome, e.g. when the caller gave up, so a
+// half-open circuit lets the next probe through.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) setState(state State) {
+	logging.L().Warn("circuit breaker state changed",
+		zap.String("target", b.target),
+		zap.String("from", b.state.String()),
+		zap.String("to", state.String()),
+		zap.Int("failures", b.failures),
+	)
+	b.state = state
+}
@@ -0,0 +1,333 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/metrics"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	//how much of a non problem error body is kept on StatusError
+	errorBodyLimit = 512
+)
+
+type ClientConfig struct {
+	Target           string // downstream service name: token audience, metrics label and breaker key
+	BaseURL          string
+	Timeout          time.Duration // per attempt, default 10s. A sooner context deadline wins
+	Signer           *auth.Signer  // nil sends unsigned requests
+	MaxRetries       int           // retries after the first attempt, default 2, negative disables retries
+	RetryBaseDelay   time.Duration // default 100ms
+	RetryMaxDelay    time.Duration // default 2s
+	MaxResponseBytes int64         // default 4MB
+	Breaker          BreakerConfig
+	Transport        http.RoundTripper // default http.DefaultTransport
+}
+
+// Client calls one downstream service. Every attempt is signed and carries the trace headers of
+// the context, idempotent requests are retried with jittered backoff and a circuit breaker shared
+// by all clients of the target rejects calls while it keeps failing.
+type Client struct {
+	config     ClientConfig
+	httpClient *http.Client
+	breaker    *Breaker
+}
+
+func New(config ClientConfig) *Client {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 2
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = 100 * time.Millisecond
+	}
+	if config.RetryMaxDelay <= 0 {
+		config.RetryMaxDelay = 2 * time.Second
+	}
+	if config.MaxResponseBytes <= 0 {
+		config.MaxResponseBytes = 4 << 20
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+
+	return &Client{
+		config:     config,
+		httpClient: metrics.InstrumentClient(config.Target, &http.Client{Transport: config.Transport}),
+		breaker:    breakerFor(config.Target, config.Breaker),
+	}
+}
+
+func (c *Client) BaseURL() string {
+	return c.config.BaseURL
+}
+
+type Request struct {
+	Method string
+	Path   string
+	Body   any // encoded as JSON, []byte is sent as is
+	Header http.Header
+
+	//POST and PATCH are only retried when the caller marks them safe to repeat, which an
+	//Idempotency-Key header does as well
+	Idempotent bool
+}
+
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	return c.Do(ctx, Request{Method: http.MethodGet, Path: path}, out)
+}
+
+func (c *Client) Post(ctx context.Context, path string, body any, out any) error {
+	return c.Do(ctx, Request{Method: http.MethodPost, Path: path, Body: body}, out)
+}
+
+func (c *Client) Delete(ctx context.Context, path string, out any) error {
+	return c.Do(ctx, Request{Method: http.MethodDelete, Path: path}, out)
+}
+
+// Do sends req and decodes a 2xx JSON response into out, which may be nil. Other responses are
+// returned as *StatusError, rejected calls as ErrCircuitOpen.
+func (c *Client) Do(ctx context.Context, req Request, out any) error {
+	body, err := encodeBody(req.Body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request body: %w", c.config.Target, err)
+	}
+
+	retries := 0
+	if c.retryable(req) {
+		retries = c.config.MaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		data, err := c.attempt(ctx, req, body)
+		if err == nil {
+			return decodeBody(data, out)
+		}
+		//a retry the breaker rejected says less about what went wrong than the attempt before
+		if lastErr != nil && errors.Is(err, ErrCircuitOpen) {
+			return lastErr
+		}
+		lastErr = err
+
+		retryAfter, retry := c.shouldRetry(ctx, err)
+		if !retry || attempt >= retries {
+			return err
+		}
+
+		delay := c.backoff(attempt, retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return err
+		}
+
+		logging.FromContext(ctx).Warn("retrying service call",
+			zap.String("target", c.config.Target),
+			zap.String("method", req.Method),
+			zap.String("path", req.Path),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends req once through the breaker and returns the body of a 2xx response.
+func (c *Client) attempt(ctx context.Context, req Request, body []byte) ([]byte, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%s %s %s: %w", c.config.Target, req.Method, req.Path, err)
+	}
+
+	data, err := c.send(ctx, req, body)
+
+	//the target being down or overloaded counts against the breaker, requests it rejected do
+	//not, and neither does our own caller giving up
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		c.breaker.Record(true)
+	case errors.As(err, &statusErr):
+		c.breaker.Record(statusErr.StatusCode < 500)
+	case ctx.Err() != nil:
+		c.breaker.Release()
+	default:
+		c.breaker.Record(false)
+	}
+
+	return data, err
+}
+
+func (c *Client) send(ctx context.Context, req Request, body []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, c.config.BaseURL+req.Path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", c.config.Target, err)
+	}
+
+	for key, values := range req.Header {
+		httpReq.Header[key] = values
+	}
+	if body != nil && httpReq.Header.Get("Content-Type") == "" {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	tracing.InjectHTTP(ctx, httpReq.Header)
+
+	//v2 tokens carry a nonce that is burned on first use, so every attempt is signed anew
+	if c.config.Signer != nil {
+		if err := c.config.Signer.SignRequest(httpReq, c.config.Target, body); err != nil {
+			return nil, fmt.Errorf("failed to sign %s request: %w", c.config.Target, err)
+		}
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s %s %s: %w", c.config.Target, req.Method, req.Path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s response: %w", c.config.Target, err)
+	}
+	if int64(len(data)) > c.config.MaxResponseBytes {
+		return nil, fmt.Errorf("%s %s %s: %w (%d bytes)", c.config.Target, req.Method, req.Path, ErrResponseTooLarge, c.config.MaxResponseBytes)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, c.statusError(req, resp, data)
+	}
+
+	return data, nil
+}
+
+func (c *Client) statusError(req Request, resp *http.Response, data []byte) *StatusError {
+	statusErr := &StatusError{
+		Target:     c.config.Target,
+		Method:     req.Method,
+		Path:       req.Path,
+		StatusCode: resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == apperror.ProblemContentType {
+		var problem apperror.Problem
+		if err := json.Unmarshal(data, &problem); err == nil {
+			statusErr.Problem = &problem
+			return statusErr
+		}
+	}
+
+	if len(data) > errorBodyLimit {
+		data = data[:errorBodyLimit]
+	}
+	statusErr.Body = string(data)
+	return statusErr
+}
+
+func (c *Client) retryable(req Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Idempotent || req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// shouldRetry reports whether err is worth another attempt and how long the target asked us to
+// wait, if it did.
+func (c *Client) shouldRetry(ctx context.Context, err error) (time.Duration, bool) {
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrResponseTooLarge) {
+		return 0, false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.retryAfter, statusErr.Retryable()
+	}
+
+	//everything else failed on the way: refused or reset connections and attempt timeouts
+	return 0, true
+}
+
+// backoff is exponential with full jitter, a Retry-After from the target takes precedence.
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, c.config.RetryMaxDelay)
+	}
+
+	ceiling := min(c.config.RetryBaseDelay<<attempt, c.config.RetryMaxDelay)
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
+
+func encodeBody(body any) ([]byte, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return b, nil
+	default:
+		return json.Marshal(b)
+	}
+}
+
+func decodeBody(data []byte, out any) error {
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
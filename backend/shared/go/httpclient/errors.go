@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+)
+
+var (
+	ErrCircuitOpen      = errors.New("circuit breaker is open")
+	ErrResponseTooLarge = errors.New("response body exceeds the size limit")
+)
+
+// StatusError is a non 2xx response. Problem is set when the target answered with problem
+// details, Body holds the start of any other error body.
+type StatusError struct {
+	Target     string
+	Method     string
+	Path       string
+	StatusCode int
+	Problem    *apperror.Problem
+	Body       string
+
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	detail := e.Body
+	if e.Problem != nil {
+		detail = e.Problem.Detail
+	}
+
+	return fmt.Sprintf("%s %s %s returned %d: %s", e.Target, e.Method, e.Path, e.StatusCode, detail)
+}
+
+// Retryable reports whether the same request may succeed later: the target was overloaded,
+// restarting or did not answer in time.
+func (e *StatusError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// IsStatus reports whether err is a StatusError with status code.
+func IsStatus(err error, code int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == code
+}
+
+// AppError maps a failed downstream call onto the error this service answers with: client errors
+// of the target keep their code, anything else means the target is unavailable to us.
+func AppError(err error, message string) *apperror.Error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			return apperror.Wrap(err, apperror.CodeNotFound, message)
+		case http.StatusConflict:
+			return apperror.Wrap(err, apperror.CodeConflict, message)
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			return apperror.Wrap(err, apperror.CodeInvalidArgument, message)
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apperror.Wrap(err, apperror.CodeTimeout, message)
+	}
+
+	return apperror.Wrap(err, apperror.CodeUnavailable, message)
+}
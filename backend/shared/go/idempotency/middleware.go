@@ -0,0 +1,284 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	//matches the widest idempotency_key column of the services
+	maxKeyLength = 100
+)
+
+type contextKey string
+
+const keyContextKey contextKey = "idempotencyKey"
+
+type Config struct {
+	TTL          time.Duration // how long a response is replayed, default 24h
+	Lease        time.Duration // how long an in-flight request holds its key, renewed while it runs, default 1m
+	MaxBodyBytes int64         // default 1MB
+}
+
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.Lease <= 0 {
+		c.Lease = time.Minute
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+
+	return c
+}
+
+// KeyFromContext returns the Idempotency-Key of the request, empty when it had none.
+func KeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyContextKey).(string)
+	return key
+}
+
+// Middleware honours the Idempotency-Key header on POST and PATCH. The first request with a key
+// runs and its response is stored, duplicates get the stored response replayed. Reusing a key for
+// a different request is a 422, reusing it while the first one is still running a 409. Server
+// errors are not stored so the client can retry them. Keys are scoped to the route and the
+// authenticated caller, so it has to run after authentication. Requests without a caller are served
+// without idempotency, their keys would be shared by everybody.
+func Middleware(store KeyStore, config Config) func(http.Handler) http.Handler {
+	config = config.withDefaults()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(KeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !validKey(key) {
+				apperror.Write(w, r, apperror.InvalidArgument("Idempotency-Key must be 1 to 100 printable ASCII characters"))
+				return
+			}
+			scope, ok := scopeOf(r)
+			if !ok {
+				logging.FromContext(r.Context()).Warn("Idempotency-Key ignored, the route does not authenticate its caller", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.MaxBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					apperror.Write(w, r, apperror.New(apperror.CodeTooLarge, ""))
+					return
+				}
+				apperror.Write(w, r, apperror.Wrap(err, apperror.CodeInvalidArgument, "failed to read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			token, existing, err := store.Claim(ctx, scope, key, fingerprint(r, body), config.Lease)
+			if err != nil {
+				apperror.Write(w, r, err)
+				return
+			}
+			if token == "" {
+				replay(w, r, existing, fingerprint(r, body))
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				releaseCtx, cancel := storeContext(ctx)
+				defer cancel()
+				if err := store.Release(releaseCtx, scope, key, token); err != nil {
+					logging.FromContext(ctx).Error("failed to release idempotency key", zap.String("scope", scope), zap.Error(err))
+				}
+			}()
+
+			stopExtending := extendLease(ctx, store, scope, key, token, config.Lease)
+			defer stopExtending() //a panicking handler stops it too
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(ctx, keyContextKey, key)))
+			stopExtending()
+
+			if recorder.status >= 500 {
+				return
+			}
+			storeCtx, cancel := storeContext(ctx)
+			defer cancel()
+			if err := store.Complete(storeCtx, scope, key, token, recorder.status, storedHeader(recorder.Header()), recorder.body.Bytes(), config.TTL); err != nil {
+				logging.FromContext(ctx).Error("failed to store idempotent response", zap.String("scope", scope), zap.Error(err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// storeContext bounds a write of the outcome, which has to happen even when the client went away.
+func storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+}
+
+// extendLease renews the claim every third of the lease until the returned stop is called, so a
+// slow handler keeps its key and a duplicate cannot run alongside it. Stop may be called repeatedly.
+func extendLease(ctx context.Context, store KeyStore, scope string, key string, token string, lease time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			extendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lease/3)
+			err := store.Extend(extendCtx, scope, key, token, lease)
+			cancel()
+			if errors.Is(err, ErrClaimLost) {
+				logging.FromContext(ctx).Warn("idempotency key was taken over while its request was running", zap.String("scope", scope))
+				return
+			}
+			if err != nil {
+				logging.FromContext(ctx).Error("failed to extend idempotency key", zap.String("scope", scope), zap.Error(err))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, existing *Record, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		apperror.Write(w, r, apperror.New(apperror.CodeUnprocessable, "Idempotency-Key was already used for a different request"))
+		return
+	}
+	if existing.Status != StatusCompleted || existing.ResponseStatus == nil {
+		w.Header().Set("Retry-After", "1")
+		apperror.Write(w, r, apperror.Conflict("a request with this Idempotency-Key is still in progress"))
+		return
+	}
+
+	for name, values := range existing.ResponseHeaders {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(*existing.ResponseStatus)
+	w.Write(existing.ResponseBody)
+}
+
+// storedHeader drops the headers that belong to the request being answered rather than to the
+// response, a replay gets its own.
+func storedHeader(header http.Header) http.Header {
+	stored := header.Clone()
+	for _, name := range []string{tracing.RequestIDHeader, tracing.CorrelationIDHeader, tracing.TraceparentHeader, "Date", "Content-Length"} {
+		stored.Del(name)
+	}
+
+	return stored
+}
+
+// scopeOf keeps keys of different routes and callers apart. The route template is used rather
+// than the path, so the same key on another resource is reported as reuse instead of running.
+// It reports false when neither a user nor a service was authenticated.
+func scopeOf(r *http.Request) (string, bool) {
+	route := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+
+	var caller string
+	if principal, err := middleware.GetPrincipalFromContext(r.Context()); err == nil && principal.UserID != "" {
+		caller = "user:" + principal.UserID
+	} else if serviceName, err := middleware.GetServiceNameFromContext(r.Context()); err == nil && serviceName != "" {
+		caller = "service:" + serviceName
+	} else {
+		return "", false
+	}
+
+	return r.Method + " " + route + " " + caller, true
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func validKey(key string) bool {
+	if len(key) > maxKeyLength || strings.TrimSpace(key) == "" {
+		return false
+	}
+	for _, c := range key {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and deadlines.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,370 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/middleware"
+	"github.com/gorilla/mux"
+)
+
+// memoryStore keeps records like Store does in Postgres, with a clock the tests move.
+type memoryStore struct {
+	mu      sync.Mutex
+	now     time.Time
+	records map[string]*Record
+	tokens  int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{now: time.Now(), records: map[string]*Record{}}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, scope string, key string, fingerprint string, lease time.Duration) (string, *Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[scope+" "+key]; ok && !existing.ExpiresAt.Before(s.now) {
+		record := *existing
+		return "", &record, nil
+	}
+
+	s.tokens++
+	token := strconv.Itoa(s.tokens)
+	s.records[scope+" "+key] = &Record{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusProcessing,
+		ClaimToken:  &token,
+		CreatedAt:   s.now,
+		ExpiresAt:   s.now.Add(lease),
+	}
+
+	return token, nil, nil
+}
+
+func (s *memoryStore) Extend(ctx context.Context, scope string, key string, token string, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.claimed(scope, key, token)
+	if record == nil {
+		return ErrClaimLost
+	}
+	record.ExpiresAt = s.now.Add(lease)
+
+	return nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, scope string, key string, token string, status int, header http.Header, body []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.claimed(scope, key, token)
+	if record == nil {
+		return ErrClaimLost
+	}
+	record.Status = StatusCompleted
+	record.ResponseStatus = &status
+	record.ResponseHeaders = Header(header)
+	record.ResponseBody = body
+	record.ExpiresAt = s.now.Add(ttl)
+
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, scope string, key string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.claimed(scope, key, token) != nil {
+		delete(s.records, scope+" "+key)
+	}
+
+	return nil
+}
+
+// claimed must be called with mu held.
+func (s *memoryStore) claimed(scope string, key string, token string) *Record {
+	record, ok := s.records[scope+" "+key]
+	if !ok || record.Status != StatusProcessing || record.ClaimToken == nil || *record.ClaimToken != token {
+		return nil
+	}
+
+	return record
+}
+
+func (s *memoryStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = s.now.Add(d)
+}
+
+func (s *memoryStore) record(key string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[testScope+" "+key]
+	if !ok {
+		return Record{}, false
+	}
+
+	return *record, true
+}
+
+const (
+	testGatewaySecret = "gateway-secret"
+	testScope         = "POST /orders user:user-1"
+	testLease         = time.Minute
+)
+
+// newRouter serves handler on POST /orders behind user auth and the middleware, the way services mount it.
+func newRouter(store KeyStore, handler http.HandlerFunc) http.Handler {
+	authenticated := middleware.NewUserAuthMiddleware(middleware.UserAuthConfig{GatewaySecret: testGatewaySecret})
+	idempotent := Middleware(store, Config{TTL: time.Hour, Lease: testLease})
+
+	router := mux.NewRouter()
+	router.Handle("/orders", authenticated(idempotent(handler))).Methods(http.MethodPost)
+	return router
+}
+
+func newRequest(key string, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	r.Header.Set(KeyHeader, key)
+	r.Header.Set(middleware.UserIDHeader, "user-1")
+	r.Header.Set(middleware.GatewayAuthHeader, gatewayToken())
+	return r
+}
+
+func gatewayToken() string {
+	payload := "apiGateway:" + strconv.FormatInt(time.Now().Unix(), 10)
+	h := hmac.New(sha256.New, []byte(testGatewaySecret))
+	h.Write([]byte(payload))
+	return payload + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+func serve(router http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// fingerprintOf is the fingerprint the middleware stores for newRequest(key, body).
+func fingerprintOf(body string) string {
+	return fingerprint(httptest.NewRequest(http.MethodPost, "/orders", nil), []byte(body))
+}
+
+func completedRecord(fingerprint string, status int, body string, expiresAt time.Time) *Record {
+	return &Record{
+		Scope:           testScope,
+		Key:             "key-1",
+		Fingerprint:     fingerprint,
+		Status:          StatusCompleted,
+		ResponseStatus:  &status,
+		ResponseHeaders: Header{"Content-Type": {"application/json"}},
+		ResponseBody:    []byte(body),
+		ExpiresAt:       expiresAt,
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	token := "stale"
+
+	for _, tc := range []struct {
+		name        string
+		stored      *Record // already in the store under key-1
+		handlerCode int
+		wantStatus  int
+		wantBody    string
+		wantRuns    int
+		wantReplay  bool
+		wantStored  bool // a completed record is left behind
+	}{
+		{
+			name:        "first request runs and its response is stored",
+			handlerCode: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"id":"order-1"}`,
+			wantRuns:    1,
+			wantStored:  true,
+		},
+		{
+			name:       "completed response is replayed",
+			stored:     completedRecord(fingerprintOf(`{"total":10}`), http.StatusCreated, `{"id":"order-0"}`, now.Add(time.Hour)),
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"order-0"}`,
+			wantReplay: true,
+			wantStored: true,
+		},
+		{
+			name:       "key reused for a different request",
+			stored:     completedRecord(fingerprintOf(`{"total":99}`), http.StatusCreated, `{"id":"order-0"}`, now.Add(time.Hour)),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantStored: true,
+		},
+		{
+			name: "request still in flight",
+			stored: &Record{Scope: testScope, Key: "key-1", Fingerprint: fingerprintOf(`{"total":10}`),
+				Status: StatusProcessing, ClaimToken: &token, ExpiresAt: now.Add(testLease)},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "lease of a crashed request is taken over after it expired",
+			stored: &Record{Scope: testScope, Key: "key-1", Fingerprint: fingerprintOf(`{"total":10}`),
+				Status: StatusProcessing, ClaimToken: &token, ExpiresAt: now.Add(-time.Second)},
+			handlerCode: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"id":"order-1"}`,
+			wantRuns:    1,
+			wantStored:  true,
+		},
+		{
+			name:        "replay window is over",
+			stored:      completedRecord(fingerprintOf(`{"total":10}`), http.StatusCreated, `{"id":"order-0"}`, now.Add(-time.Second)),
+			handlerCode: http.StatusCreated,
+			wantStatus:  http.StatusCreated,
+			wantBody:    `{"id":"order-1"}`,
+			wantRuns:    1,
+			wantStored:  true,
+		},
+		{
+			name:        "server errors are released so the client can retry",
+			handlerCode: http.StatusInternalServerError,
+			wantStatus:  http.StatusInternalServerError,
+			wantBody:    `{"id":"order-1"}`,
+			wantRuns:    1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemoryStore()
+			store.now = now
+			if tc.stored != nil {
+				store.records[testScope+" key-1"] = tc.stored
+			}
+
+			runs := 0
+			router := newRouter(store, func(w http.ResponseWriter, r *http.Request) {
+				runs++
+				if got := KeyFromContext(r.Context()); got != "key-1" {
+					t.Errorf("handler got key %q", got)
+				}
+				w.WriteHeader(tc.handlerCode)
+				io.WriteString(w, `{"id":"order-1"}`)
+			})
+
+			w := serve(router, newRequest("key-1", `{"total":10}`))
+			if w.Code != tc.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			if tc.wantBody != "" && w.Body.String() != tc.wantBody {
+				t.Errorf("got body %s, want %s", w.Body, tc.wantBody)
+			}
+			if runs != tc.wantRuns {
+				t.Errorf("handler ran %d times, want %d", runs, tc.wantRuns)
+			}
+			if replayed := w.Header().Get(ReplayedHeader) == "true"; replayed != tc.wantReplay {
+				t.Errorf("replayed %v, want %v", replayed, tc.wantReplay)
+			}
+
+			record, ok := store.record("key-1")
+			if stored := ok && record.Status == StatusCompleted; stored != tc.wantStored {
+				t.Errorf("completed record stored %v, want %v: %+v", stored, tc.wantStored, record)
+			}
+		})
+	}
+}
+
+func TestMiddlewareConcurrentRequest(t *testing.T) {
+	store := newMemoryStore()
+
+	var router http.Handler
+	var duplicate *httptest.ResponseRecorder
+	router = newRouter(store, func(w http.ResponseWriter, r *http.Request) {
+		//the duplicate arrives while the first request holds the key
+		duplicate = serve(router, newRequest("key-1", `{"total":10}`))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if w := serve(router, newRequest("key-1", `{"total":10}`)); w.Code != http.StatusCreated {
+		t.Fatalf("first request got status %d", w.Code)
+	}
+	if duplicate.Code != http.StatusConflict || duplicate.Header().Get("Retry-After") == "" {
+		t.Fatalf("duplicate got status %d with Retry-After %q, want 409 with a Retry-After", duplicate.Code, duplicate.Header().Get("Retry-After"))
+	}
+}
+
+func TestMiddlewareLostClaim(t *testing.T) {
+	store := newMemoryStore()
+
+	var router http.Handler
+	runs := 0
+	router = newRouter(store, func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		if runs == 1 {
+			//the first request outlives its lease, a retry takes the key over and completes first
+			store.advance(testLease + time.Second)
+			if w := serve(router, newRequest("key-1", `{"total":10}`)); w.Code != http.StatusCreated {
+				t.Errorf("retry got status %d", w.Code)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"run":%d}`, runs)
+	})
+
+	serve(router, newRequest("key-1", `{"total":10}`))
+	if runs != 2 {
+		t.Fatalf("handler ran %d times, want 2", runs)
+	}
+
+	//the claim token keeps the late first request from overwriting the response of the retry
+	record, ok := store.record("key-1")
+	if !ok || record.Status != StatusCompleted || string(record.ResponseBody) != `{"run":2}` {
+		t.Fatalf("got record %+v with body %s, want the response of the retry", record, record.ResponseBody)
+	}
+
+	w := serve(router, newRequest("key-1", `{"total":10}`))
+	if w.Body.String() != `{"run":2}` || w.Header().Get(ReplayedHeader) != "true" {
+		t.Fatalf("got %s, want the replayed response of the retry", w.Body)
+	}
+}
+
+func TestMiddlewareSkips(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		mutate func(r *http.Request)
+		want   int
+	}{
+		{"no key", func(r *http.Request) { r.Header.Del(KeyHeader) }, http.StatusCreated},
+		{"invalid key", func(r *http.Request) { r.Header.Set(KeyHeader, strings.Repeat("k", maxKeyLength+1)) }, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemoryStore()
+			router := newRouter(store, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			r := newRequest("key-1", `{"total":10}`)
+			tc.mutate(r)
+			if w := serve(router, r); w.Code != tc.want {
+				t.Fatalf("got status %d, want %d", w.Code, tc.want)
+			}
+			if len(store.records) != 0 {
+				t.Fatalf("stored %d records", len(store.records))
+			}
+		})
+	}
+}
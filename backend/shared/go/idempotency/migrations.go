@@ -0,0 +1,20 @@
+package idempotency
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations creates the idempotency_keys table of Store. Services using the middleware include
+// it in their migrator, see migrate.Config.Include.
+func Migrations() fs.FS {
+	migrations, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		panic(err) //the directory is embedded, it is always there
+	}
+
+	return migrations
+}
@@ -0,0 +1,19 @@
+-- Idempotency-Key store used by the shared idempotency middleware.
+-- One row per (scope, key), the scope is the method, route and caller. The response is kept for replays once completed.
+-- This is safe to re-run.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  scope varchar(255) NOT NULL,
+  idempotency_key varchar(100) NOT NULL,
+  fingerprint char(64) NOT NULL,
+  status varchar(20) NOT NULL,
+  response_status integer,
+  response_headers jsonb,
+  response_body bytea,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  completed_at timestamptz,
+  expires_at timestamptz NOT NULL,
+  PRIMARY KEY (scope, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,5 @@
+-- The claim token identifies the request holding a processing key, so a request whose lease ran
+-- out and was taken over cannot complete or release the key of its successor.
+-- This is safe to re-run.
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS claim_token uuid;
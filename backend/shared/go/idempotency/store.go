@@ -0,0 +1,210 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	StatusProcessing Status = "processing"
+	StatusCompleted  Status = "completed"
+)
+
+// ErrClaimLost is returned when the lease of a claim ran out and another request took the key over.
+var ErrClaimLost = errors.New("idempotency key was claimed by another request")
+
+// Record is the first request seen for a key and, once completed, the response it got.
+// ExpiresAt is the end of the lease while processing, and the end of the replay window once completed.
+type Record struct {
+	Scope           string     `gorm:"column:scope;primaryKey"`
+	Key             string     `gorm:"column:idempotency_key;primaryKey"`
+	Fingerprint     string     `gorm:"column:fingerprint"`
+	Status          Status     `gorm:"column:status"`
+	ClaimToken      *string    `gorm:"column:claim_token"`
+	ResponseStatus  *int       `gorm:"column:response_status"`
+	ResponseHeaders Header     `gorm:"column:response_headers"`
+	ResponseBody    []byte     `gorm:"column:response_body"`
+	CreatedAt       time.Time  `gorm:"column:created_at"`
+	CompletedAt     *time.Time `gorm:"column:completed_at"`
+	ExpiresAt       time.Time  `gorm:"column:expires_at"`
+}
+
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Header stores response headers as jsonb.
+type Header http.Header
+
+// GormDataType names the column type, gorm cannot derive one for a map.
+func (Header) GormDataType() string {
+	return "jsonb"
+}
+
+func (h *Header) Scan(value any) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return errors.New("unsupported type for idempotency header")
+	}
+}
+
+func (h Header) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// KeyStore is what Middleware needs of a store, Store is the one backed by Postgres.
+type KeyStore interface {
+	Claim(ctx context.Context, scope string, key string, fingerprint string, lease time.Duration) (string, *Record, error)
+	Extend(ctx context.Context, scope string, key string, token string, lease time.Duration) error
+	Complete(ctx context.Context, scope string, key string, token string, status int, header http.Header, body []byte, ttl time.Duration) error
+	Release(ctx context.Context, scope string, key string, token string) error
+}
+
+// Store keeps idempotency records in Postgres, in the table created by Migrations.
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Claim takes the key for a request with fingerprint for the length of lease and returns the claim
+// token that Extend, Complete and Release need. It only succeeds when the key was never used in scope
+// or its previous lease or replay window expired, otherwise the token is empty and the stored record
+// is returned.
+func (s *Store) Claim(ctx context.Context, scope string, key string, fingerprint string, lease time.Duration) (string, *Record, error) {
+	now := time.Now()
+	token := uuid.NewString()
+
+	result := s.db.WithContext(ctx).Exec(`
+		INSERT INTO idempotency_keys (scope, idempotency_key, fingerprint, status, claim_token, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (scope, idempotency_key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status = EXCLUDED.status,
+			claim_token = EXCLUDED.claim_token,
+			response_status = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < ?`,
+		scope, key, fingerprint, StatusProcessing, token, now, now.Add(lease),
+		now,
+	)
+	if result.Error != nil {
+		return "", nil, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return token, nil, nil
+	}
+
+	var existing Record
+	err := s.db.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		First(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, fmt.Errorf("idempotency key %s disappeared while claiming", key)
+		}
+		return "", nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return "", &existing, nil
+}
+
+// Extend renews the lease of a claimed key while its request is still running.
+func (s *Store) Extend(ctx context.Context, scope string, key string, token string, lease time.Duration) error {
+	result := s.db.WithContext(ctx).
+		Model(&Record{}).
+		Where("scope = ? AND idempotency_key = ? AND status = ? AND claim_token = ?", scope, key, StatusProcessing, token).
+		Update("expires_at", time.Now().Add(lease))
+	if result.Error != nil {
+		return fmt.Errorf("failed to extend idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+// Complete stores the response of a claimed key and starts its replay window of ttl.
+func (s *Store) Complete(ctx context.Context, scope string, key string, token string, status int, header http.Header, body []byte, ttl time.Duration) error {
+	now := time.Now()
+
+	result := s.db.WithContext(ctx).
+		Model(&Record{}).
+		Where("scope = ? AND idempotency_key = ? AND status = ? AND claim_token = ?", scope, key, StatusProcessing, token).
+		Updates(map[string]interface{}{
+			"status":           StatusCompleted,
+			"response_status":  status,
+			"response_headers": Header(header),
+			"response_body":    body,
+			"completed_at":     now,
+			"expires_at":       now.Add(ttl),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+
+	return nil
+}
+
+// Release gives up a claimed key without a response, so a retry of the request runs again. A claim
+// that was taken over in the meantime is left alone.
+func (s *Store) Release(ctx context.Context, scope string, key string, token string) error {
+	err := s.db.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ? AND status = ? AND claim_token = ?", scope, key, StatusProcessing, token).
+		Delete(&Record{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes records whose replay window or lease is over, Claim would take them over anyway.
+func (s *Store) DeleteExpired(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&Record{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
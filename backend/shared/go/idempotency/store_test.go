@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMockStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+
+	return NewStore(db), mock
+}
+
+func TestStoreClaim(t *testing.T) {
+	store, mock := newMockStore(t)
+	ctx := context.Background()
+
+	//the upsert only takes over expired keys, one affected row is a claim
+	mock.ExpectExec("INSERT INTO idempotency_keys .* WHERE idempotency_keys.expires_at < ").
+		WithArgs("scope", "key", "fp", StatusProcessing, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	token, existing, err := store.Claim(ctx, "scope", "key", "fp", time.Minute)
+	if err != nil || token == "" || existing != nil {
+		t.Fatalf("got token %q, record %+v, error %v for a free key", token, existing, err)
+	}
+
+	mock.ExpectExec("INSERT INTO idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT \* FROM "idempotency_keys" WHERE scope = \$1 AND idempotency_key = \$2`).
+		WithArgs("scope", "key", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"scope", "idempotency_key", "fingerprint", "status"}).
+			AddRow("scope", "key", "fp", StatusProcessing))
+	token, existing, err = store.Claim(ctx, "scope", "key", "fp", time.Minute)
+	if err != nil || token != "" || existing == nil || existing.Status != StatusProcessing {
+		t.Fatalf("got token %q, record %+v, error %v for a held key", token, existing, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreClaimTokenFencing(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		call func(store *Store) error
+		sql  string
+	}{
+		{"extend", func(store *Store) error {
+			return store.Extend(ctx, "scope", "key", "token", time.Minute)
+		}, `UPDATE "idempotency_keys" SET "expires_at"=\$1 WHERE scope = \$2 AND idempotency_key = \$3 AND status = \$4 AND claim_token = \$5`},
+		{"complete", func(store *Store) error {
+			return store.Complete(ctx, "scope", "key", "token", http.StatusCreated, nil, []byte("{}"), time.Hour)
+		}, `UPDATE "idempotency_keys" SET .* WHERE scope = \$\d+ AND idempotency_key = \$\d+ AND status = \$\d+ AND claim_token = \$\d+`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store, mock := newMockStore(t)
+
+			mock.ExpectExec(tc.sql).WillReturnResult(sqlmock.NewResult(0, 1))
+			if err := tc.call(store); err != nil {
+				t.Fatalf("got %v while holding the claim", err)
+			}
+
+			//no row matched the token, the lease ran out and another request took the key
+			mock.ExpectExec(tc.sql).WillReturnResult(sqlmock.NewResult(0, 0))
+			if err := tc.call(store); !errors.Is(err, ErrClaimLost) {
+				t.Fatalf("got %v, want ErrClaimLost", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,5 +1,47 @@
 package kafka
 
+import (
+	"time"
+)
+
+// ProducerOptions are the producer settings of a service config, see the config package. The defaults
+// publish synchronously, wait for every in-sync replica and keep the messages of one key on one partition.
+type ProducerOptions struct {
+	Async        bool          `env:"KAFKA_PRODUCER_ASYNC" default:"false"`
+	BatchSize    int           `env:"KAFKA_PRODUCER_BATCH_SIZE" default:"100" validate:"min=1"`
+	BatchBytes   int64         `env:"KAFKA_PRODUCER_BATCH_BYTES" default:"1048576" validate:"min=1"`
+	Linger       time.Duration `env:"KAFKA_PRODUCER_LINGER" default:"1s" validate:"gt=0"`
+	Compression  string        `env:"KAFKA_PRODUCER_COMPRESSION" default:"none"`
+	RequiredAcks string        `env:"KAFKA_PRODUCER_ACKS" default:"all"`
+	Balancer     string        `env:"KAFKA_PRODUCER_BALANCER" default:"hash"`
+}
+
+// ProducerConfig turns the options into the producer template of a KafkaBroker.
+func (o ProducerOptions) ProducerConfig() (ProducerConfig, error) {
+	compression, err := ParseCompression(o.Compression)
+	if err != nil {
+		return ProducerConfig{}, err
+	}
+	acks, err := ParseRequiredAcks(o.RequiredAcks)
+	if err != nil {
+		return ProducerConfig{}, err
+	}
+	balancer, err := ParseBalancer(o.Balancer)
+	if err != nil {
+		return ProducerConfig{}, err
+	}
+
+	return ProducerConfig{
+		Async:        o.Async,
+		BatchSize:    o.BatchSize,
+		BatchBytes:   o.BatchBytes,
+		Linger:       o.Linger,
+		Compression:  compression,
+		RequiredAcks: acks,
+		Balancer:     balancer,
+	}, nil
+}
+
 // func Writer(brokers []string, topic string) *kafka.Writer {
 // 	return &kafka.Writer{
 // 		Addr:     kafka.TCP(brokers...),
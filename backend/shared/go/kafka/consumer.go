@@ -2,6 +2,8 @@ package kafka
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -10,16 +12,37 @@ type KafkaConsumer struct {
 	reader *kafka.Reader
 }
 
+type ConsumerConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+
+	// CommitInterval batches offset commits and flushes them in the background at this interval.
+	// Zero commits synchronously on every CommitMessages call.
+	CommitInterval time.Duration
+}
+
 func NewConsumer(brokers []string, topic string, groupId string) *KafkaConsumer {
+	return NewConsumerWithConfig(ConsumerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupId,
+	})
+}
+
+func NewConsumerWithConfig(config ConsumerConfig) *KafkaConsumer {
 	return &KafkaConsumer{
 		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers: brokers,
-			Topic:   topic,
-			GroupID: groupId,
+			Brokers:        config.Brokers,
+			Topic:          config.Topic,
+			GroupID:        config.GroupID,
+			CommitInterval: config.CommitInterval,
 		}),
 	}
 }
 
+// ReadMessage reads and commits in one step, so the message is lost if handling it fails afterwards.
+// Use FetchMessage and CommitMessages for at-least-once handling.
 func (c *KafkaConsumer) ReadMessage(ctx context.Context) ([]byte, []byte, error) {
 	msg, err := c.reader.ReadMessage(ctx) //when ctx is cancelled or times out, this will return an error
 	if err != nil {
@@ -29,6 +52,83 @@ func (c *KafkaConsumer) ReadMessage(ctx context.Context) ([]byte, []byte, error)
 	return msg.Key, msg.Value, nil
 }
 
+// FetchMessage reads the next message without committing it.
+// The offset only moves once the message is passed to CommitMessages, so a crash before that redelivers it.
+func (c *KafkaConsumer) FetchMessage(ctx context.Context) (Message, error) {
+	msg, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return fromKafkaMessage(msg), nil
+}
+
+// CommitMessages commits the offsets of fully handled messages.
+// With a CommitInterval the commit is queued and flushed in the next batch or on Close.
+func (c *KafkaConsumer) CommitMessages(ctx context.Context, msgs ...Message) error {
+	offsets := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		offsets[i] = toKafkaOffset(msg)
+	}
+
+	return c.reader.CommitMessages(ctx, offsets...)
+}
+
+// Stats reports what the reader saw since the previous call, kafka-go resets its counters on every snapshot.
+func (c *KafkaConsumer) Stats() ConsumerStats {
+	stats := c.reader.Stats()
+	return ConsumerStats{
+		Rebalances: stats.Rebalances,
+		Errors:     stats.Errors,
+	}
+}
+
 func (c *KafkaConsumer) Close() error {
 	return c.reader.Close()
 }
+
+// CommitOffset moves groupID past offset in a partition of topic without joining the group, for tools that
+// handle a single message found with ScanTopic. The group offset never moves back, and the broker rejects
+// the commit while the group has active members.
+func CommitOffset(ctx context.Context, brokers []string, groupID string, topic string, partition int, offset int64) error {
+	client := &kafka.Client{Addr: kafka.TCP(brokers...)}
+
+	fetched, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: {partition}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch offset of %s: %w", groupID, err)
+	}
+	if fetched.Error != nil {
+		return fmt.Errorf("failed to fetch offset of %s: %w", groupID, fetched.Error)
+	}
+	for _, fetchedPartition := range fetched.Topics[topic] {
+		if fetchedPartition.Partition != partition {
+			continue
+		}
+		if fetchedPartition.Error != nil {
+			return fmt.Errorf("failed to fetch offset of %s: %w", groupID, fetchedPartition.Error)
+		}
+		if fetchedPartition.CommittedOffset > offset {
+			return nil
+		}
+	}
+
+	//generation -1 commits for a group without members, like kafka-consumer-groups --reset-offsets
+	committed, err := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: {{Partition: partition, Offset: offset + 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit offset of %s: %w", groupID, err)
+	}
+	for _, committedPartition := range committed.Topics[topic] {
+		if committedPartition.Error != nil {
+			return fmt.Errorf("failed to commit offset of %s: %w", groupID, committedPartition.Error)
+		}
+	}
+
+	return nil
+}
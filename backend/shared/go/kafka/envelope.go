@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope is the versioned wrapper every service publishes to kafka.
+// The payload stays raw so a consumer can read the metadata before deciding which struct to decode into.
+type Envelope struct {
+	EventID       string          `json:"eventId"`
+	EventType     EventType       `json:"eventType"`
+	Source        string          `json:"source"`
+	OccurredAt    time.Time       `json:"occurredAt"`
+	SchemaVersion int             `json:"schemaVersion"`
+	CorrelationID string          `json:"correlationId,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NewEnvelope wraps payload with a fresh event id and the current schema version of eventType.
+func NewEnvelope[T any](eventType EventType, source string, correlationID string, payload T) (Envelope, error) {
+	if source == "" {
+		return Envelope{}, errors.New("event source is required")
+	}
+
+	version, ok := schemaVersions[eventType]
+	if !ok {
+		return Envelope{}, fmt.Errorf("unknown event type: %s", eventType)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	eventID := uuid.NewString()
+	if correlationID == "" {
+		correlationID = eventID //the first event of a flow starts its own correlation chain
+	}
+
+	return Envelope{
+		EventID:       eventID,
+		EventType:     eventType,
+		Source:        source,
+		OccurredAt:    time.Now().UTC(),
+		SchemaVersion: version,
+		CorrelationID: correlationID,
+		Payload:       raw,
+	}, nil
+}
+
+func (e Envelope) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func DecodeEnvelope(value []byte) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return Envelope{}, fmt.Errorf("invalid event envelope: %w", err)
+	}
+
+	if envelope.EventID == "" || envelope.EventType == "" {
+		return Envelope{}, errors.New("invalid event envelope: missing eventId or eventType")
+	}
+
+	return envelope, nil
+}
+
+// DecodePayload unmarshals the envelope payload into T.
+// Envelopes written with a newer schema than this binary knows about are rejected instead of half-decoded.
+func DecodePayload[T any](envelope Envelope) (T, error) {
+	var payload T
+
+	if current, ok := schemaVersions[envelope.EventType]; ok && envelope.SchemaVersion > current {
+		return payload, fmt.Errorf("unsupported schema version %d for %s (max %d)", envelope.SchemaVersion, envelope.EventType, current)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		return payload, fmt.Errorf("failed to decode %s payload: %w", envelope.EventType, err)
+	}
+
+	return payload, nil
+}
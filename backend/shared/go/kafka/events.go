@@ -0,0 +1,128 @@
+package kafka
+
+import "time"
+
+// Topics shared between producers and consumers.
+const (
+	TopicOrderEvent   = "order_event"
+	TopicPaymentEvent = "payment_event"
+	TopicCartEvent    = "cart_event"
+	TopicSellerEvent  = "seller_event"
+)
+
+type EventType string
+
+// Event types follow the <aggregate>.<past tense verb> naming used by the service outboxes.
+const (
+	EventOrderCreated           EventType = "order.created"
+	EventOrderPaid              EventType = "order.paid"
+	EventOrderCancelled         EventType = "order.cancelled"
+	EventPaymentSettled         EventType = "payment.settled"
+	EventCartAbandoned          EventType = "cart.abandoned"
+	EventSellerProductPublished EventType = "seller_product.published"
+)
+
+// schemaVersions holds the current payload version of every known event type.
+// Bump the version when a payload changes in a way old consumers cannot read.
+var schemaVersions = map[EventType]int{
+	EventOrderCreated:           1,
+	EventOrderPaid:              1,
+	EventOrderCancelled:         1,
+	EventPaymentSettled:         1,
+	EventCartAbandoned:          1,
+	EventSellerProductPublished: 1,
+}
+
+var eventTopics = map[EventType]string{
+	EventOrderCreated:           TopicOrderEvent,
+	EventOrderPaid:              TopicOrderEvent,
+	EventOrderCancelled:         TopicOrderEvent,
+	EventPaymentSettled:         TopicPaymentEvent,
+	EventCartAbandoned:          TopicCartEvent,
+	EventSellerProductPublished: TopicSellerEvent,
+}
+
+// TopicFor returns the topic an event type is published to.
+func TopicFor(eventType EventType) (string, bool) {
+	topic, ok := eventTopics[eventType]
+	return topic, ok
+}
+
+func SchemaVersion(eventType EventType) (int, bool) {
+	version, ok := schemaVersions[eventType]
+	return version, ok
+}
+
+type OrderItemEvent struct {
+	ProductID *string `json:"productId"`
+	SellerID  *string `json:"sellerId"`
+	BrandID   *string `json:"brandId"`
+	Name      string  `json:"name"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unitPrice"`
+}
+
+type OrderCreatedEvent struct {
+	OrderID       string           `json:"orderId"`
+	OrderNumber   string           `json:"orderNumber"`
+	UserID        string           `json:"userId"`
+	SellerID      *string          `json:"sellerId"`
+	OrderSource   string           `json:"orderSource"`
+	TotalAmount   float64          `json:"totalAmount"`
+	Currency      string           `json:"currency"`
+	CustomerName  string           `json:"customerName"`
+	CustomerPhone string           `json:"customerPhone"`
+	Items         []OrderItemEvent `json:"items"`
+	CreatedAt     time.Time        `json:"createdAt"`
+}
+
+type OrderPaidEvent struct {
+	OrderID       string    `json:"orderId"`
+	OrderNumber   string    `json:"orderNumber"`
+	UserID        string    `json:"userId"`
+	PaymentID     string    `json:"paymentId"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	CustomerPhone string    `json:"customerPhone"`
+	PaidAt        time.Time `json:"paidAt"`
+}
+
+type OrderCancelledEvent struct {
+	OrderID       string    `json:"orderId"`
+	OrderNumber   string    `json:"orderNumber"`
+	UserID        string    `json:"userId"`
+	Reason        *string   `json:"reason"`
+	CancelledBy   *string   `json:"cancelledBy"`
+	CustomerPhone string    `json:"customerPhone"`
+	CancelledAt   time.Time `json:"cancelledAt"`
+}
+
+type PaymentSettledEvent struct {
+	PaymentID     string    `json:"paymentId"`
+	PaymentNumber string    `json:"paymentNumber"`
+	OrderID       string    `json:"orderId"`
+	UserID        string    `json:"userId"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	PaymentMethod string    `json:"paymentMethod"`
+	CustomerPhone string    `json:"customerPhone"`
+	SettledAt     time.Time `json:"settledAt"`
+}
+
+type CartAbandonedEvent struct {
+	CartID         string    `json:"cartId"`
+	UserID         string    `json:"userId"`
+	ItemCount      int       `json:"itemCount"`
+	Total          float64   `json:"total"`
+	Currency       string    `json:"currency"`
+	LastActivityAt time.Time `json:"lastActivityAt"`
+}
+
+type SellerProductPublishedEvent struct {
+	ProductID   string    `json:"productId"`
+	SellerID    string    `json:"sellerId"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Price       float64   `json:"price"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
@@ -1,11 +1,92 @@
 package kafka
 
-// type Consumer interface {
-// 	ReadMessage(ctx context.Context) ([]byte, []byte, error)
-// 	Close() error
-// }
-
-// type Producer interface {
-// 	ReadMessage(ctx context.Context) ([]byte, []byte, error)
-// 	Close() error
-// }
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer is implemented by *KafkaConsumer and *MemoryConsumer.
+type Consumer interface {
+	ReadMessage(ctx context.Context) ([]byte, []byte, error)
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Stats() ConsumerStats
+	Close() error
+}
+
+// ConsumerStats counts events since the previous Stats call.
+type ConsumerStats struct {
+	Rebalances int64
+	Errors     int64
+}
+
+// Producer is implemented by *KafkaProducer and *MemoryProducer.
+type Producer interface {
+	PublishMessage(ctx context.Context, key []byte, value []byte) error
+	PublishMessageAsync(ctx context.Context, key []byte, value []byte, onDelivery func(DeliveryReport)) error
+	PublishMessageWithHeaders(ctx context.Context, key []byte, value []byte, headers map[string]string) error
+	PublishEvent(ctx context.Context, key string, envelope Envelope) error
+	Flush(ctx context.Context) error
+	Close() error
+}
+
+// Broker hands out producers and consumers, services depend on it instead of dialing kafka themselves
+// so the same code runs against a real cluster or a MemoryBroker.
+type Broker interface {
+	NewProducer(topic string) Producer
+	NewConsumer(config ConsumerConfig) Consumer
+	Ping(ctx context.Context) error
+}
+
+// KafkaBroker is the Broker backed by a real cluster.
+// Producer is the template for every producer it creates, Brokers and Topic are filled in.
+type KafkaBroker struct {
+	Brokers  []string
+	Producer ProducerConfig
+}
+
+func NewKafkaBroker(brokers []string) *KafkaBroker {
+	return &KafkaBroker{Brokers: brokers}
+}
+
+func (b *KafkaBroker) NewProducer(topic string) Producer {
+	config := b.Producer
+	config.Brokers = b.Brokers
+	config.Topic = topic
+
+	return NewProducerWithConfig(config)
+}
+
+func (b *KafkaBroker) NewConsumer(config ConsumerConfig) Consumer {
+	if len(config.Brokers) == 0 {
+		config.Brokers = b.Brokers
+	}
+
+	return NewConsumerWithConfig(config)
+}
+
+// Ping succeeds as soon as one of the brokers accepts a connection and answers a metadata request.
+func (b *KafkaBroker) Ping(ctx context.Context) error {
+	if len(b.Brokers) == 0 {
+		return errors.New("no kafka brokers configured")
+	}
+
+	var err error
+	for _, address := range b.Brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", address)
+		if err != nil {
+			continue
+		}
+		_, err = conn.Brokers()
+		conn.Close()
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no kafka broker reachable: %w", err)
+}
@@ -0,0 +1,385 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-process stand-in for a kafka cluster, meant for tests and local runs.
+// Topics are created on first use, keyed messages are hashed to a partition and members of a
+// consumer group split the partitions between them. Offsets are committed per group, so a
+// consumer that joins (or rejoins after a rebalance) resumes from the last committed offset.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string]*memoryTopic
+	groups     map[string]*memoryGroup
+	notify     chan struct{} //closed and replaced whenever a consumer may have something new to do
+}
+
+type memoryTopic struct {
+	partitions [][]Message
+	next       int //round robin partition for messages without a key
+}
+
+type memoryGroup struct {
+	committed []int64
+	members   []*MemoryConsumer
+}
+
+// NewMemoryBroker creates a broker whose topics get the given number of partitions unless created explicitly.
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	if partitions <= 0 {
+		partitions = 1
+	}
+
+	return &MemoryBroker{
+		partitions: partitions,
+		topics:     make(map[string]*memoryTopic),
+		groups:     make(map[string]*memoryGroup),
+		notify:     make(chan struct{}),
+	}
+}
+
+// CreateTopic creates topic with its own partition count, it is a no-op when the topic already exists.
+func (b *MemoryBroker) CreateTopic(topic string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.topics[topic]; !ok {
+		b.topics[topic] = &memoryTopic{partitions: make([][]Message, max(partitions, 1))}
+	}
+}
+
+// Messages returns a copy of everything published to topic, ordered by partition and offset.
+func (b *MemoryBroker) Messages(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var msgs []Message
+	for _, partition := range b.topic(topic).partitions {
+		msgs = append(msgs, partition...)
+	}
+
+	return msgs
+}
+
+// CommittedOffset returns the next offset groupID will read from a partition of topic.
+func (b *MemoryBroker) CommittedOffset(topic string, groupID string, partition int) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, ok := b.groups[groupKey(topic, groupID)]
+	if !ok || partition >= len(group.committed) {
+		return 0
+	}
+
+	return group.committed[partition]
+}
+
+// Ping always succeeds, the broker lives in this process.
+func (b *MemoryBroker) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (b *MemoryBroker) NewProducer(topic string) Producer {
+	return &MemoryProducer{broker: b, topic: topic}
+}
+
+func (b *MemoryBroker) NewConsumer(config ConsumerConfig) Consumer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	consumer := &MemoryConsumer{
+		broker:    b,
+		topic:     config.Topic,
+		positions: make(map[int]int64),
+	}
+
+	if config.GroupID != "" {
+		key := groupKey(config.Topic, config.GroupID)
+		group, ok := b.groups[key]
+		if !ok {
+			group = &memoryGroup{committed: make([]int64, len(b.topic(config.Topic).partitions))}
+			b.groups[key] = group
+		}
+		group.members = append(group.members, consumer)
+		consumer.group = group
+		b.rebalance(group)
+	}
+
+	return consumer
+}
+
+// topic must be called with mu held.
+func (b *MemoryBroker) topic(name string) *memoryTopic {
+	topic, ok := b.topics[name]
+	if !ok {
+		topic = &memoryTopic{partitions: make([][]Message, b.partitions)}
+		b.topics[name] = topic
+	}
+
+	return topic
+}
+
+// rebalance drops every member's uncommitted position, like a kafka rebalance the members continue
+// from the committed offsets of their new assignment. Must be called with mu held.
+func (b *MemoryBroker) rebalance(group *memoryGroup) {
+	for _, member := range group.members {
+		clear(member.positions)
+		member.rebalances++
+	}
+	b.broadcast()
+}
+
+// broadcast wakes every consumer waiting in FetchMessage. Must be called with mu held.
+func (b *MemoryBroker) broadcast() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+func groupKey(topic string, groupID string) string {
+	return topic + "/" + groupID
+}
+
+type MemoryProducer struct {
+	broker *MemoryBroker
+	topic  string
+	closed bool
+}
+
+func (p *MemoryProducer) PublishMessage(ctx context.Context, key []byte, value []byte) error {
+	return p.PublishMessageAsync(ctx, key, value, nil)
+}
+
+// PublishMessageAsync stores the message right away, onDelivery has run by the time it returns.
+func (p *MemoryProducer) PublishMessageAsync(ctx context.Context, key []byte, value []byte, onDelivery func(DeliveryReport)) error {
+	msg, err := p.publish(ctx, key, value, traceHeaders(ctx))
+	if onDelivery != nil {
+		onDelivery(DeliveryReport{
+			Topic:     p.topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Key:       key,
+			Err:       err,
+		})
+	}
+
+	return err
+}
+
+// PublishMessageWithHeaders stores the message with headers, they win over the trace headers of ctx.
+func (p *MemoryProducer) PublishMessageWithHeaders(ctx context.Context, key []byte, value []byte, headers map[string]string) error {
+	_, err := p.publish(ctx, key, value, mergeHeaders(traceHeaders(ctx), headers))
+	return err
+}
+
+func (p *MemoryProducer) publish(ctx context.Context, key []byte, value []byte, headers map[string]string) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+
+	b := p.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if p.closed {
+		return Message{}, io.ErrClosedPipe
+	}
+
+	topic := b.topic(p.topic)
+	partition := topic.next % len(topic.partitions)
+	if key != nil {
+		hash := fnv.New32a()
+		hash.Write(key)
+		partition = int(hash.Sum32() % uint32(len(topic.partitions)))
+	} else {
+		topic.next++
+	}
+
+	msg := Message{
+		Topic:     p.topic,
+		Partition: partition,
+		Offset:    int64(len(topic.partitions[partition])),
+		Key:       append([]byte(nil), key...),
+		Value:     append([]byte(nil), value...),
+		Headers:   headers,
+		Time:      time.Now(),
+	}
+	topic.partitions[partition] = append(topic.partitions[partition], msg)
+	b.broadcast()
+
+	return msg, nil
+}
+
+func (p *MemoryProducer) PublishEvent(ctx context.Context, key string, envelope Envelope) error {
+	return publishEvent(ctx, p, key, envelope)
+}
+
+// Flush has nothing to wait for, messages are stored synchronously.
+func (p *MemoryProducer) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (p *MemoryProducer) Close() error {
+	p.broker.mu.Lock()
+	defer p.broker.mu.Unlock()
+
+	p.closed = true
+	return nil
+}
+
+// MemoryConsumer reads from a MemoryBroker. Without a group it reads every partition from the start
+// and cannot commit, the same as a kafka reader without a GroupID.
+type MemoryConsumer struct {
+	broker    *MemoryBroker
+	topic     string
+	group     *memoryGroup
+	positions map[int]int64 //next offset to fetch per partition, missing means the committed offset
+	closed    bool
+
+	rebalances int64 //since the last Stats call
+}
+
+// ReadMessage fetches and commits in one step, like KafkaConsumer.ReadMessage.
+func (c *MemoryConsumer) ReadMessage(ctx context.Context) ([]byte, []byte, error) {
+	msg, err := c.FetchMessage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.group != nil {
+		if err := c.CommitMessages(ctx, msg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return msg.Key, msg.Value, nil
+}
+
+// FetchMessage blocks until one of the consumer's partitions has a message or ctx is done.
+func (c *MemoryConsumer) FetchMessage(ctx context.Context) (Message, error) {
+	b := c.broker
+	for {
+		b.mu.Lock()
+		if c.closed {
+			b.mu.Unlock()
+			return Message{}, io.EOF
+		}
+		msg, ok := c.next()
+		wait := b.notify
+		b.mu.Unlock()
+
+		if ok {
+			return msg, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// next must be called with the broker mu held.
+func (c *MemoryConsumer) next() (Message, bool) {
+	topic := c.broker.topic(c.topic)
+	for partition, log := range topic.partitions {
+		if !c.assigned(partition) {
+			continue
+		}
+
+		position, ok := c.positions[partition]
+		if !ok && c.group != nil {
+			position = c.group.committed[partition]
+		}
+		if position < int64(len(log)) {
+			c.positions[partition] = position + 1
+			msg := log[position]
+			msg.HighWaterMark = int64(len(log))
+			return msg, true
+		}
+	}
+
+	return Message{}, false
+}
+
+// assigned spreads partitions over group members round robin, a consumer without a group reads them all.
+func (c *MemoryConsumer) assigned(partition int) bool {
+	if c.group == nil {
+		return true
+	}
+
+	for i, member := range c.group.members {
+		if member == c {
+			return partition%len(c.group.members) == i
+		}
+	}
+
+	return false
+}
+
+// CommitMessages moves the group offset past each message. Offsets never move backwards,
+// so committing an older message after a newer one is harmless.
+func (c *MemoryConsumer) CommitMessages(ctx context.Context, msgs ...Message) error {
+	if c.group == nil {
+		return errors.New("unavailable when GroupID is not set")
+	}
+
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c.closed {
+		return io.ErrClosedPipe
+	}
+
+	for _, msg := range msgs {
+		if msg.Topic != c.topic || msg.Partition < 0 || msg.Partition >= len(c.group.committed) {
+			return errors.New("message does not belong to this consumer")
+		}
+		c.group.committed[msg.Partition] = max(c.group.committed[msg.Partition], msg.Offset+1)
+	}
+
+	return nil
+}
+
+func (c *MemoryConsumer) Stats() ConsumerStats {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	stats := ConsumerStats{Rebalances: c.rebalances}
+	c.rebalances = 0
+	return stats
+}
+
+// Close leaves the group, its partitions are handed to the remaining members.
+func (c *MemoryConsumer) Close() error {
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if c.group != nil {
+		for i, member := range c.group.members {
+			if member == c {
+				c.group.members = append(c.group.members[:i], c.group.members[i+1:]...)
+				break
+			}
+		}
+		b.rebalance(c.group)
+	} else {
+		b.broadcast()
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+)
+
+func fetch(t *testing.T, consumer Consumer) Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msg, err := consumer.FetchMessage(ctx)
+	if err != nil {
+		t.Fatalf("FetchMessage: %v", err)
+	}
+
+	return msg
+}
+
+func expectNoMessage(t *testing.T, consumer Consumer) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if msg, err := consumer.FetchMessage(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected no message, got offset %d of partition %d (err %v)", msg.Offset, msg.Partition, err)
+	}
+}
+
+func TestMemoryBrokerRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(3)
+	producer := broker.NewProducer(TopicOrderEvent)
+
+	var published []Envelope
+	for i := 0; i < 3; i++ {
+		envelope, err := NewEnvelope(EventOrderCreated, "order-service", "", OrderCreatedEvent{})
+		if err != nil {
+			t.Fatalf("NewEnvelope: %v", err)
+		}
+		if err := producer.PublishEvent(ctx, "order-1", envelope); err != nil {
+			t.Fatalf("PublishEvent: %v", err)
+		}
+		published = append(published, envelope)
+	}
+
+	consumer := broker.NewConsumer(ConsumerConfig{Topic: TopicOrderEvent})
+	defer consumer.Close()
+
+	partition := -1
+	for i, want := range published {
+		msg := fetch(t, consumer)
+		if string(msg.Key) != "order-1" {
+			t.Fatalf("message %d: key %q, want order-1", i, msg.Key)
+		}
+		if partition == -1 {
+			partition = msg.Partition
+		} else if msg.Partition != partition {
+			t.Fatalf("message %d: partition %d, same key was on partition %d", i, msg.Partition, partition)
+		}
+		if msg.Offset != int64(i) {
+			t.Fatalf("message %d: offset %d", i, msg.Offset)
+		}
+
+		got, err := DecodeEnvelope(msg.Value)
+		if err != nil {
+			t.Fatalf("DecodeEnvelope: %v", err)
+		}
+		if got.EventID != want.EventID || got.EventType != want.EventType {
+			t.Fatalf("message %d: got event %s %s, want %s %s", i, got.EventType, got.EventID, want.EventType, want.EventID)
+		}
+	}
+	expectNoMessage(t, consumer)
+}
+
+func TestMemoryBrokerCommittedOffsets(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(1)
+	producer := broker.NewProducer("topic")
+	for _, value := range []string{"a", "b", "c"} {
+		if err := producer.PublishMessage(ctx, nil, []byte(value)); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+
+	first := broker.NewConsumer(ConsumerConfig{Topic: "topic", GroupID: "group"})
+	a := fetch(t, first)
+	b := fetch(t, first)
+	if err := first.CommitMessages(ctx, a); err != nil {
+		t.Fatalf("CommitMessages: %v", err)
+	}
+	if got := broker.CommittedOffset("topic", "group", 0); got != 1 {
+		t.Fatalf("committed offset %d, want 1", got)
+	}
+
+	//b was fetched but never committed, the next member of the group starts over at b
+	first.Close()
+	second := broker.NewConsumer(ConsumerConfig{Topic: "topic", GroupID: "group"})
+	defer second.Close()
+	if msg := fetch(t, second); msg.Offset != b.Offset || string(msg.Value) != "b" {
+		t.Fatalf("got offset %d %q, want the uncommitted b at offset %d", msg.Offset, msg.Value, b.Offset)
+	}
+
+	//offsets never move backwards
+	c := fetch(t, second)
+	if err := second.CommitMessages(ctx, c, a); err != nil {
+		t.Fatalf("CommitMessages: %v", err)
+	}
+	if got := broker.CommittedOffset("topic", "group", 0); got != 3 {
+		t.Fatalf("committed offset %d, want 3", got)
+	}
+
+	//another group keeps its own offsets
+	other := broker.NewConsumer(ConsumerConfig{Topic: "topic", GroupID: "other"})
+	defer other.Close()
+	if msg := fetch(t, other); msg.Offset != 0 {
+		t.Fatalf("other group got offset %d, want 0", msg.Offset)
+	}
+
+	if err := broker.NewConsumer(ConsumerConfig{Topic: "topic"}).CommitMessages(ctx, c); err == nil {
+		t.Fatal("commit without a group succeeded")
+	}
+}
+
+func TestMemoryBrokerRebalance(t *testing.T) {
+	ctx := context.Background()
+	broker := NewMemoryBroker(1)
+	broker.CreateTopic("topic", 2)
+	producer := broker.NewProducer("topic")
+	//keyless messages alternate between the partitions
+	for _, value := range []string{"p0-a", "p1-a", "p0-b", "p1-b"} {
+		if err := producer.PublishMessage(ctx, nil, []byte(value)); err != nil {
+			t.Fatalf("PublishMessage: %v", err)
+		}
+	}
+
+	first := broker.NewConsumer(ConsumerConfig{Topic: "topic", GroupID: "group"})
+	first.Stats() //joining counts as a rebalance too
+	msg := fetch(t, first)
+	if msg.Partition != 0 || msg.Offset != 0 {
+		t.Fatalf("got partition %d offset %d, want partition 0 offset 0", msg.Partition, msg.Offset)
+	}
+	if err := first.CommitMessages(ctx, msg); err != nil {
+		t.Fatalf("CommitMessages: %v", err)
+	}
+
+	//a new member takes partition 1, the first member continues from its committed offset on partition 0
+	second := broker.NewConsumer(ConsumerConfig{Topic: "topic", GroupID: "group"})
+	if stats := first.Stats(); stats.Rebalances != 1 {
+		t.Fatalf("first consumer saw %d rebalances, want 1", stats.Rebalances)
+	}
+	for _, want := range []struct {
+		consumer  Consumer
+		partition int
+		value     string
+	}{
+		{first, 0, "p0-b"},
+		{second, 1, "p1-a"},
+		{second, 1, "p1-b"},
+	} {
+		msg := fetch(t, want.consumer)
+		if msg.Partition != want.partition || string(msg.Value) != want.value {
+			t.Fatalf("got %q from partition %d, want %q from partition %d", msg.Value, msg.Partition, want.value, want.partition)
+		}
+	}
+	expectNoMessage(t, first)
+
+	//the second member leaves without committing, its partition goes back to the first member from offset 0
+	second.Close()
+	defer first.Close()
+	for _, value := range []string{"p0-b", "p1-a", "p1-b"} {
+		if msg := fetch(t, first); string(msg.Value) != value {
+			t.Fatalf("got %q after the rebalance, want %q", msg.Value, value)
+		}
+	}
+	expectNoMessage(t, first)
+}
+
+func TestMemoryProducerPublishMessageWithHeaders(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	producer := broker.NewProducer("topic")
+
+	//the headers of a republished message win, the context only fills in what they lack
+	ctx := tracing.WithRequestID(tracing.WithCorrelationID(context.Background(), "from-context"), "request-1")
+	headers := map[string]string{tracing.CorrelationIDHeader: "original", "x-custom": "kept"}
+	if err := producer.PublishMessageWithHeaders(ctx, []byte("key"), []byte("value"), headers); err != nil {
+		t.Fatalf("PublishMessageWithHeaders: %v", err)
+	}
+
+	msgs := broker.Messages("topic")
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	for key, want := range map[string]string{
+		tracing.CorrelationIDHeader: "original",
+		tracing.RequestIDHeader:     "request-1",
+		"x-custom":                  "kept",
+	} {
+		if got := msgs[0].Headers[key]; got != want {
+			t.Errorf("header %s is %q, want %q", key, got, want)
+		}
+	}
+	if headers[tracing.RequestIDHeader] != "" {
+		t.Fatal("the headers of the caller were modified")
+	}
+}
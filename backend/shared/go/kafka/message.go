@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message is a record read from kafka together with its position in the topic.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Time      time.Time
+
+	// HighWaterMark is the partition end offset seen when the message was fetched, HighWaterMark-Offset-1 is the lag behind it.
+	HighWaterMark int64
+}
+
+func fromKafkaMessage(msg kafka.Message) Message {
+	var headers map[string]string
+	if len(msg.Headers) > 0 {
+		headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+	}
+
+	return Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   headers,
+		Time:      msg.Time,
+
+		HighWaterMark: msg.HighWaterMark,
+	}
+}
+
+// toKafkaOffset keeps only what kafka-go needs to commit the message.
+func toKafkaOffset(msg Message) kafka.Message {
+	return kafka.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	}
+}
+
+// ContextFromHeaders restores the trace and correlation context a producer attached to msg.
+func ContextFromHeaders(ctx context.Context, msg Message) context.Context {
+	return tracing.Extract(ctx, func(key string) string {
+		return msg.Headers[key]
+	})
+}
+
+func traceHeaders(ctx context.Context) map[string]string {
+	var headers map[string]string
+	tracing.Inject(ctx, func(key string, value string) {
+		if headers == nil {
+			headers = make(map[string]string, 2)
+		}
+		headers[key] = value
+	})
+
+	return headers
+}
+
+// mergeHeaders adds headers to base, a key in both keeps the value of headers.
+func mergeHeaders(base map[string]string, headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(headers))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+func toKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	kafkaHeaders := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		kafkaHeaders = append(kafkaHeaders, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	return kafkaHeaders
+}
@@ -2,31 +2,324 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
+type Compression string
+
+const (
+	CompressionNone   Compression = ""
+	CompressionGzip   Compression = "gzip"
+	CompressionSnappy Compression = "snappy"
+	CompressionLz4    Compression = "lz4"
+	CompressionZstd   Compression = "zstd"
+)
+
+// RequiredAcks uses the kafka values, the zero value keeps the kafka-go default of not waiting for acks.
+type RequiredAcks int
+
+const (
+	AcksNone   RequiredAcks = 0
+	AcksLeader RequiredAcks = 1
+	AcksAll    RequiredAcks = -1
+)
+
+// Balancer picks the partition of a message.
+type Balancer string
+
+const (
+	// BalancerLeastBytes sends a message to the partition that received the fewest bytes and ignores the key.
+	// It is the zero value and how the producers partitioned before keys were used.
+	BalancerLeastBytes Balancer = ""
+	// BalancerHash sends messages with the same key to the same partition, so they keep their order.
+	// Messages without a key are spread round robin.
+	BalancerHash Balancer = "hash"
+)
+
+// DeliveryReport tells an async publisher what happened to one message.
+type DeliveryReport struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Err       error
+}
+
+type ProducerConfig struct {
+	Brokers []string
+	Topic   string
+
+	// Async makes PublishMessage return as soon as the message is queued. Failures are only
+	// reported through OnDelivery or the callback given to PublishMessageAsync.
+	Async        bool
+	BatchSize    int           // messages per batch, defaults to 100
+	BatchBytes   int64         // bytes per batch, defaults to 1MB
+	Linger       time.Duration // how long a batch waits to fill up, defaults to 1s
+	Compression  Compression
+	RequiredAcks RequiredAcks
+	Balancer     Balancer
+
+	// OnDelivery is called for every message once the broker acknowledged or rejected it.
+	// It runs on the writer's goroutines and must not block.
+	OnDelivery func(DeliveryReport)
+}
+
 type KafkaProducer struct {
-	writer *kafka.Writer
+	writer     *kafka.Writer
+	onDelivery func(DeliveryReport)
+
+	mu      sync.Mutex
+	pending int
+	idle    chan struct{} //closed when pending drops to zero
 }
 
 func NewProducer(brokers []string, topic string) *KafkaProducer {
-	return &KafkaProducer{
-		writer: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Topic:    topic,
-			Balancer: &kafka.LeastBytes{},
-		},
+	return NewProducerWithConfig(ProducerConfig{
+		Brokers: brokers,
+		Topic:   topic,
+	})
+}
+
+func NewProducerWithConfig(config ProducerConfig) *KafkaProducer {
+	p := &KafkaProducer{
+		onDelivery: config.OnDelivery,
+		idle:       closedChannel(),
+	}
+
+	p.writer = &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     toKafkaBalancer(config.Balancer),
+		Async:        config.Async,
+		BatchSize:    config.BatchSize,
+		BatchBytes:   config.BatchBytes,
+		BatchTimeout: config.Linger,
+		RequiredAcks: kafka.RequiredAcks(config.RequiredAcks),
+		Compression:  toKafkaCompression(config.Compression),
+	}
+	if config.Async {
+		p.writer.Completion = p.complete
 	}
+
+	return p
+}
+
+// ParseCompression accepts the codec names used in env files, "" and "none" disable compression.
+func ParseCompression(value string) (Compression, error) {
+	switch Compression(value) {
+	case CompressionNone, "none":
+		return CompressionNone, nil
+	case CompressionGzip, CompressionSnappy, CompressionLz4, CompressionZstd:
+		return Compression(value), nil
+	default:
+		return CompressionNone, fmt.Errorf("unknown kafka compression: %s", value)
+	}
+}
+
+// ParseRequiredAcks accepts "none", "leader", "all" or the kafka numbers 0, 1, -1.
+func ParseRequiredAcks(value string) (RequiredAcks, error) {
+	switch value {
+	case "", "none", "0":
+		return AcksNone, nil
+	case "leader", "one", "1":
+		return AcksLeader, nil
+	case "all", "-1":
+		return AcksAll, nil
+	default:
+		return AcksNone, fmt.Errorf("unknown kafka required acks: %s", value)
+	}
+}
+
+// ParseBalancer accepts "hash" and "least_bytes", "" keeps BalancerLeastBytes.
+func ParseBalancer(value string) (Balancer, error) {
+	switch value {
+	case "", "least_bytes":
+		return BalancerLeastBytes, nil
+	case string(BalancerHash):
+		return BalancerHash, nil
+	default:
+		return BalancerLeastBytes, fmt.Errorf("unknown kafka balancer: %s", value)
+	}
+}
+
+// RequireDelivery fails unless a nil error from PublishMessage means the message is stored on every in-sync
+// replica. Callers that drop their own copy of a message once it is published, like an outbox, need it.
+func (c ProducerConfig) RequireDelivery() error {
+	if c.Async {
+		return errors.New("async producers only report delivery through callbacks")
+	}
+	if c.RequiredAcks != AcksAll {
+		return fmt.Errorf("required acks must be all (-1), got %d", c.RequiredAcks)
+	}
+
+	return nil
 }
 
+// PublishMessage publishes value with the trace and correlation headers of ctx attached.
+// In async mode it only queues the message, the returned error covers queueing and not delivery.
 func (p *KafkaProducer) PublishMessage(ctx context.Context, key []byte, value []byte) error {
-	return p.writer.WriteMessages(ctx, kafka.Message{
-		Key:   key,
-		Value: value,
+	return p.PublishMessageAsync(ctx, key, value, nil)
+}
+
+// PublishMessageAsync is PublishMessage with a callback for this message only, called after OnDelivery.
+// In sync mode the callback has run by the time PublishMessageAsync returns, without partition and offset.
+func (p *KafkaProducer) PublishMessageAsync(ctx context.Context, key []byte, value []byte, onDelivery func(DeliveryReport)) error {
+	return p.write(ctx, kafka.Message{
+		Key:        key,
+		Value:      value,
+		Headers:    toKafkaHeaders(traceHeaders(ctx)),
+		WriterData: onDelivery,
 	})
 }
 
+// PublishMessageWithHeaders is PublishMessage with headers of its own, e.g. those of a message that is
+// published again. They win over the trace and correlation headers of ctx.
+func (p *KafkaProducer) PublishMessageWithHeaders(ctx context.Context, key []byte, value []byte, headers map[string]string) error {
+	return p.write(ctx, kafka.Message{
+		Key:     key,
+		Value:   value,
+		Headers: toKafkaHeaders(mergeHeaders(traceHeaders(ctx), headers)),
+	})
+}
+
+func (p *KafkaProducer) write(ctx context.Context, msg kafka.Message) error {
+	if !p.writer.Async {
+		err := p.writer.WriteMessages(ctx, msg)
+		p.report(msg, err)
+		return err
+	}
+
+	p.track(1)
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
+		//rejected before it was queued, Completion will never see it
+		p.complete([]kafka.Message{msg}, err)
+		return err
+	}
+
+	return nil
+}
+
+// PublishEvent encodes the envelope and publishes it keyed by the aggregate id. With BalancerHash
+// the events of one aggregate share a partition and keep their order.
+func (p *KafkaProducer) PublishEvent(ctx context.Context, key string, envelope Envelope) error {
+	return publishEvent(ctx, p, key, envelope)
+}
+
+// Flush waits until every queued message was delivered or failed, or until ctx is done.
+// Call it before Close during shutdown to bound how long pending messages may take.
+func (p *KafkaProducer) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("flush interrupted with %d pending messages: %w", p.Pending(), ctx.Err())
+	}
+}
+
+// Pending returns the number of messages that are queued but not yet acknowledged.
+func (p *KafkaProducer) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pending
+}
+
 func (p *KafkaProducer) Close() error {
 	return p.writer.Close()
 }
+
+func (p *KafkaProducer) track(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.pending += n
+}
+
+// complete is the async writer Completion hook, it reports every message and releases Flush once nothing is pending.
+func (p *KafkaProducer) complete(msgs []kafka.Message, err error) {
+	for _, msg := range msgs {
+		p.report(msg, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = max(p.pending-len(msgs), 0)
+	if p.pending == 0 {
+		select {
+		case <-p.idle:
+		default:
+			close(p.idle)
+		}
+	}
+}
+
+func (p *KafkaProducer) report(msg kafka.Message, err error) {
+	report := DeliveryReport{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Err:       err,
+	}
+	if report.Topic == "" {
+		report.Topic = p.writer.Topic
+	}
+
+	if p.onDelivery != nil {
+		p.onDelivery(report)
+	}
+	if callback, ok := msg.WriterData.(func(DeliveryReport)); ok && callback != nil {
+		callback(report)
+	}
+}
+
+func publishEvent(ctx context.Context, p Producer, key string, envelope Envelope) error {
+	value, err := envelope.Encode()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", envelope.EventType, err)
+	}
+
+	return p.PublishMessage(ctx, []byte(key), value)
+}
+
+func toKafkaCompression(compression Compression) kafka.Compression {
+	switch compression {
+	case CompressionGzip:
+		return kafka.Gzip
+	case CompressionSnappy:
+		return kafka.Snappy
+	case CompressionLz4:
+		return kafka.Lz4
+	case CompressionZstd:
+		return kafka.Zstd
+	default:
+		return 0
+	}
+}
+
+func toKafkaBalancer(balancer Balancer) kafka.Balancer {
+	if balancer == BalancerHash {
+		return &kafka.Hash{} //same key, same partition, nil keys are spread round robin
+	}
+
+	return &kafka.LeastBytes{}
+}
+
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+)
+
+const usage = `usage: %s -topic <topic> [range] [filters] (-to-topic <topic> | -route | -dry-run)
+
+range:   -partitions 0,2  -from-offset n  -to-offset n  -from-time RFC3339  -to-time RFC3339
+filters: -keys a,b  -event-types order.created,order.paid
+modes:   -to-topic re-publishes matches, -route passes them to the handlers registered in this binary,
+         -dry-run only lists them
+
+`
+
+// RouterFlags lets a service add its own flags to the replay command. It is called before the flags are
+// parsed and returns what builds the router from them, which only runs for -route.
+type RouterFlags func(flags *flag.FlagSet) func() (Router, error)
+
+// Main parses replay flags from args and runs the replay. routerFlags is nil for binaries without local
+// handlers, -route is rejected there. Services embed it in their own replay command to expose -route.
+func Main(ctx context.Context, name string, args []string, routerFlags RouterFlags, out io.Writer) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = func() {
+		fmt.Fprintf(out, usage, name)
+		flags.PrintDefaults()
+	}
+
+	var newRouter func() (Router, error)
+	if routerFlags != nil {
+		newRouter = routerFlags(flags)
+	}
+
+	brokers := flags.String("brokers", "localhost:9092", "comma separated kafka brokers")
+	topic := flags.String("topic", "", "topic to read from")
+	partitions := flags.String("partitions", "", "comma separated partitions, all when empty")
+	fromOffset := flags.Int64("from-offset", 0, "first offset to read in every partition")
+	toOffset := flags.Int64("to-offset", 0, "stop before this offset, the current end when 0")
+	fromTime := flags.String("from-time", "", "start at messages written at or after this RFC3339 time")
+	toTime := flags.String("to-time", "", "stop at messages written after this RFC3339 time")
+	keys := flags.String("keys", "", "comma separated message keys to replay")
+	eventTypes := flags.String("event-types", "", "comma separated envelope event types to replay")
+	targetTopic := flags.String("to-topic", "", "re-publish matches to this topic")
+	route := flags.Bool("route", false, "route matches through the locally registered handlers")
+	dryRun := flags.Bool("dry-run", false, "only list matches")
+	progress := flags.Duration("progress", 5*time.Second, "progress report interval")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config := Config{
+		Brokers:          splitList(*brokers),
+		Topic:            *topic,
+		Keys:             splitList(*keys),
+		EventTypes:       splitList(*eventTypes),
+		TargetTopic:      *targetTopic,
+		DryRun:           *dryRun,
+		ProgressInterval: *progress,
+		Out:              out,
+		Range: kafka.ScanOptions{
+			FromOffset: *fromOffset,
+			ToOffset:   *toOffset,
+		},
+	}
+
+	if *route {
+		if newRouter == nil {
+			return errors.New("-route is not available, this binary has no handlers registered")
+		}
+		router, err := newRouter()
+		if err != nil {
+			return err
+		}
+		config.Router = router
+	}
+
+	for _, value := range splitList(*partitions) {
+		partition, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid partition %q", value)
+		}
+		config.Range.Partitions = append(config.Range.Partitions, partition)
+	}
+
+	var err error
+	if config.Range.FromTime, err = parseTime(*fromTime); err != nil {
+		return fmt.Errorf("invalid -from-time: %w", err)
+	}
+	if config.Range.ToTime, err = parseTime(*toTime); err != nil {
+		return fmt.Errorf("invalid -to-time: %w", err)
+	}
+
+	broker := kafka.NewKafkaBroker(config.Brokers)
+	//replayed messages go back to the partition of their key and are only counted once stored
+	broker.Producer = kafka.ProducerConfig{RequiredAcks: kafka.AcksAll, Balancer: kafka.BalancerHash}
+	replayer, err := NewReplayer(config, broker)
+	if err != nil {
+		flags.Usage()
+		return err
+	}
+
+	stats, err := replayer.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if stats.Failed > 0 {
+		return fmt.Errorf("%d of %d matched messages failed to replay", stats.Failed, stats.Matched)
+	}
+
+	return nil
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+
+	return values
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,188 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/kafka"
+)
+
+// Router is what a service registers to replay messages through its own handlers, events.Router satisfies it.
+type Router interface {
+	Route(ctx context.Context, msg kafka.Message) error
+}
+
+type Config struct {
+	Brokers []string
+	Topic   string
+	Range   kafka.ScanOptions
+
+	Keys       []string // only replay messages with one of these keys
+	EventTypes []string // only replay envelopes of these event types
+
+	TargetTopic string // re-publish matches here
+	Router      Router // or route them through local handlers
+	DryRun      bool   // only list matches, nothing is published or routed
+
+	ProgressInterval time.Duration
+	Out              io.Writer
+}
+
+// Stats counts what a replay did, per partition and in total.
+type Stats struct {
+	Partition int
+	Start     int64
+	End       int64
+	Offset    int64
+	Scanned   int
+	Matched   int
+	Replayed  int
+	Failed    int
+}
+
+type Replayer struct {
+	config     Config
+	producer   kafka.Producer
+	total      Stats
+	current    Stats
+	lastReport time.Time
+}
+
+func NewReplayer(config Config, broker kafka.Broker) (*Replayer, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("a source topic is required")
+	}
+	if !config.DryRun && config.TargetTopic == "" && config.Router == nil {
+		return nil, fmt.Errorf("either a target topic, a router or dry run is required")
+	}
+	if config.TargetTopic != "" && config.Router != nil {
+		return nil, fmt.Errorf("a target topic and a router cannot be used together")
+	}
+	if config.TargetTopic == config.Topic {
+		return nil, fmt.Errorf("replaying %s into itself would replay forever", config.Topic)
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = 5 * time.Second
+	}
+	if config.Out == nil {
+		config.Out = io.Discard
+	}
+
+	replayer := &Replayer{config: config}
+	if config.TargetTopic != "" && !config.DryRun {
+		replayer.producer = broker.NewProducer(config.TargetTopic)
+	}
+
+	return replayer, nil
+}
+
+// Run scans the configured range and replays every match, failures are counted and reported but do not stop the scan.
+func (r *Replayer) Run(ctx context.Context) (Stats, error) {
+	if r.producer != nil {
+		defer r.producer.Close()
+	}
+
+	options := r.config.Range
+	options.OnPartition = func(partition int, start int64, end int64) {
+		r.finishPartition()
+		r.current = Stats{Partition: partition, Start: start, End: end, Offset: start}
+	}
+
+	r.lastReport = time.Now()
+	err := kafka.ScanRange(ctx, r.config.Brokers, r.config.Topic, options, func(msg kafka.Message) error {
+		r.replay(ctx, msg)
+		if time.Since(r.lastReport) >= r.config.ProgressInterval {
+			r.report("progress", r.current)
+			r.lastReport = time.Now()
+		}
+		return nil
+	})
+	r.finishPartition()
+
+	if r.producer != nil {
+		if flushErr := r.producer.Flush(ctx); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+
+	r.report("total", r.total)
+	return r.total, err
+}
+
+func (r *Replayer) replay(ctx context.Context, msg kafka.Message) {
+	r.current.Scanned++
+	r.current.Offset = msg.Offset
+
+	eventType, ok := r.matches(msg)
+	if !ok {
+		return
+	}
+	r.current.Matched++
+
+	if r.config.DryRun {
+		fmt.Fprintf(r.config.Out, "match %s/%d@%d key=%s event=%s\n", msg.Topic, msg.Partition, msg.Offset, msg.Key, eventType)
+		return
+	}
+
+	//keep the trace and correlation id of the original message so replays show up next to it
+	msgCtx := kafka.ContextFromHeaders(ctx, msg)
+
+	var err error
+	if r.producer != nil {
+		err = r.producer.PublishMessage(msgCtx, msg.Key, msg.Value)
+	} else {
+		err = r.config.Router.Route(msgCtx, msg)
+	}
+
+	if err != nil {
+		r.current.Failed++
+		fmt.Fprintf(r.config.Out, "failed %s/%d@%d: %v\n", msg.Topic, msg.Partition, msg.Offset, err)
+		return
+	}
+	r.current.Replayed++
+}
+
+func (r *Replayer) matches(msg kafka.Message) (string, bool) {
+	if len(r.config.Keys) > 0 && !slices.Contains(r.config.Keys, string(msg.Key)) {
+		return "", false
+	}
+
+	envelope, err := kafka.DecodeEnvelope(msg.Value)
+	eventType := "-"
+	if err == nil {
+		eventType = string(envelope.EventType)
+	}
+
+	if len(r.config.EventTypes) > 0 && (err != nil || !slices.Contains(r.config.EventTypes, eventType)) {
+		return "", false
+	}
+
+	return eventType, true
+}
+
+func (r *Replayer) finishPartition() {
+	if r.current.End == 0 && r.current.Scanned == 0 {
+		return
+	}
+
+	r.report("partition done", r.current)
+	r.total.Scanned += r.current.Scanned
+	r.total.Matched += r.current.Matched
+	r.total.Replayed += r.current.Replayed
+	r.total.Failed += r.current.Failed
+	r.current = Stats{}
+}
+
+func (r *Replayer) report(label string, stats Stats) {
+	if label == "total" {
+		fmt.Fprintf(r.config.Out, "%s: scanned=%d matched=%d replayed=%d failed=%d\n",
+			label, stats.Scanned, stats.Matched, stats.Replayed, stats.Failed)
+		return
+	}
+
+	fmt.Fprintf(r.config.Out, "%s: partition=%d offset=%d/%d scanned=%d matched=%d replayed=%d failed=%d\n",
+		label, stats.Partition, stats.Offset, stats.End, stats.Scanned, stats.Matched, stats.Replayed, stats.Failed)
+}
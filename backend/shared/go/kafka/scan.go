@@ -0,0 +1,155 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrStopScan can be returned from a ScanTopic callback to stop reading without an error.
+var ErrStopScan = errors.New("stop scan")
+
+// ScanOptions narrows ScanRange down to part of a topic. The zero value scans everything.
+type ScanOptions struct {
+	Partitions []int     // all partitions when empty
+	FromOffset int64     // first offset to read, clamped to what the broker still retains
+	FromTime   time.Time // takes precedence over FromOffset, starts at the first message written at or after it
+	ToOffset   int64     // exclusive end offset, zero means the end of the partition when the scan started
+	ToTime     time.Time // stops a partition at its first message written after it
+
+	// IdleTimeout is how long a fetch may come back empty before the rest of the range counts as gone,
+	// e.g. when compaction removed the last offsets of a partition. Defaults to 10s.
+	IdleTimeout time.Duration
+
+	// OnPartition is called before a partition is read with the offsets that will be scanned, [start, end).
+	OnPartition func(partition int, start int64, end int64)
+}
+
+// ScanTopic reads every partition of topic from its first offset up to the offset that was last
+// when the scan started. It does not join a consumer group, so nothing is committed.
+func ScanTopic(ctx context.Context, brokers []string, topic string, fn func(Message) error) error {
+	return ScanRange(ctx, brokers, topic, ScanOptions{}, fn)
+}
+
+// ScanRange is ScanTopic limited by options, partitions are read one after another in id order.
+func ScanRange(ctx context.Context, brokers []string, topic string, options ScanOptions, fn func(Message) error) error {
+	if len(brokers) == 0 {
+		return errors.New("at least one broker is required")
+	}
+
+	conn, err := kafka.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		return fmt.Errorf("failed to dial kafka: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+
+	ids := make([]int, 0, len(partitions))
+	for _, partition := range partitions {
+		if len(options.Partitions) == 0 || slices.Contains(options.Partitions, partition.ID) {
+			ids = append(ids, partition.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("topic %s has none of the requested partitions", topic)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		if err := scanPartition(ctx, brokers, topic, id, options, fn); err != nil {
+			if errors.Is(err, ErrStopScan) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanPartition(ctx context.Context, brokers []string, topic string, partition int, options ScanOptions, fn func(Message) error) error {
+	leader, err := kafka.DialLeader(ctx, "tcp", brokers[0], topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to dial leader of %s/%d: %w", topic, partition, err)
+	}
+	defer leader.Close()
+
+	first, last, err := leader.ReadOffsets()
+	if err != nil {
+		return fmt.Errorf("failed to read offsets of %s/%d: %w", topic, partition, err)
+	}
+
+	start := max(options.FromOffset, first)
+	if !options.FromTime.IsZero() {
+		if start, err = leader.ReadOffset(options.FromTime); err != nil {
+			return fmt.Errorf("failed to find offset of %s/%d at %s: %w", topic, partition, options.FromTime, err)
+		}
+		//-1 when every message is older than FromTime, SetOffset would take it for the last offset and wait for new ones
+		if start < 0 {
+			start = last
+		}
+	}
+	end := last
+	if options.ToOffset > 0 {
+		end = min(options.ToOffset, last)
+	}
+
+	if options.OnPartition != nil {
+		options.OnPartition(partition, start, max(start, end))
+	}
+	if start >= end {
+		return nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(start); err != nil {
+		return err
+	}
+
+	idleTimeout := options.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = 10 * time.Second
+	}
+
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		msg, err := reader.ReadMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			//offsets up to end existed when the scan started, nothing arriving means the rest was compacted or deleted
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return nil
+			}
+			return err
+		}
+
+		//past a gap at the end of the range the next message is one written after the scan started
+		if msg.Offset >= end {
+			return nil
+		}
+		if !options.ToTime.IsZero() && msg.Time.After(options.ToTime) {
+			return nil
+		}
+
+		if err := fn(fromKafkaMessage(msg)); err != nil {
+			return err
+		}
+
+		if msg.Offset >= end-1 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends gorm's logs to the logger of the request, so failed and slow queries carry
+// the request id. Use it with db.WithContext(ctx) in repositories. Statements are logged with
+// their placeholders, the values may be personal data or secrets.
+type GormLogger struct {
+	SlowThreshold time.Duration // queries taking longer are logged as warnings, zero disables it
+	Level         gormlogger.LogLevel
+}
+
+func NewGormLogger() *GormLogger {
+	return &GormLogger{SlowThreshold: 200 * time.Millisecond, Level: gormlogger.Warn}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.Level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		FromContext(ctx).Info(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		FromContext(ctx).Warn(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		FromContext(ctx).Error(fmt.Sprintf(msg, args...))
+	}
+}
+
+// ParamsFilter drops the values gorm would otherwise interpolate into the logged statement.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	//not found is an expected outcome of lookups, the repositories turn it into their own errors
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.Level >= gormlogger.Error:
+		sql, rows := fc()
+		FromContext(ctx).Error("query failed", zap.Error(err), zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("duration", elapsed))
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= gormlogger.Warn:
+		sql, rows := fc()
+		FromContext(ctx).Warn("slow query", zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("duration", elapsed), zap.Duration("threshold", l.SlowThreshold))
+	case l.Level >= gormlogger.Info:
+		sql, rows := fc()
+		FromContext(ctx).Debug("query", zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("duration", elapsed))
+	}
+}
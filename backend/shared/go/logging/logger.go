@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type contextKey string
+
+const loggerKey contextKey = "logger"
+
+// Options are the logging settings of a service config, see the config package. LOG_LEVEL sets the
+// minimum level and LOG_FORMAT=console switches to human readable output for local development.
+type Options struct {
+	Level  string `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+	Format string `env:"LOG_FORMAT" default:"json" validate:"oneof=json console"`
+}
+
+// New builds the JSON logger of service, zero options keep zap's production defaults.
+// Secrets are redacted from every entry, see RedactString.
+func New(service string, options Options) (*zap.Logger, error) {
+	config := zap.NewProductionConfig()
+	config.EncoderConfig.TimeKey = "time"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	if options.Level != "" {
+		level, err := zapcore.ParseLevel(options.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		config.Level = zap.NewAtomicLevelAt(level)
+	}
+	if strings.EqualFold(options.Format, "console") {
+		config.Encoding = "console"
+		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return redactingCore{core}
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	return logger.With(zap.String("service", service)), nil
+}
+
+// Init builds the logger with New, makes it the global logger returned by L and routes the
+// standard library log package through it, so leftover log.Printf calls are structured and redacted too.
+func Init(service string, options Options) (*zap.Logger, error) {
+	logger, err := New(service, options)
+	if err != nil {
+		return nil, err
+	}
+
+	zap.ReplaceGlobals(logger)
+	zap.RedirectStdLog(logger)
+	return logger, nil
+}
+
+// L is the global logger, a no-op until Init ran.
+func L() *zap.Logger {
+	return zap.L()
+}
+
+func WithContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger of the request being handled, which already carries its request id.
+// Outside of a request, e.g. in kafka handlers, it is the global logger with the ids found in ctx.
+func FromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.Logger); ok {
+		return logger
+	}
+
+	return L().With(contextFields(ctx)...)
+}
+
+func contextFields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if requestID := tracing.RequestIDFromContext(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	if correlationID := tracing.CorrelationIDFromContext(ctx); correlationID != "" {
+		fields = append(fields, zap.String("correlation_id", correlationID))
+	}
+	if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+		fields = append(fields, zap.String("trace_id", traceID))
+	}
+
+	return fields
+}
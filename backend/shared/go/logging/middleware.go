@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// probe and scrape endpoints are hit every few seconds, their access logs are debug only
+var quietPaths = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// Middleware stores a logger carrying the request, correlation and trace ids in the request context
+// and writes one access log per request. It has to run after tracing.Middleware, which assigns the ids.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		logger := L().With(contextFields(r.Context())...)
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r.WithContext(WithContext(r.Context(), logger)))
+
+		route := ""
+		if current := mux.CurrentRoute(r); current != nil {
+			route, _ = current.GetPathTemplate()
+		}
+
+		level := zapcore.InfoLevel
+		switch {
+		case recorder.status >= 500:
+			level = zapcore.ErrorLevel
+		case recorder.status >= 400:
+			level = zapcore.WarnLevel
+		case quietPaths[r.URL.Path]:
+			level = zapcore.DebugLevel
+		}
+
+		logger.Log(level, "request",
+			zap.String("method", r.Method),
+			zap.String("route", route),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", recorder.status),
+			zap.Int("bytes", recorder.bytes),
+			zap.Duration("duration", time.Since(started)),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and deadlines.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+const gormStartKey = "metrics:started_at"
+
+var (
+	gormQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gorm",
+		Name:      "queries_total",
+		Help:      "Queries run through gorm by operation, table and status (ok, not_found or error).",
+	}, []string{"operation", "table", "status"})
+
+	gormDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gorm",
+		Name:      "query_duration_seconds",
+		Help:      "Time spent in gorm queries by operation and table.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+// GormPlugin records query metrics, register it with db.Use(metrics.GormPlugin{}).
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("metrics:before_create", startQuery),
+		callback.Create().After("gorm:create").Register("metrics:after_create", finishQuery("create")),
+		callback.Query().Before("gorm:query").Register("metrics:before_query", startQuery),
+		callback.Query().After("gorm:query").Register("metrics:after_query", finishQuery("query")),
+		callback.Update().Before("gorm:update").Register("metrics:before_update", startQuery),
+		callback.Update().After("gorm:update").Register("metrics:after_update", finishQuery("update")),
+		callback.Delete().Before("gorm:delete").Register("metrics:before_delete", startQuery),
+		callback.Delete().After("gorm:delete").Register("metrics:after_delete", finishQuery("delete")),
+		callback.Row().Before("gorm:row").Register("metrics:before_row", startQuery),
+		callback.Row().After("gorm:row").Register("metrics:after_row", finishQuery("row")),
+		callback.Raw().Before("gorm:raw").Register("metrics:before_raw", startQuery),
+		callback.Raw().After("gorm:raw").Register("metrics:after_raw", finishQuery("raw")),
+	)
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func finishQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		started, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		status := "ok"
+		switch {
+		case errors.Is(db.Error, gorm.ErrRecordNotFound):
+			status = "not_found"
+		case db.Error != nil:
+			status = "error"
+		}
+
+		gormQueries.WithLabelValues(operation, table, status).Inc()
+		gormDuration.WithLabelValues(operation, table).Observe(time.Since(started).Seconds())
+	}
+}
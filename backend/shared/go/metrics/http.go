@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// latency buckets for request/response work, from cache hits to slow downstream calls
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var (
+	serverRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "http",
+		Subsystem: "server",
+		Name:      "requests_total",
+		Help:      "HTTP requests served by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	serverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "http",
+		Subsystem: "server",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve HTTP requests by route template, method and status.",
+		Buckets:   durationBuckets,
+	}, []string{"route", "method", "status"})
+
+	serverInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "http",
+		Subsystem: "server",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served by route template and method.",
+	}, []string{"route", "method"})
+
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "http",
+		Subsystem: "client",
+		Name:      "requests_total",
+		Help:      "Outgoing HTTP requests by target, method and status, status is \"error\" when no response arrived.",
+	}, []string{"target", "method", "status"})
+
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "http",
+		Subsystem: "client",
+		Name:      "request_duration_seconds",
+		Help:      "Time until the response headers of outgoing HTTP requests arrived.",
+		Buckets:   durationBuckets,
+	}, []string{"target", "method", "status"})
+
+	clientInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "http",
+		Subsystem: "client",
+		Name:      "requests_in_flight",
+		Help:      "Outgoing HTTP requests waiting for a response by target.",
+	}, []string{"target"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		serverRequests, serverDuration, serverInFlight,
+		clientRequests, clientDuration, clientInFlight,
+		gormQueries, gormDuration,
+	)
+}
+
+// Handler serves every metric registered with the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records server metrics. It has to be added with router.Use so the matched
+// route template is known, raw paths would give every id its own series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		inFlight := serverInFlight.WithLabelValues(route, r.Method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		started := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		status := strconv.Itoa(recorder.status)
+		serverRequests.WithLabelValues(route, r.Method, status).Inc()
+		serverDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(started).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and deadlines.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// InstrumentClient records client metrics for every request client sends, labelled with target,
+// usually the name of the downstream service. client is changed in place and returned.
+func InstrumentClient(target string, client *http.Client) *http.Client {
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	client.Transport = &instrumentedTransport{target: target, next: transport}
+	return client
+}
+
+type instrumentedTransport struct {
+	target string
+	next   http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	inFlight := clientInFlight.WithLabelValues(t.target)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	clientRequests.WithLabelValues(t.target, req.Method, status).Inc()
+	clientDuration.WithLabelValues(t.target, req.Method, status).Observe(time.Since(started).Seconds())
+
+	return resp, err
+}
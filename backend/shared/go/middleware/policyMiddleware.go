@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/tracing"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// PolicyDenial is the audit record of an internal call the policy refused.
+type PolicyDenial struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Method  string    `json:"method"`
+	Route   string    `json:"route"`
+	Path    string    `json:"path"`
+	TraceID string    `json:"trace_id,omitempty"`
+	Reason  string    `json:"reason"`
+}
+
+// PolicyAuditor receives every denial, LogPolicyDenial is used when none is given.
+type PolicyAuditor func(ctx context.Context, denial PolicyDenial)
+
+// InternalPolicyMiddleware only lets callers through that the policy allows on the matched route.
+// It has to run after the service auth middleware, which puts the caller into the context.
+func InternalPolicyMiddleware(policy *auth.Policy, audit PolicyAuditor) mux.MiddlewareFunc {
+	if audit == nil {
+		audit = LogPolicyDenial
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.URL.Path
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+
+			denial := PolicyDenial{
+				Time:    time.Now().UTC(),
+				Method:  r.Method,
+				Route:   route,
+				Path:    r.URL.Path,
+				TraceID: tracing.TraceIDFromContext(r.Context()),
+			}
+
+			service, err := GetServiceNameFromContext(r.Context())
+			if err != nil {
+				denial.Reason = "caller not authenticated"
+				audit(r.Context(), denial)
+				apperror.Write(w, r, apperror.Unauthenticated("service authentication required"))
+				return
+			}
+
+			if !policy.Allowed(service, r.Method, route) {
+				denial.Service = service
+				denial.Reason = "no policy rule allows this call"
+				audit(r.Context(), denial)
+				apperror.Write(w, r, apperror.PermissionDenied("service is not allowed to call this route"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LogPolicyDenial writes the denial as a single audit line.
+func LogPolicyDenial(ctx context.Context, denial PolicyDenial) {
+	logging.FromContext(ctx).Warn("audit: internal call denied", zap.Any("denial", denial))
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+)
+
+// RecoverMiddleware turns a panicking handler into a 500 problem response carrying the request
+// and correlation ids, and logs the panic with its stack. It should be the innermost middleware
+// so access logs and metrics still see the 500.
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &headerRecorder{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			//net/http uses this panic to abort a response on purpose, it must reach the server
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+			logging.FromContext(r.Context()).Error("handler panicked",
+				zap.Error(err),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			//once the status line went out the client sees a truncated response instead
+			if !recorder.wroteHeader {
+				apperror.WriteProblem(w, apperror.NewProblem(r, apperror.Internal(err)))
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+type headerRecorder struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (r *headerRecorder) WriteHeader(status int) {
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *headerRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for flushing and deadlines.
+func (r *headerRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
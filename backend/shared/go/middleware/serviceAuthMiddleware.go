@@ -1,40 +1,116 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
 	"net/http"
-	"os"
 
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
 	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 )
 
 const (
 	ServiceAuthHeader = "x-service-auth"
 	ServiceNameHeader = "x-service-name"
+
+	serviceClaimsKey contextKey = "serviceClaims"
+
+	//bodies of internal calls are small, this only bounds what is buffered to check a body hash
+	maxSignedBodyBytes = 1 << 20
+)
+
+var (
+	legacyTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "service_auth",
+		Name:      "legacy_tokens_accepted_total",
+		Help:      "Legacy service tokens accepted by calling service, should stay at zero before legacy support is turned off.",
+	}, []string{"service"})
 )
 
-func ServiceAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Header.Get(ServiceAuthHeader)
-		serviceName := r.Header.Get(ServiceNameHeader)
+func init() {
+	prometheus.MustRegister(legacyTokens)
+}
+
+// NewServiceAuthMiddleware rejects requests without a valid service token. The verified claims are
+// stored in the request context, see GetServiceClaimsFromContext. Services build verifier from their
+// config, see auth.ServiceAuthOptions.
+func NewServiceAuthMiddleware(verifier *auth.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get(ServiceAuthHeader)
+			serviceName := r.Header.Get(ServiceNameHeader)
+
+			if token == "" || serviceName == "" {
+				apperror.Write(w, r, apperror.Unauthenticated("service authentication required"))
+				return
+			}
+
+			body, err := readSignedBody(w, r)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					apperror.Write(w, r, apperror.Wrap(err, apperror.CodeTooLarge, ""))
+				} else {
+					apperror.Write(w, r, apperror.Wrap(err, apperror.CodeInvalidArgument, "failed to read request body"))
+				}
+				return
+			}
 
-		if token == "" || serviceName == "" {
-			utils.WriteError(w, http.StatusUnauthorized, errors.New("Service authentication required"))
-			return
-		}
+			claims, err := verifier.Verify(r.Context(), token, body)
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("rejected service token", zap.String("service", serviceName), zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
+				apperror.Write(w, r, apperror.Wrap(err, apperror.CodeUnauthenticated, "invalid service token"))
+				return
+			}
 
-		serviceSecret := os.Getenv("SERVICE_SECRET")
-		if serviceSecret == "" {
-			utils.WriteError(w, http.StatusInternalServerError, errors.New("Service secret not configured"))
-			return
-		}
+			if claims.Issuer != serviceName {
+				apperror.Write(w, r, apperror.Unauthenticated("service name does not match token"))
+				return
+			}
+			if claims.Legacy {
+				legacyTokens.WithLabelValues(claims.Issuer).Inc()
+				logging.FromContext(r.Context()).Warn("accepted legacy service token", zap.String("service", claims.Issuer), zap.String("method", r.Method), zap.String("path", r.URL.Path))
+			}
+
+			ctx := context.WithValue(r.Context(), serviceClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func GetServiceClaimsFromContext(ctx context.Context) (*auth.ServiceClaims, error) {
+	return utils.GetValueFromContext[*auth.ServiceClaims](ctx, serviceClaimsKey)
+}
+
+// GetServiceNameFromContext returns the verified name of the calling service.
+func GetServiceNameFromContext(ctx context.Context) (string, error) {
+	claims, err := GetServiceClaimsFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Issuer, nil
+}
 
-		if err := utils.VerifyServiceToken(token, serviceSecret); err != nil {
-			utils.WriteError(w, http.StatusUnauthorized, err)
-			return
-		}
+// readSignedBody buffers the body so a body hash can be checked, and puts it back for the handler.
+// Bodies over maxSignedBodyBytes fail with an *http.MaxBytesError.
+func readSignedBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
 
-		next.ServeHTTP(w, r)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
 
-	})
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
 }
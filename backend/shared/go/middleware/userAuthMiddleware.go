@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/auth"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	principalKey contextKey = "principal"
+
+	GatewayAuthHeader  = "x-gateway-auth"
+	UserIDHeader       = "x-user-id"
+	UserRoleHeader     = "x-user-role"
+	UserPhoneHeader    = "x-user-phone"
+	SellerIDHeader     = "x-seller-id"
+	UserAuthTimeHeader = "x-user-auth-time" // unix seconds of the user's last login
+)
+
+type UserAuthConfig struct {
+	// Tokens verifies "Authorization: Bearer" user tokens, nil disables them.
+	Tokens *auth.UserTokenVerifier
+	// GatewaySecret enables the x-user-* headers, they are only trusted on requests
+	// that also carry a valid x-gateway-auth signature. Empty disables them.
+	GatewaySecret string
+}
+
+// NewUserAuthMiddleware puts the verified Principal into the request context and rejects
+// requests without one. A bearer token is used when present, the gateway headers otherwise.
+func NewUserAuthMiddleware(config UserAuthConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticateUser(r, config)
+			if err != nil {
+				logging.FromContext(r.Context()).Warn("rejected user authentication", zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
+				apperror.Write(w, r, apperror.Wrap(err, apperror.CodeUnauthenticated, err.Error()))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), principalKey, principal)
+			ctx = context.WithValue(ctx, userIDKey, principal.UserID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authenticateUser(r *http.Request, config UserAuthConfig) (*auth.Principal, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && config.Tokens != nil {
+		return config.Tokens.Verify(r.Context(), strings.TrimSpace(token))
+	}
+
+	userId := r.Header.Get(UserIDHeader)
+	if userId == "" {
+		return nil, errors.New("userID not found in request")
+	}
+
+	if config.GatewaySecret == "" {
+		return nil, errors.New("user token required")
+	}
+	if err := auth.VerifyGatewayToken(r.Header.Get(GatewayAuthHeader), config.GatewaySecret); err != nil {
+		return nil, errors.New("user headers are only accepted from the api gateway")
+	}
+
+	principal := &auth.Principal{
+		UserID:   userId,
+		SellerID: r.Header.Get(SellerIDHeader),
+		Phone:    r.Header.Get(UserPhoneHeader),
+		Source:   "gateway",
+	}
+	if authTime, err := strconv.ParseInt(r.Header.Get(UserAuthTimeHeader), 10, 64); err == nil && authTime > 0 {
+		principal.AuthTime = time.Unix(authTime, 0)
+	}
+	for _, role := range strings.Split(r.Header.Get(UserRoleHeader), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			principal.Roles = append(principal.Roles, role)
+		}
+	}
+
+	return principal, nil
+}
+
+func GetPrincipalFromContext(ctx context.Context) (*auth.Principal, error) {
+	return utils.GetValueFromContext[*auth.Principal](ctx, principalKey)
+}
+
+// UserAuthOptions are the user auth settings of a service config, see the config package. Bearer
+// tokens need a JWKS, USER_JWKS_FILE wins over USER_JWKS_URL, and the gateway headers GATEWAY_SECRET.
+type UserAuthOptions struct {
+	JWKSFile      string `env:"USER_JWKS_FILE"`
+	JWKSURL       string `env:"USER_JWKS_URL" validate:"omitempty,url"`
+	JWTIssuer     string `env:"USER_JWT_ISSUER"`
+	JWTAudience   string `env:"USER_JWT_AUDIENCE"`
+	GatewaySecret string `env:"GATEWAY_SECRET,secret"`
+}
+
+// Config loads the JWKS and fails unless at least one of the two ways to authenticate is configured.
+func (o UserAuthOptions) Config() (UserAuthConfig, error) {
+	config := UserAuthConfig{GatewaySecret: o.GatewaySecret}
+
+	var keys auth.KeySource
+	var err error
+	if o.JWKSFile != "" {
+		keys, err = auth.NewJWKSFile(o.JWKSFile)
+	} else if o.JWKSURL != "" {
+		keys, err = auth.NewJWKSURL(o.JWKSURL, 10*time.Minute)
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if keys != nil {
+		config.Tokens, err = auth.NewUserTokenVerifier(auth.UserTokenConfig{
+			Keys:     keys,
+			Issuer:   o.JWTIssuer,
+			Audience: o.JWTAudience,
+		})
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if config.Tokens == nil && config.GatewaySecret == "" {
+		return config, errors.New("neither a JWKS nor GATEWAY_SECRET is configured")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrUsage is returned by Command for unknown arguments.
+var ErrUsage = errors.New("usage: migrate [up | down [steps] | status]")
+
+// Command runs the migrate subcommand of a service binary with the arguments after "migrate":
+// up (the default), down [steps] reverting one migration unless told otherwise, and status.
+// Results are written to out.
+func Command(ctx context.Context, migrator *Migrator, args []string, out io.Writer) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		if len(args) > 1 {
+			return ErrUsage
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		for _, version := range applied {
+			fmt.Fprintln(out, "applied", version)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 2 {
+			return ErrUsage
+		}
+		if len(args) == 2 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return ErrUsage
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, version := range reverted {
+			fmt.Fprintln(out, "reverted", version)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		if len(args) > 1 {
+			return ErrUsage
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				state += " (modified since)"
+			}
+			fmt.Fprintf(out, "%-60s %s\n", status.Version, state)
+		}
+	default:
+		return ErrUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migration is a pair of <version>_<name>.up.sql and <version>_<name>.down.sql files. The version
+// is the whole file name without the suffix, migrations run in its lexical order, so the usual
+// YYYYMMDD_name prefix keeps them in date order.
+type Migration struct {
+	Version string
+	Up      string
+	Down    string
+}
+
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+type Config struct {
+	Table   string // default schema_migrations
+	LockKey int64  // pg_advisory_lock key, default derived from Table
+
+	// Include adds the migrations shipped with shared packages, e.g. idempotency.Migrations(), with
+	// their files at the root. They run in one version order with the service's own migrations.
+	Include []fs.FS
+}
+
+// Status is a migration and whether it was applied.
+type Status struct {
+	Version   string
+	AppliedAt *time.Time
+	Modified  bool // the up file changed after it was applied
+}
+
+// Migrator applies embedded migrations to Postgres. Every run holds an advisory lock for the
+// whole database, so replicas booting together apply each migration once.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	table      string
+	lockKey    int64
+}
+
+// New reads the migrations in dir of fsys, usually an embed.FS of the service.
+func New(db *gorm.DB, fsys fs.FS, dir string, config Config) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql db: %w", err)
+	}
+
+	migrations, err := LoadAll(fsys, dir, config.Include...)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Table == "" {
+		config.Table = "schema_migrations"
+	}
+	if config.LockKey == 0 {
+		config.LockKey = lockKey(config.Table)
+	}
+
+	return &Migrator{db: sqlDB, migrations: migrations, table: config.Table, lockKey: config.LockKey}, nil
+}
+
+// LoadAll reads the migrations in dir of fsys and those of include, see Config.Include, in one version order.
+// A version found more than once is an error.
+func LoadAll(fsys fs.FS, dir string, include ...fs.FS) ([]Migration, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, included := range include {
+		more, err := Load(included, ".")
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, more...)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migration %s is included more than once", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Load reads and pairs the .up.sql and .down.sql files in dir. Every up file needs its down file and
+// every down file its up file, a migration that cannot be undone says so in a down file that fails.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[string]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		var version string
+		var up bool
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			version, up = strings.TrimSuffix(name, ".up.sql"), true
+		case strings.HasSuffix(name, ".down.sql"):
+			version = strings.TrimSuffix(name, ".down.sql")
+		default:
+			return nil, fmt.Errorf("migration %s has to end in .up.sql or .down.sql", name)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version}
+			byVersion[version] = migration
+		}
+		if up {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", migration.Version)
+		}
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %s has no down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns their versions.
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	var applied []string
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if record, ok := done[migration.Version]; ok {
+				if record.checksum != "" && record.checksum != migration.checksum() {
+					logging.L().Warn("applied migration was modified, it is not run again", zap.String("version", migration.Version))
+				}
+				continue
+			}
+
+			start := time.Now()
+			err := m.run(ctx, conn, migration.Up,
+				"INSERT INTO "+m.table+" (version, checksum) VALUES ($1, $2)", migration.Version, migration.checksum())
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration.Version, err)
+			}
+			logging.L().Info("applied migration", zap.String("version", migration.Version), zap.Duration("duration", time.Since(start)))
+			applied = append(applied, migration.Version)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns their versions.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]string, error) {
+	var reverted []string
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			err := m.run(ctx, conn, migration.Down, "DELETE FROM "+m.table+" WHERE version = $1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migration.Version, err)
+			}
+			logging.L().Info("reverted migration", zap.String("version", migration.Version))
+			reverted = append(reverted, migration.Version)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists the known migrations in order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version}
+			if record, ok := done[migration.Version]; ok {
+				appliedAt := record.appliedAt
+				status.AppliedAt = &appliedAt
+				status.Modified = record.checksum != "" && record.checksum != migration.checksum()
+			}
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// locked runs fn on a single connection holding the advisory lock, session locks belong to a connection.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	//the pool's statement_timeout would cut off long migrations and waiting for the lock, RESET restores it
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 0"); err != nil {
+		return fmt.Errorf("failed to lift statement timeout: %w", err)
+	}
+	defer func() {
+		resetCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(resetCtx, "RESET statement_timeout"); err != nil {
+			logging.L().Error("failed to reset statement timeout", zap.Error(err))
+		}
+	}()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		//unlocked even when ctx is done, the connection goes back to the pool
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", m.lockKey); err != nil {
+			logging.L().Error("failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+m.table+` (
+		version varchar(255) PRIMARY KEY,
+		checksum char(64),
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", m.table, err)
+	}
+
+	return fn(conn)
+}
+
+type appliedRecord struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[string]appliedRecord, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, COALESCE(checksum, ''), applied_at FROM "+m.table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]appliedRecord{}
+	for rows.Next() {
+		var version string
+		var record appliedRecord
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = record
+	}
+
+	return applied, rows.Err()
+}
+
+// run executes script and the bookkeeping statement in one transaction, a failed migration leaves nothing behind.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, script string, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	//no arguments, so the driver sends the script as is and it may hold several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func lockKey(table string) int64 {
+	sum := sha256.Sum256([]byte("migrate:" + table))
+	var key int64
+	for _, b := range sum[:8] {
+		key = key<<8 | int64(b)
+	}
+
+	return key
+}
@@ -0,0 +1,251 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func file(data string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(data)}
+}
+
+func versions(migrations []Migration) []string {
+	var list []string
+	for _, migration := range migrations {
+		list = append(list, migration.Version)
+	}
+
+	return list
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/20260201_b.up.sql":   file("up b"),
+		"migrations/20260201_b.down.sql": file("down b"),
+		"migrations/20260101_a.up.sql":   file("up a"),
+		"migrations/20260101_a.down.sql": file("down a"),
+		"migrations/README.md":           file("not a migration"),
+		"migrations/nested/x.up.sql":     file("ignored"),
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []Migration{
+		{Version: "20260101_a", Up: "up a", Down: "down a"},
+		{Version: "20260201_b", Up: "up b", Down: "down b"},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Fatalf("got %+v, want %+v", migrations, want)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"up without down", fstest.MapFS{"m/1_a.up.sql": file("up")}, "1_a has no down file"},
+		{"down without up", fstest.MapFS{"m/1_a.down.sql": file("down")}, "1_a has no up file"},
+		{"unknown suffix", fstest.MapFS{"m/1_a.sql": file("up")}, "has to end in .up.sql or .down.sql"},
+		{"missing directory", fstest.MapFS{}, "failed to read migrations"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(tc.fsys, "m")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("got error %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	service := fstest.MapFS{
+		"migrations/20260101_a.up.sql":   file("up a"),
+		"migrations/20260101_a.down.sql": file("down a"),
+		"migrations/20260301_c.up.sql":   file("up c"),
+		"migrations/20260301_c.down.sql": file("down c"),
+	}
+	shared := fstest.MapFS{
+		"20260201_b.up.sql":   file("up b"),
+		"20260201_b.down.sql": file("down b"),
+	}
+
+	migrations, err := LoadAll(service, "migrations", shared)
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if got, want := versions(migrations), []string{"20260101_a", "20260201_b", "20260301_c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got versions %v, want %v", got, want)
+	}
+
+	if _, err := LoadAll(service, "migrations", shared, shared); err == nil || !strings.Contains(err.Error(), "included more than once") {
+		t.Fatalf("got error %v for a migration included twice", err)
+	}
+	if _, err := LoadAll(service, "migrations", fstest.MapFS{"x.up.sql": file("up")}); err == nil {
+		t.Fatal("an included migration without a down file was accepted")
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	if lockKey("schema_migrations") != lockKey("schema_migrations") {
+		t.Fatal("lock key is not stable")
+	}
+	if lockKey("schema_migrations") == lockKey("other_migrations") {
+		t.Fatal("different tables share a lock key")
+	}
+}
+
+// newMockMigrator returns a migrator on sqlmock, whose expectations have to be met in order.
+func newMockMigrator(t *testing.T, fsys fs.FS) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := Load(fsys, ".")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	return &Migrator{db: db, migrations: migrations, table: "schema_migrations", lockKey: 42}, mock
+}
+
+func exact(sql string) string {
+	return "^" + regexp.QuoteMeta(sql) + "$"
+}
+
+// expectLocked expects the statements of locked around fn, which adds its own in between.
+func expectLocked(mock sqlmock.Sqlmock, applied *sqlmock.Rows, fn func()) {
+	mock.ExpectExec(exact("SET statement_timeout = 0")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(exact("SELECT pg_advisory_lock($1)")).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(exact("SELECT version, COALESCE(checksum, ''), applied_at FROM schema_migrations")).WillReturnRows(applied)
+	fn()
+	mock.ExpectExec(exact("SELECT pg_advisory_unlock($1)")).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(exact("RESET statement_timeout")).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+var twoMigrations = fstest.MapFS{
+	"1_a.up.sql":   file("CREATE TABLE a (id int)"),
+	"1_a.down.sql": file("DROP TABLE a"),
+	"2_b.up.sql":   file("CREATE TABLE b (id int)"),
+	"2_b.down.sql": file("DROP TABLE b"),
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	migrator, mock := newMockMigrator(t, twoMigrations)
+	a := Migration{Up: "CREATE TABLE a (id int)"}
+	b := Migration{Up: "CREATE TABLE b (id int)"}
+
+	applied := sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).AddRow("1_a", a.checksum(), time.Now())
+	expectLocked(mock, applied, func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(exact(b.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(exact("INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)")).
+			WithArgs("2_b", b.checksum()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	})
+
+	got, err := migrator.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"2_b"}) {
+		t.Fatalf("applied %v, want [2_b]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpRollsBackAFailedMigration(t *testing.T) {
+	migrator, mock := newMockMigrator(t, twoMigrations)
+	a := Migration{Up: "CREATE TABLE a (id int)"}
+	b := Migration{Up: "CREATE TABLE b (id int)"}
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}), func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(exact(a.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").WithArgs("1_a", a.checksum()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(exact(b.Up)).WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+	})
+
+	got, err := migrator.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to apply migration 2_b") {
+		t.Fatalf("got error %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"1_a"}) {
+		t.Fatalf("applied %v, want [1_a]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownRevertsNewestFirst(t *testing.T) {
+	migrator, mock := newMockMigrator(t, twoMigrations)
+
+	applied := sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow("1_a", "", time.Now()).
+		AddRow("2_b", "", time.Now())
+	expectLocked(mock, applied, func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(exact("DROP TABLE b")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(exact("DELETE FROM schema_migrations WHERE version = $1")).WithArgs("2_b").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	})
+
+	got, err := migrator.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"2_b"}) {
+		t.Fatalf("reverted %v, want [2_b]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusFlagsModifiedMigrations(t *testing.T) {
+	migrator, mock := newMockMigrator(t, twoMigrations)
+
+	appliedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	applied := sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).AddRow("1_a", strings.Repeat("0", 64), appliedAt)
+	expectLocked(mock, applied, func() {})
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(statuses))
+	}
+	if statuses[0].AppliedAt == nil || !statuses[0].AppliedAt.Equal(appliedAt) || !statuses[0].Modified {
+		t.Fatalf("got %+v for the applied and modified 1_a", statuses[0])
+	}
+	if statuses[1].AppliedAt != nil || statuses[1].Modified {
+		t.Fatalf("got %+v for the pending 2_b", statuses[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/logging"
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/metrics"
+	"go.uber.org/zap"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+const replicaResolver = "replica"
+
+// Options tunes the connection pools. It is embedded in the service configs, so every service
+// reads the same DB_* keys, see the config package.
+type Options struct {
+	MaxOpenConns       int           `env:"DB_MAX_OPEN_CONNS" default:"25" validate:"min=1"`
+	MaxIdleConns       int           `env:"DB_MAX_IDLE_CONNS" default:"10" validate:"min=0"`
+	ConnMaxLifetime    time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime    time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+	StatementTimeout   time.Duration `env:"DB_STATEMENT_TIMEOUT" default:"30s"` // zero disables it
+	SlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms"`
+	PrepareStmt        bool          `env:"DB_PREPARE_STATEMENTS" default:"false"` // off for pgbouncer in transaction mode
+	ReplicaHosts       []string      `env:"DB_REPLICA_HOSTS"`                      // host or host:port, same credentials as the primary
+}
+
+type Config struct {
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	ApplicationName string // shows up in pg_stat_activity, usually the service name
+	Options
+}
+
+// Open connects to the primary and, when ReplicaHosts is set, registers the replicas for
+// ReadReplica. Queries are logged through logging.GormLogger and recorded by metrics.GormPlugin.
+// The pools are sized per database, so the primary and each replica get MaxOpenConns.
+func Open(config Config) (*gorm.DB, error) {
+	config.MaxIdleConns = min(config.MaxIdleConns, config.MaxOpenConns)
+
+	db, err := gorm.Open(gormpostgres.Open(config.dsn(config.Host, config.Port)), &gorm.Config{
+		PrepareStmt: config.PrepareStmt,
+		Logger:      &logging.GormLogger{SlowThreshold: config.SlowQueryThreshold, Level: gormlogger.Warn},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql db: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		logging.L().Warn("gorm metrics disabled", zap.Error(err))
+	}
+
+	if len(config.ReplicaHosts) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(config.ReplicaHosts))
+		for _, replica := range config.ReplicaHosts {
+			host, port, err := splitHostPort(replica, config.Port)
+			if err != nil {
+				return nil, fmt.Errorf("invalid replica host %q: %w", replica, err)
+			}
+			replicas = append(replicas, gormpostgres.Open(config.dsn(host, port)))
+		}
+
+		//registered by name rather than globally: only queries opting in with ReadReplica leave the primary
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}, replicaResolver).
+			SetMaxOpenConns(config.MaxOpenConns).
+			SetMaxIdleConns(config.MaxIdleConns).
+			SetConnMaxLifetime(config.ConnMaxLifetime).
+			SetConnMaxIdleTime(config.ConnMaxIdleTime)
+		if err := db.Use(resolver); err != nil {
+			return nil, fmt.Errorf("failed to register read replicas: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+// ReadReplica sends the reads of db to a replica, when there are any. Use it for heavy reads that
+// can be a little behind the primary, e.g. listings, never to read back what the request just wrote.
+// Writes and transactions stay on the primary.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(replicaResolver))
+}
+
+// dsn builds a keyword/value connection string. Keywords pgx does not know, like statement_timeout,
+// are sent to the server as run-time parameters of every connection.
+func (c Config) dsn(host string, port int) string {
+	params := []string{
+		"host=" + quote(host),
+		fmt.Sprintf("port=%d", port),
+		"user=" + quote(c.User),
+		"password=" + quote(c.Password),
+		"dbname=" + quote(c.Name),
+		"sslmode=" + quote(c.SSLMode),
+	}
+	if c.ApplicationName != "" {
+		params = append(params, "application_name="+quote(c.ApplicationName))
+	}
+	if c.StatementTimeout > 0 {
+		params = append(params, fmt.Sprintf("statement_timeout=%d", c.StatementTimeout.Milliseconds()))
+	}
+
+	return strings.Join(params, " ")
+}
+
+// quote escapes a value of a keyword/value connection string, passwords may contain anything.
+func quote(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+func splitHostPort(value string, defaultPort int) (string, int, error) {
+	if !strings.Contains(value, ":") {
+		return value, defaultPort, nil
+	}
+
+	host, portValue, err := net.SplitHostPort(value)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port %q", portValue)
+	}
+
+	return host, port, nil
+}
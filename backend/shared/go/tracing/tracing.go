@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TraceparentHeader   = "traceparent"
+	CorrelationIDHeader = "x-correlation-id"
+	RequestIDHeader     = "x-request-id"
+)
+
+type contextKey string
+
+const (
+	traceContextKey    contextKey = "traceContext"
+	correlationIDKey   contextKey = "correlationID"
+	requestIDKey       contextKey = "requestID"
+	maxRequestIDLength            = 128
+	sampledFlag        byte       = 0x01
+	traceparentLength             = 55
+)
+
+// TraceContext is the W3C trace context of one hop: the trace it belongs to and the span of the current service.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Flags   byte
+}
+
+// NewTraceContext starts a new sampled trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   sampledFlag,
+	}
+}
+
+// Child keeps the trace and flags but gets a new span id, used for every outgoing call.
+func (t TraceContext) Child() TraceContext {
+	return TraceContext{
+		TraceID: t.TraceID,
+		SpanID:  randomHex(8),
+		Flags:   t.Flags,
+	}
+}
+
+// String formats the context as a version 00 traceparent header value.
+func (t TraceContext) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", t.TraceID, t.SpanID, t.Flags)
+}
+
+// ParseTraceparent parses a traceparent header, see https://www.w3.org/TR/trace-context/#traceparent-header.
+func ParseTraceparent(value string) (TraceContext, error) {
+	value = strings.TrimSpace(value)
+	parts := strings.Split(value, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceContext{}, errors.New("invalid traceparent format")
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || version[0] == 0xff {
+		return TraceContext{}, errors.New("invalid traceparent version")
+	}
+	//version 00 has exactly four fields, later versions may append more
+	if version[0] == 0 && len(value) != traceparentLength {
+		return TraceContext{}, errors.New("invalid traceparent format")
+	}
+
+	traceID, spanID := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !isHex(traceID) || !isHex(spanID) || isZero(traceID) || isZero(spanID) {
+		return TraceContext{}, errors.New("invalid traceparent ids")
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return TraceContext{}, errors.New("invalid traceparent flags")
+	}
+
+	return TraceContext{TraceID: traceID, SpanID: spanID, Flags: flags[0]}, nil
+}
+
+func WithTraceContext(ctx context.Context, traceContext TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey, traceContext)
+}
+
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	traceContext, ok := ctx.Value(traceContextKey).(TraceContext)
+	return traceContext, ok
+}
+
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationIDFromContext returns the correlation id of the request or message being handled, or "" outside of one.
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey).(string)
+	return correlationID
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the x-request-id of the request or message being handled, or "" outside of one.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// TraceIDFromContext is a shortcut for log fields.
+func TraceIDFromContext(ctx context.Context) string {
+	traceContext, _ := TraceContextFromContext(ctx)
+	return traceContext.TraceID
+}
+
+// Extract restores the trace, request and correlation context from carrier headers (HTTP or kafka).
+// A missing or invalid traceparent starts a new trace and a missing or malformed request id is
+// replaced by a fresh uuid. A missing correlation id falls back to the request id, so every hop
+// ends up with all three.
+func Extract(ctx context.Context, get func(key string) string) context.Context {
+	traceContext, err := ParseTraceparent(get(TraceparentHeader))
+	if err != nil {
+		traceContext = NewTraceContext()
+	} else {
+		traceContext = traceContext.Child()
+	}
+
+	requestID := get(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+
+	correlationID := get(CorrelationIDHeader)
+	if correlationID == "" {
+		correlationID = requestID
+	}
+
+	ctx = WithTraceContext(ctx, traceContext)
+	ctx = WithRequestID(ctx, requestID)
+	return WithCorrelationID(ctx, correlationID)
+}
+
+// Inject writes the context of ctx into outgoing headers, with a new span id for the call.
+// Nothing is written when ctx carries no trace, so callers outside a request do not invent one.
+func Inject(ctx context.Context, set func(key string, value string)) {
+	if traceContext, ok := TraceContextFromContext(ctx); ok {
+		set(TraceparentHeader, traceContext.Child().String())
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		set(RequestIDHeader, requestID)
+	}
+	if correlationID := CorrelationIDFromContext(ctx); correlationID != "" {
+		set(CorrelationIDHeader, correlationID)
+	}
+}
+
+// InjectHTTP adds the trace headers of ctx to an outgoing HTTP request.
+func InjectHTTP(ctx context.Context, header http.Header) {
+	Inject(ctx, header.Set)
+}
+
+// Middleware restores the trace context of incoming requests and echoes the request and correlation ids in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := Extract(r.Context(), r.Header.Get)
+		w.Header().Set(RequestIDHeader, RequestIDFromContext(ctx))
+		w.Header().Set(CorrelationIDHeader, CorrelationIDFromContext(ctx))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// request ids end up in logs and headers, anything beyond a plain token is replaced
+func validRequestID(value string) bool {
+	if value == "" || len(value) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHex(value string) bool {
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+func isZero(value string) bool {
+	return strings.Trim(value, "0") == ""
+}
@@ -22,10 +22,6 @@ func WriteJSONResponse(w http.ResponseWriter, status int, v any) error {
 	return json.NewEncoder(w).Encode(v)
 }
 
-func WriteError(w http.ResponseWriter, status int, err error) {
-	WriteJSONResponse(w, status, map[string]string{"error": err.Error()})
-}
-
 type JSONB map[string]interface{}
 
 func (j *JSONB) Scan(value interface{}) error { //json to map[string]interface{}, gorm calls this method when retrieving from db
@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"github.com/google/uuid"
+)
+
+type FilterOp int
+
+const (
+	OpEq     FilterOp = iota // ?status=active
+	OpIn                     // ?status=active,draft
+	OpRange                  // ?price_from=10&price_to=20, both inclusive and optional
+	OpSearch                 // ?search=shirt, case-insensitive substring match over all Columns
+)
+
+type FilterKind int
+
+const (
+	KindString FilterKind = iota
+	KindInt
+	KindNumber
+	KindBool
+	KindTime // RFC 3339 or a plain date
+	KindUUID
+)
+
+// Filter exposes a column as a query parameter. Columns come from code, never from the request.
+type Filter struct {
+	Param   string
+	Columns []string // OpSearch matches any of them, the other operators use the first
+	Op      FilterOp
+	Kind    FilterKind
+}
+
+// ListSpec whitelists what a list endpoint can be sorted and filtered by.
+type ListSpec struct {
+	Sorts        map[string]string // sort parameter to column, e.g. "price": "price"
+	DefaultSort  string            // e.g. "-created_at", a leading "-" sorts descending
+	TieBreaker   string            // unique column appended to every sort so cursors are stable, default "id"
+	Filters      []Filter
+	DefaultLimit int // default 20
+	MaxLimit     int // default 100
+}
+
+// PageParams are the query parameters every list endpoint accepts next to its filters.
+type PageParams struct {
+	Cursor       string `query:"cursor"`
+	Limit        int    `query:"limit"`
+	Sort         string `query:"sort"` // comma separated, e.g. "-price,name"
+	IncludeTotal bool   `query:"include_total"`
+}
+
+type sortColumn struct {
+	column string
+	desc   bool
+}
+
+type condition struct {
+	sql  string
+	args []any
+}
+
+// ListQuery is a parsed and validated list request, see Paginate.
+type ListQuery struct {
+	Limit        int
+	IncludeTotal bool
+
+	sorts      []sortColumn
+	sortKey    string
+	conditions []condition
+	cursor     string // verified by Paginate, which holds the key
+}
+
+// ParseListQuery reads the page parameters and the filters of spec from r. Unknown sort fields
+// and malformed filter values are invalid arguments, the cursor is checked by Paginate.
+func ParseListQuery(r *http.Request, spec ListSpec) (*ListQuery, error) {
+	var params PageParams
+	if err := DecodeQueryParams(&params, r); err != nil {
+		return nil, apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid query parameters")
+	}
+
+	query := &ListQuery{IncludeTotal: params.IncludeTotal}
+
+	defaultLimit, maxLimit := spec.DefaultLimit, spec.MaxLimit
+	if defaultLimit <= 0 {
+		defaultLimit = 20
+	}
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+	switch {
+	case params.Limit < 0:
+		return nil, apperror.InvalidArgument("limit must not be negative")
+	case params.Limit == 0:
+		query.Limit = defaultLimit
+	default:
+		query.Limit = min(params.Limit, maxLimit)
+	}
+
+	sorts, err := parseSort(params.Sort, spec)
+	if err != nil {
+		return nil, err
+	}
+	query.sorts = sorts
+	query.sortKey = sortKey(sorts)
+
+	values := r.URL.Query()
+	for _, filter := range spec.Filters {
+		conditions, err := filter.conditions(values.Get, values.Has)
+		if err != nil {
+			return nil, err
+		}
+		query.conditions = append(query.conditions, conditions...)
+	}
+	query.cursor = params.Cursor
+
+	return query, nil
+}
+
+// Where adds a condition that is not exposed as a filter, e.g. the owner of the listed rows.
+func (q *ListQuery) Where(sql string, args ...any) *ListQuery {
+	q.conditions = append(q.conditions, condition{sql: sql, args: args})
+	return q
+}
+
+func parseSort(value string, spec ListSpec) ([]sortColumn, error) {
+	if value == "" {
+		value = spec.DefaultSort
+	}
+	tieBreaker := spec.TieBreaker
+	if tieBreaker == "" {
+		tieBreaker = "id"
+	}
+
+	var sorts []sortColumn
+	seen := map[string]bool{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		desc := strings.HasPrefix(field, "-")
+		name := strings.TrimPrefix(field, "-")
+		column, ok := spec.Sorts[name]
+		if !ok {
+			return nil, apperror.InvalidArgument(fmt.Sprintf("cannot sort by %q", name))
+		}
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		sorts = append(sorts, sortColumn{column: column, desc: desc})
+	}
+
+	//the tie breaker follows the direction of the first sort, so the default newest first stays newest first
+	if !seen[tieBreaker] {
+		desc := len(sorts) > 0 && sorts[0].desc
+		sorts = append(sorts, sortColumn{column: tieBreaker, desc: desc})
+	}
+
+	return sorts, nil
+}
+
+func sortKey(sorts []sortColumn) string {
+	parts := make([]string, 0, len(sorts))
+	for _, sort := range sorts {
+		if sort.desc {
+			parts = append(parts, "-"+sort.column)
+		} else {
+			parts = append(parts, sort.column)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (f Filter) conditions(get func(string) string, has func(string) bool) ([]condition, error) {
+	if len(f.Columns) == 0 {
+		return nil, fmt.Errorf("filter %s has no columns", f.Param)
+	}
+	column := f.Columns[0]
+
+	switch f.Op {
+	case OpRange:
+		var conditions []condition
+		if from := get(f.Param + "_from"); from != "" {
+			value, err := f.parse(f.Param+"_from", from)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition{sql: column + " >= ?", args: []any{value}})
+		}
+		if to := get(f.Param + "_to"); to != "" {
+			value, err := f.parse(f.Param+"_to", to)
+			if err != nil {
+				return nil, err
+			}
+			//a plain date as upper bound means the whole day
+			if t, ok := value.(time.Time); ok && len(to) == len(time.DateOnly) {
+				conditions = append(conditions, condition{sql: column + " < ?", args: []any{t.AddDate(0, 0, 1)}})
+			} else {
+				conditions = append(conditions, condition{sql: column + " <= ?", args: []any{value}})
+			}
+		}
+		return conditions, nil
+	}
+
+	if !has(f.Param) || get(f.Param) == "" {
+		return nil, nil
+	}
+	raw := get(f.Param)
+
+	switch f.Op {
+	case OpIn:
+		parts := strings.Split(raw, ",")
+		values := make([]any, 0, len(parts))
+		for _, part := range parts {
+			value, err := f.parse(f.Param, strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return []condition{{sql: column + " IN ?", args: []any{values}}}, nil
+	case OpSearch:
+		pattern := "%" + escapeLike(raw) + "%"
+		parts := make([]string, 0, len(f.Columns))
+		args := make([]any, 0, len(f.Columns))
+		for _, searchColumn := range f.Columns {
+			parts = append(parts, searchColumn+" ILIKE ?")
+			args = append(args, pattern)
+		}
+		return []condition{{sql: "(" + strings.Join(parts, " OR ") + ")", args: args}}, nil
+	default:
+		value, err := f.parse(f.Param, raw)
+		if err != nil {
+			return nil, err
+		}
+		return []condition{{sql: column + " = ?", args: []any{value}}}, nil
+	}
+}
+
+func (f Filter) parse(param string, raw string) (any, error) {
+	var value any
+	var err error
+
+	switch f.Kind {
+	case KindInt:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case KindNumber:
+		value, err = strconv.ParseFloat(raw, 64)
+	case KindBool:
+		value, err = strconv.ParseBool(raw)
+	case KindTime:
+		value, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			value, err = time.Parse(time.DateOnly, raw)
+		}
+	case KindUUID:
+		var id uuid.UUID
+		id, err = uuid.Parse(raw)
+		value = id.String()
+	default:
+		value = raw
+	}
+	if err != nil {
+		return nil, apperror.InvalidArgument(fmt.Sprintf("invalid value %q for %s", raw, param))
+	}
+
+	return value, nil
+}
+
+// escapeLike makes the search term match literally, % and _ are wildcards otherwise.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+)
+
+var testSpec = ListSpec{
+	Sorts:       map[string]string{"created_at": "created_at", "price": "price", "name": "name"},
+	DefaultSort: "-created_at",
+	Filters: []Filter{
+		{Param: "status", Columns: []string{"status"}, Op: OpIn},
+		{Param: "seller_id", Columns: []string{"seller_id"}, Op: OpEq, Kind: KindUUID},
+		{Param: "price", Columns: []string{"price"}, Op: OpRange, Kind: KindNumber},
+		{Param: "created", Columns: []string{"created_at"}, Op: OpRange, Kind: KindTime},
+		{Param: "search", Columns: []string{"name", "description"}, Op: OpSearch},
+	},
+	DefaultLimit: 10,
+	MaxLimit:     50,
+}
+
+func parseQuery(t *testing.T, rawQuery string) (*ListQuery, error) {
+	t.Helper()
+	return ParseListQuery(httptest.NewRequest("GET", "/items?"+rawQuery, nil), testSpec)
+}
+
+func isInvalidArgument(err error) bool {
+	var appErr *apperror.Error
+	return errors.As(err, &appErr) && appErr.Code == apperror.CodeInvalidArgument
+}
+
+func TestParseListQueryLimit(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"", 10},
+		{"limit=5", 5},
+		{"limit=500", 50},
+	} {
+		query, err := parseQuery(t, tc.query)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.query, err)
+		}
+		if query.Limit != tc.want {
+			t.Errorf("%q: got limit %d, want %d", tc.query, query.Limit, tc.want)
+		}
+	}
+
+	for _, rawQuery := range []string{"limit=-1", "limit=ten"} {
+		if _, err := parseQuery(t, rawQuery); !isInvalidArgument(err) {
+			t.Errorf("%q: got %v, want an invalid argument", rawQuery, err)
+		}
+	}
+}
+
+func TestParseListQuerySort(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		sort string
+		want string
+	}{
+		{"default sort with the tie breaker following it", "", "-created_at,-id"},
+		{"several fields", "price,-name", "price,-name,id"},
+		{"repeated field counts once", "price,-price", "price,id"},
+		{"blank entries are skipped", " price , ", "price,id"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := parseQuery(t, url.Values{"sort": {tc.sort}}.Encode())
+			if err != nil {
+				t.Fatalf("ParseListQuery: %v", err)
+			}
+			if query.sortKey != tc.want {
+				t.Fatalf("got sort %q, want %q", query.sortKey, tc.want)
+			}
+		})
+	}
+
+	//only whitelisted fields, a column name that is not a sort parameter included
+	for _, sort := range []string{"password", "seller_id", "price;drop table items"} {
+		if _, err := parseQuery(t, url.Values{"sort": {sort}}.Encode()); !isInvalidArgument(err) {
+			t.Errorf("sort %q: got %v, want an invalid argument", sort, err)
+		}
+	}
+
+	spec := testSpec
+	spec.TieBreaker = "sku"
+	query, err := ParseListQuery(httptest.NewRequest("GET", "/items?sort=price", nil), spec)
+	if err != nil || query.sortKey != "price,sku" {
+		t.Fatalf("got %v, %v, want the sort to end in the custom tie breaker", query, err)
+	}
+}
+
+func TestParseListQueryFilters(t *testing.T) {
+	sellerID := "8f14e45f-ceea-467a-9f4f-6b1a1a1a1a1a"
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		query url.Values
+		want  []condition
+	}{
+		{"no filters", url.Values{}, nil},
+		{"empty values are ignored", url.Values{"status": {""}, "search": {""}}, nil},
+		{"eq", url.Values{"seller_id": {sellerID}}, []condition{{sql: "seller_id = ?", args: []any{sellerID}}}},
+		{"in", url.Values{"status": {"active, draft"}}, []condition{{sql: "status IN ?", args: []any{[]any{"active", "draft"}}}}},
+		{"range with both bounds", url.Values{"price_from": {"10"}, "price_to": {"20.5"}}, []condition{
+			{sql: "price >= ?", args: []any{10.0}},
+			{sql: "price <= ?", args: []any{20.5}},
+		}},
+		{"range with one bound", url.Values{"price_to": {"20"}}, []condition{{sql: "price <= ?", args: []any{20.0}}}},
+		{"plain date as upper bound covers the whole day", url.Values{"created_from": {"2026-10-01"}, "created_to": {"2026-10-01"}}, []condition{
+			{sql: "created_at >= ?", args: []any{day}},
+			{sql: "created_at < ?", args: []any{day.AddDate(0, 0, 1)}},
+		}},
+		{"timestamp as upper bound is exact", url.Values{"created_to": {"2026-10-01T12:00:00Z"}}, []condition{
+			{sql: "created_at <= ?", args: []any{day.Add(12 * time.Hour)}},
+		}},
+		{"search over every column", url.Values{"search": {"shirt"}}, []condition{
+			{sql: "(name ILIKE ? OR description ILIKE ?)", args: []any{"%shirt%", "%shirt%"}},
+		}},
+		{"search wildcards match literally", url.Values{"search": {`50%_off\`}}, []condition{
+			{sql: "(name ILIKE ? OR description ILIKE ?)", args: []any{`%50\%\_off\\%`, `%50\%\_off\\%`}},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := parseQuery(t, tc.query.Encode())
+			if err != nil {
+				t.Fatalf("ParseListQuery: %v", err)
+			}
+			if !reflect.DeepEqual(query.conditions, tc.want) {
+				t.Fatalf("got conditions %+v, want %+v", query.conditions, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseListQueryRejectsFilterValues(t *testing.T) {
+	for _, query := range []url.Values{
+		{"seller_id": {"not-a-uuid"}},
+		{"price_from": {"cheap"}},
+		{"created_to": {"01/10/2026"}},
+		{"status": {"active"}, "price_to": {"1e"}},
+	} {
+		_, err := parseQuery(t, query.Encode())
+		if !isInvalidArgument(err) {
+			t.Errorf("%s: got %v, want an invalid argument", query.Encode(), err)
+		}
+	}
+}
+
+func TestListQueryWhere(t *testing.T) {
+	query, err := parseQuery(t, "status=active")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query.Where("user_id = ?", "user-1")
+	if len(query.conditions) != 2 || query.conditions[1].sql != "user_id = ?" {
+		t.Fatalf("got conditions %+v", query.conditions)
+	}
+	if !strings.Contains(query.conditions[0].sql, "status") {
+		t.Fatalf("the filter condition was replaced: %+v", query.conditions)
+	}
+}
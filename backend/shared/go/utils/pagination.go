@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var errCursorSignature = errors.New("cursor signature does not match")
+
+// Paginated is the response envelope of every list endpoint.
+type Paginated[T any] struct {
+	Data       []T      `json:"data"`
+	Pagination PageInfo `json:"pagination"`
+}
+
+// PageInfo carries the cursors to pass as ?cursor= for the neighbouring pages, nil when there is
+// none. Total is only counted when the request asked for it with ?include_total=true.
+type PageInfo struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	PrevCursor *string `json:"prev_cursor"`
+	Total      *int64  `json:"total,omitempty"`
+}
+
+// MapPage converts the rows of page, e.g. models into response DTOs.
+func MapPage[T any, R any](page Paginated[T], fn func(T) R) Paginated[R] {
+	data := make([]R, 0, len(page.Data))
+	for _, row := range page.Data {
+		data = append(data, fn(row))
+	}
+
+	return Paginated[R]{Data: data, Pagination: page.Pagination}
+}
+
+// cursor is the position after (or before, when Backward) a row: the values of its sort columns.
+type cursor struct {
+	Sort     string            `json:"s"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+// encode returns the cursor as <payload>.<signature>, both base64url.
+func (c cursor) encode(key []byte) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + signCursor(key, payload), nil
+}
+
+func decodeCursor(value string, key []byte) (*cursor, error) {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(key, payload))) {
+		return nil, errCursorSignature
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func signCursor(key []byte, payload string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+var schemaCache sync.Map
+
+// Paginate runs query against db, which may already be scoped, e.g. to the owner of the rows or
+// with preloads. Pages are keyset based: the sort columns must be columns of T and NOT NULL, the
+// tie breaker makes the order total so no row is skipped or repeated between pages.
+//
+// Cursors are signed with key so clients cannot forge positions, every instance of a service needs
+// the same one, usually the required PAGINATION_CURSOR_KEY of its config. Foreign or tampered
+// cursors are invalid arguments.
+func Paginate[T any](ctx context.Context, db *gorm.DB, query *ListQuery, key []byte) (Paginated[T], error) {
+	if len(key) == 0 {
+		return Paginated[T]{}, errors.New("pagination cursor key is not set")
+	}
+
+	var position *cursor
+	if query.cursor != "" {
+		decoded, err := decodeCursor(query.cursor, key)
+		if err != nil || decoded.Sort != query.sortKey || len(decoded.Values) != len(query.sorts) {
+			return Paginated[T]{}, apperror.InvalidArgument("invalid cursor, it has to come from a previous page with the same sort")
+		}
+		position = decoded
+	}
+
+	var model T
+	modelSchema, err := schema.Parse(&model, &schemaCache, db.NamingStrategy)
+	if err != nil {
+		return Paginated[T]{}, fmt.Errorf("failed to parse %T: %w", model, err)
+	}
+
+	fields := make([]*schema.Field, 0, len(query.sorts))
+	for _, sort := range query.sorts {
+		name := sort.column[strings.LastIndex(sort.column, ".")+1:]
+		field := modelSchema.LookUpField(name)
+		if field == nil {
+			return Paginated[T]{}, fmt.Errorf("sort column %s is not a field of %T", sort.column, model)
+		}
+		fields = append(fields, field)
+	}
+
+	q := db.WithContext(ctx)
+	if q.Statement.Model == nil {
+		q = q.Model(&model)
+	}
+	for _, condition := range query.conditions {
+		q = q.Where(condition.sql, condition.args...)
+	}
+	//every statement below starts from the filtered query instead of piling onto it
+	q = q.Session(&gorm.Session{})
+
+	page := Paginated[T]{Data: []T{}, Pagination: PageInfo{Limit: query.Limit}}
+
+	if query.IncludeTotal {
+		var total int64
+		if err := q.Count(&total).Error; err != nil {
+			return Paginated[T]{}, fmt.Errorf("failed to count rows: %w", err)
+		}
+		page.Pagination.Total = &total
+	}
+
+	backward := position != nil && position.Backward
+	find := q
+	if position != nil {
+		sql, args, err := keysetCondition(query.sorts, fields, position.Values, backward)
+		if err != nil {
+			return Paginated[T]{}, err
+		}
+		find = find.Where(sql, args...)
+	}
+	for _, sort := range query.sorts {
+		direction := " ASC"
+		if sort.desc != backward {
+			direction = " DESC"
+		}
+		find = find.Order(sort.column + direction)
+	}
+
+	var rows []T
+	if err := find.Limit(query.Limit + 1).Find(&rows).Error; err != nil {
+		return Paginated[T]{}, fmt.Errorf("failed to list rows: %w", err)
+	}
+
+	hasMore := len(rows) > query.Limit
+	if hasMore {
+		rows = rows[:query.Limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+	page.Data = append(page.Data, rows...)
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	//walking forward there is a previous page whenever we came from a cursor, and the other way round
+	hasNext, hasPrev := hasMore, position != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if hasNext {
+		if page.Pagination.NextCursor, err = rowCursor(ctx, rows[len(rows)-1], fields, query.sortKey, false, key); err != nil {
+			return Paginated[T]{}, err
+		}
+	}
+	if hasPrev {
+		if page.Pagination.PrevCursor, err = rowCursor(ctx, rows[0], fields, query.sortKey, true, key); err != nil {
+			return Paginated[T]{}, err
+		}
+	}
+
+	return page, nil
+}
+
+// keysetCondition selects the rows after the cursor in sort order:
+// (a > ?) OR (a = ? AND b > ?) OR ... with < for descending columns.
+func keysetCondition(sorts []sortColumn, fields []*schema.Field, raw []json.RawMessage, backward bool) (string, []any, error) {
+	values := make([]any, len(raw))
+	for i, field := range fields {
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return "", nil, apperror.Wrap(err, apperror.CodeInvalidArgument, "invalid cursor, it has to come from a previous page with the same sort")
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	var clauses []string
+	var args []any
+	for i, sort := range sorts {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, sorts[j].column+" = ?")
+			args = append(args, values[j])
+		}
+
+		operator := " > ?"
+		if sort.desc != backward {
+			operator = " < ?"
+		}
+		parts = append(parts, sort.column+operator)
+		args = append(args, values[i])
+
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+}
+
+func rowCursor[T any](ctx context.Context, row T, fields []*schema.Field, sortKey string, backward bool, key []byte) (*string, error) {
+	value := reflect.ValueOf(&row).Elem()
+
+	c := cursor{Sort: sortKey, Backward: backward, Values: make([]json.RawMessage, 0, len(fields))}
+	for _, field := range fields {
+		fieldValue, _ := field.ValueOf(ctx, value)
+		data, err := json.Marshal(fieldValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cursor value of %s: %w", field.DBName, err)
+		}
+		c.Values = append(c.Values, data)
+	}
+
+	encoded, err := c.encode(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	return &encoded, nil
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+var testCursorKey = []byte("test-cursor-key-of-at-least-32-chars")
+
+type item struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+func TestCursorSigning(t *testing.T) {
+	c := cursor{Sort: "-created_at,-id", Values: []json.RawMessage{json.RawMessage(`"2026-10-01T00:00:00Z"`), json.RawMessage(`7`)}}
+	encoded, err := c.encode(testCursorKey)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	decoded, err := decodeCursor(encoded, testCursorKey)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if decoded.Sort != c.Sort || len(decoded.Values) != 2 || string(decoded.Values[1]) != "7" || decoded.Backward {
+		t.Fatalf("got %+v, want %+v", decoded, c)
+	}
+
+	payload, signature, _ := strings.Cut(encoded, ".")
+	forged, _ := json.Marshal(cursor{Sort: c.Sort, Values: []json.RawMessage{json.RawMessage(`"2000-01-01T00:00:00Z"`), json.RawMessage(`1`)}})
+	garbage := "not-base64!"
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		key   []byte
+		want  error // nil for any error
+	}{
+		{"signed with another key", encoded, []byte("another-key-of-at-least-32-chars!!"), errCursorSignature},
+		{"payload swapped", base64.RawURLEncoding.EncodeToString(forged) + "." + signature, testCursorKey, errCursorSignature},
+		{"signature dropped", payload, testCursorKey, errCursorSignature},
+		{"empty", "", testCursorKey, errCursorSignature},
+		{"signed but not base64", garbage + "." + signCursor(testCursorKey, garbage), testCursorKey, nil},
+		{"signed but not json", "bm90IGpzb24." + signCursor(testCursorKey, "bm90IGpzb24"), testCursorKey, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeCursor(tc.value, tc.key)
+			if err == nil || (tc.want != nil && !errors.Is(err, tc.want)) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	fields := itemFields(t, "created_at", "id")
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	raw := []json.RawMessage{mustJSON(t, at), mustJSON(t, 7)}
+
+	for _, tc := range []struct {
+		name     string
+		sorts    []sortColumn
+		backward bool
+		want     string
+	}{
+		{"ascending", []sortColumn{{"created_at", false}, {"id", false}}, false, "((created_at > ?) OR (created_at = ? AND id > ?))"},
+		{"descending", []sortColumn{{"created_at", true}, {"id", true}}, false, "((created_at < ?) OR (created_at = ? AND id < ?))"},
+		{"descending walked backward", []sortColumn{{"created_at", true}, {"id", true}}, true, "((created_at > ?) OR (created_at = ? AND id > ?))"},
+		{"mixed directions", []sortColumn{{"created_at", false}, {"id", true}}, false, "((created_at > ?) OR (created_at = ? AND id < ?))"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sql, args, err := keysetCondition(tc.sorts, fields, raw, tc.backward)
+			if err != nil {
+				t.Fatalf("keysetCondition: %v", err)
+			}
+			if sql != tc.want {
+				t.Fatalf("got %s, want %s", sql, tc.want)
+			}
+			if len(args) != 3 || !args[0].(time.Time).Equal(at) || !args[1].(time.Time).Equal(at) || args[2] != int64(7) {
+				t.Fatalf("got args %v", args)
+			}
+		})
+	}
+
+	//a signed cursor whose values do not fit the columns, e.g. from an older version of the model
+	if _, _, err := keysetCondition([]sortColumn{{"created_at", false}, {"id", false}}, fields, []json.RawMessage{mustJSON(t, "yesterday"), mustJSON(t, 7)}, false); !isInvalidArgument(err) {
+		t.Fatalf("got %v, want an invalid argument", err)
+	}
+}
+
+func TestPaginateWalksPages(t *testing.T) {
+	db, mock := newMockDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	row := func(id int64) []driver.Value {
+		return []driver.Value{id, "item", base.Add(time.Duration(id) * time.Minute)}
+	}
+	rows := func(ids ...int64) *sqlmock.Rows {
+		result := sqlmock.NewRows([]string{"id", "name", "created_at"})
+		for _, id := range ids {
+			result.AddRow(row(id)...)
+		}
+		return result
+	}
+
+	//newest first, one row more than the limit tells there is a next page
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "items" WHERE user_id = $1 ORDER BY created_at DESC,id DESC LIMIT $2`)).
+		WithArgs("user-1", 3).
+		WillReturnRows(rows(5, 4, 3))
+	first := paginate(t, ctx, db, "limit=2")
+	assertPage(t, "first page", first, []int64{5, 4}, true, false)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "items" WHERE user_id = $1 AND (((created_at < $2) OR (created_at = $3 AND id < $4))) ORDER BY created_at DESC,id DESC LIMIT $5`)).
+		WithArgs("user-1", sameTime(base.Add(4*time.Minute)), sameTime(base.Add(4*time.Minute)), int64(4), 3).
+		WillReturnRows(rows(3))
+	second := paginate(t, ctx, db, "limit=2&cursor="+*first.Pagination.NextCursor)
+	assertPage(t, "second page", second, []int64{3}, false, true)
+
+	//walking back reverses the order in SQL and the rows again in Go
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "items" WHERE user_id = $1 AND (((created_at > $2) OR (created_at = $3 AND id > $4))) ORDER BY created_at ASC,id ASC LIMIT $5`)).
+		WithArgs("user-1", sameTime(base.Add(3*time.Minute)), sameTime(base.Add(3*time.Minute)), int64(3), 3).
+		WillReturnRows(rows(4, 5))
+	back := paginate(t, ctx, db, "limit=2&cursor="+*second.Pagination.PrevCursor)
+	assertPage(t, "previous page", back, []int64{5, 4}, true, false)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPaginateIncludeTotal(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "items" WHERE user_id = $1`)).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "items" WHERE user_id = $1 ORDER BY created_at DESC,id DESC LIMIT $2`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}))
+
+	page := paginate(t, context.Background(), db, "include_total=true")
+	if page.Pagination.Total == nil || *page.Pagination.Total != 0 || page.Data == nil || len(page.Data) != 0 {
+		t.Fatalf("got %+v, want an empty page with a total of 0", page)
+	}
+	if page.Pagination.NextCursor != nil || page.Pagination.PrevCursor != nil {
+		t.Fatalf("got cursors on an empty page: %+v", page.Pagination)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPaginateRejectsCursors(t *testing.T) {
+	db, _ := newMockDB(t)
+	ctx := context.Background()
+
+	other := cursor{Sort: "name,id", Values: []json.RawMessage{mustJSON(t, "a"), mustJSON(t, 1)}}
+	otherSort, err := other.encode(testCursorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := cursor{Sort: "-created_at,-id", Values: []json.RawMessage{mustJSON(t, 1)}}
+	tooShort, err := short.encode(testCursorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := (cursor{Sort: "-created_at,-id", Values: []json.RawMessage{mustJSON(t, time.Now()), mustJSON(t, 1)}}).encode([]byte("another-key-of-at-least-32-chars!!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"garbage":                 "garbage",
+		"signed with another key": foreign,
+		"from another sort":       otherSort,
+		"too few values":          tooShort,
+	} {
+		t.Run(name, func(t *testing.T) {
+			query, err := ParseListQuery(httptest.NewRequest("GET", "/items?"+url.Values{"cursor": {value}}.Encode(), nil), testSpec)
+			if err != nil {
+				t.Fatalf("ParseListQuery: %v", err)
+			}
+			if _, err := Paginate[item](ctx, db, query, testCursorKey); !isInvalidArgument(err) {
+				t.Fatalf("got %v, want an invalid argument", err)
+			}
+		})
+	}
+
+	query, _ := ParseListQuery(httptest.NewRequest("GET", "/items", nil), testSpec)
+	if _, err := Paginate[item](ctx, db, query, nil); err == nil || isInvalidArgument(err) {
+		t.Fatalf("got %v without a cursor key, want a server error", err)
+	}
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm: %v", err)
+	}
+
+	return db, mock
+}
+
+func paginate(t *testing.T, ctx context.Context, db *gorm.DB, rawQuery string) Paginated[item] {
+	t.Helper()
+
+	query, err := ParseListQuery(httptest.NewRequest("GET", "/items?"+rawQuery, nil), testSpec)
+	if err != nil {
+		t.Fatalf("ParseListQuery: %v", err)
+	}
+	page, err := Paginate[item](ctx, db, query.Where("user_id = ?", "user-1"), testCursorKey)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+
+	return page
+}
+
+func assertPage(t *testing.T, name string, page Paginated[item], ids []int64, next bool, prev bool) {
+	t.Helper()
+
+	got := make([]int64, 0, len(page.Data))
+	for _, row := range page.Data {
+		got = append(got, row.ID)
+	}
+	if !slices.Equal(got, ids) {
+		t.Fatalf("%s: got rows %v, want %v", name, got, ids)
+	}
+	if (page.Pagination.NextCursor != nil) != next || (page.Pagination.PrevCursor != nil) != prev {
+		t.Fatalf("%s: got next cursor %v and previous cursor %v, want %v and %v", name,
+			page.Pagination.NextCursor != nil, page.Pagination.PrevCursor != nil, next, prev)
+	}
+}
+
+func itemFields(t *testing.T, columns ...string) []*schema.Field {
+	t.Helper()
+
+	itemSchema, err := schema.Parse(&item{}, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make([]*schema.Field, 0, len(columns))
+	for _, column := range columns {
+		fields = append(fields, itemSchema.LookUpField(column))
+	}
+
+	return fields
+}
+
+func mustJSON(t *testing.T, value any) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// sameTime matches a time argument by instant, the location of a decoded cursor value differs.
+type sameTime time.Time
+
+func (s sameTime) Match(value driver.Value) bool {
+	t, ok := value.(time.Time)
+	return ok && t.Equal(time.Time(s))
+}
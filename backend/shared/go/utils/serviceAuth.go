@@ -6,6 +6,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,13 +27,14 @@ func GenerateServiceToken(serviceName, secret string) string {
 }
 
 func VerifyServiceToken(token, secret string) error {
-	var serviceName string
-	var timestamp int64
-	var signature string
-
 	//parse token: should be serviceName:timestamp:signature
-	n, err := fmt.Sscanf(token, "%[^:]:%d:%s", &serviceName, &timestamp, &signature)
-	if err != nil || n != 3 {
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return errors.New("invalid token format")
+	}
+	serviceName, signature := parts[0], parts[2]
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
 		return errors.New("invalid token format")
 	}
 	//check if token is not too old (5 minutes)
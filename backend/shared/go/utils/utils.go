@@ -27,7 +27,7 @@ func GetStringFromJSONB(data JSONB, path string) string {
 
 	keys := strings.Split(path, ".")
 
-	current := interface{}(data)
+	current := interface{}(map[string]interface{}(data)) //JSONB itself does not assert to map[string]interface{}
 	for _, key := range keys {
 		if currentMap, ok := current.(map[string]interface{}); ok { //assertion where value.(type) tries to assert that value is of a specific type
 			if next, exists := currentMap[key]; exists {
@@ -62,7 +62,7 @@ func GetIntFromJSONB(data JSONB, path string) int {
 
 	keys := strings.Split(path, ".")
 
-	current := interface{}(data)
+	current := interface{}(map[string]interface{}(data)) //JSONB itself does not assert to map[string]interface{}
 	for _, key := range keys {
 		if currentMap, ok := current.(map[string]interface{}); ok {
 			if next, exists := currentMap[key]; exists {
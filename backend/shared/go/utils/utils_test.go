@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestGetFromJSONB(t *testing.T) {
+	var data JSONB
+	if err := data.Scan([]byte(`{"source":"order-service","schemaVersion":2,"nested":{"id":"a-1","count":3},"flag":true}`)); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"source", "order-service"},
+		{"schemaVersion", "2"},
+		{"nested.id", "a-1"},
+		{"flag", "true"},
+		{"missing", ""},
+		{"source.id", ""},
+	} {
+		if got := GetStringFromJSONB(data, tc.path); got != tc.want {
+			t.Errorf("GetStringFromJSONB(%q): got %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"schemaVersion", 2},
+		{"nested.count", 3},
+		{"source", 0},
+		{"missing", 0},
+	} {
+		if got := GetIntFromJSONB(data, tc.path); got != tc.want {
+			t.Errorf("GetIntFromJSONB(%q): got %d, want %d", tc.path, got, tc.want)
+		}
+	}
+}
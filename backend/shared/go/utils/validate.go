@@ -2,9 +2,11 @@ package utils
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
+	"github.com/Flow-Indo/LAKOO/backend/shared/go/apperror"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -12,11 +14,20 @@ var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	//report fields by their json name, that is what clients sent
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
 }
 
+// ValidatePayload returns an apperror.Error with code invalid_argument listing the failed fields.
 func ValidatePayload(payload any) error {
 	if err := validate.Struct(payload); err != nil {
-		return errors.New("validation error: " + err.Error())
+		return apperror.Validation(err)
 	}
 	return nil
 }